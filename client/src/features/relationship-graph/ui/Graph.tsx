@@ -1,11 +1,114 @@
+import { useEffect, useRef, useState } from 'react';
+import * as d3 from 'd3';
+import { chatApi } from '@/shared/api/api';
+import type { GraphEdge, GraphNode, RelationshipGraph } from '@/shared/types';
+
+type SimNode = GraphNode & d3.SimulationNodeDatum;
+type SimEdge = Omit<GraphEdge, 'source' | 'target'> & d3.SimulationLinkDatum<SimNode>;
+
+const WIDTH = 800;
+const HEIGHT = 600;
+
+// Цвет ребра по знаку силы: тёплые отношения — бирюзовые, враждебные — красные
+const edgeColor = (strength: number) => (strength >= 0 ? '#2dd4bf' : '#f87171');
+
+const nodeRadius = (n: SimNode) => 6 + 2 * Math.sqrt(n.size);
+
+// Путь ребра до края целевого узла. Взаимные рёбра рисуются дугами,
+// чтобы оба направления были видны
+const edgePath = (d: SimEdge) => {
+    const s = d.source as SimNode;
+    const t = d.target as SimNode;
+    const [sx, sy, tx, ty] = [s.x ?? 0, s.y ?? 0, t.x ?? 0, t.y ?? 0];
+    const len = Math.hypot(tx - sx, ty - sy) || 1;
+    const ex = tx - ((tx - sx) / len) * (nodeRadius(t) + 2);
+    const ey = ty - ((ty - sy) / len) * (nodeRadius(t) + 2);
+    if (!d.mutual) return `M${sx},${sy}L${ex},${ey}`;
+    return `M${sx},${sy}A${len},${len} 0 0,1 ${ex},${ey}`;
+};
+
 const RelationShipGraph = () => {
-    return (
-        <div>
-            <h1>RelationShipGraph</h1>
+    const svgRef = useRef<SVGSVGElement>(null);
+    const [graph, setGraph] = useState<RelationshipGraph | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        chatApi.getRelationshipGraph().then(setGraph).catch((e: Error) => setError(e.message));
+    }, []);
 
-            
+    useEffect(() => {
+        if (!graph || !svgRef.current) return;
+
+        const nodes: SimNode[] = graph.nodes.map(n => ({ ...n }));
+        const edges: SimEdge[] = graph.edges.map(e => ({ ...e }));
+        const color = d3.scaleOrdinal(d3.schemeTableau10);
+
+        const svg = d3.select(svgRef.current);
+        svg.selectAll('*').remove();
+
+        // Стрелки на концах рёбер, по одной на цвет
+        svg.append('defs')
+            .selectAll('marker')
+            .data([1, -1])
+            .join('marker')
+            .attr('id', d => `arrow${d}`)
+            .attr('viewBox', '0 -5 10 10')
+            .attr('refX', 10)
+            .attr('markerWidth', 4)
+            .attr('markerHeight', 4)
+            .attr('orient', 'auto')
+            .append('path')
+            .attr('d', 'M0,-5L10,0L0,5')
+            .attr('fill', d => edgeColor(d));
+
+        const link = svg.append('g')
+            .attr('fill', 'none')
+            .selectAll('path')
+            .data(edges)
+            .join('path')
+            .attr('stroke', d => edgeColor(d.strength))
+            .attr('stroke-width', d => 1 + 4 * Math.abs(d.strength))
+            .attr('stroke-opacity', 0.7)
+            .attr('marker-end', d => `url(#arrow${d.strength >= 0 ? 1 : -1})`);
+        link.append('title').text(d =>
+            `${d.type} (trust ${d.trust.toFixed(2)}, affection ${d.affection.toFixed(2)}, respect ${d.respect.toFixed(2)})` +
+            (d.label ? `: ${d.label}` : ''));
+
+        const node = svg.append('g')
+            .selectAll('g')
+            .data(nodes)
+            .join('g');
+        node.append('circle')
+            .attr('r', nodeRadius)
+            .attr('fill', d => color(d.type));
+        node.append('text')
+            .text(d => d.label)
+            .attr('x', 12)
+            .attr('y', 4)
+            .attr('fill', 'white')
+            .attr('font-size', 12);
+
+        const simulation = d3.forceSimulation(nodes)
+            .force('link', d3.forceLink<SimNode, SimEdge>(edges).id(d => d.id).distance(120))
+            .force('charge', d3.forceManyBody().strength(-250))
+            .force('center', d3.forceCenter(WIDTH / 2, HEIGHT / 2))
+            .on('tick', () => {
+                link.attr('d', edgePath);
+                node.attr('transform', d => `translate(${d.x ?? 0},${d.y ?? 0})`);
+            });
+
+        return () => {
+            simulation.stop();
+        };
+    }, [graph]);
+
+    return (
+        <div className="flex flex-col h-screen bg-deep-midnight text-white p-6">
+            <h1 className="text-xl mb-4">Relationships</h1>
+            {error && <p className="text-red-400">{error}</p>}
+            <svg ref={svgRef} viewBox={`0 0 ${WIDTH} ${HEIGHT}`} className="flex-1 w-full" />
         </div>
     );
 };
 
-export default RelationShipGraph;
\ No newline at end of file
+export default RelationShipGraph;
@@ -1,7 +1,10 @@
-import type { Agent, AgentSummary, Event } from "../types";
+import type { Agent, AgentSummary, Event, RelationshipGraph, WorldMap } from "../types";
 
 const API_BASE_URL = ""; // Use relative path for proxy
 
+// Топики шины событий, которые показывает чат
+const STREAM_TOPICS = ["interaction", "system", "moderation", "relationship", "movement"];
+
 export const chatApi = {
   getAgents: async (): Promise<{ agents: AgentSummary[] }> => {
     const response = await fetch(`${API_BASE_URL}/agents`);
@@ -19,6 +22,22 @@ export const chatApi = {
     return response.json();
   },
 
+  getRelationshipGraph: async (): Promise<RelationshipGraph> => {
+    const response = await fetch(`${API_BASE_URL}/relationships`);
+    if (!response.ok) {
+      throw new Error("Failed to fetch relationship graph");
+    }
+    return response.json();
+  },
+
+  getWorldMap: async (): Promise<WorldMap> => {
+    const response = await fetch(`${API_BASE_URL}/world/map`);
+    if (!response.ok) {
+      throw new Error("Failed to fetch world map");
+    }
+    return response.json();
+  },
+
   injectMessage: async (agentId: string, content: string): Promise<void> => {
     const response = await fetch(`${API_BASE_URL}/agents/${agentId}/inject`, {
       method: "POST",
@@ -42,15 +61,25 @@ export const chatApi = {
     const BASE_DELAY = 1000; // 1 секунда
     let cleanup: (() => void) | null = null;
     let isActive = true;
+    // Номер последнего полученного события: после переподключения сервер
+    // досылает всё, что было пропущено
+    let lastEventId = "";
 
     const connect = () => {
       if (!isActive) return;
 
-      eventSource = new EventSource(`${API_BASE_URL}/events/stream`);
+      const resume = lastEventId ? `&lastEventId=${lastEventId}` : "";
+      eventSource = new EventSource(
+        `${API_BASE_URL}/events/stream?topics=${STREAM_TOPICS.join(",")}${resume}`,
+      );
 
-      eventSource.onmessage = (event) => {
+      // Сервер именует SSE-события по топику шины, onmessage их не получает
+      const handle = (event: MessageEvent) => {
         try {
           const data: Event = JSON.parse(event.data);
+          if (event.lastEventId) {
+            lastEventId = event.lastEventId;
+          }
           onMessage(data);
           retryCount = 0;
           
@@ -58,6 +87,10 @@ export const chatApi = {
           console.error("Failed to parse SSE message:", error);
         }
       };
+      eventSource.addEventListener("connected", () => {
+        retryCount = 0;
+      });
+      STREAM_TOPICS.forEach((topic) => eventSource?.addEventListener(topic, handle));
 
       eventSource.onerror = (error) => {
         console.error("SSE Error:", error);
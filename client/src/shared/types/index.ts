@@ -47,9 +47,11 @@ export interface AgentSummary {
     currentMood: string;
     moodIntensity: number;
     isActive: boolean;
+    location: string;
 }
 
 export interface Event {
+    id?: string;
     type: string;
     speaker?: string;
     target?: string;
@@ -57,12 +59,54 @@ export interface Event {
     tick?: number;
     topic?: string;
     source?: string;
+    agentId?: string;
+    affectedAgents?: string[];
+    conversationId?: string;
     affected_agents?: string[];
     payload?: any;
     status?: string;
     created_at?: string;
 }
 
+export interface GraphNode {
+    id: string;
+    label: string;
+    type: string;
+    size: number;
+}
+
+// Ребро направленное: как source относится к target
+export interface GraphEdge {
+    source: string;
+    target: string;
+    type: string;
+    strength: number;
+    trust: number;
+    affection: number;
+    respect: number;
+    mutual: boolean;
+    label?: string;
+}
+
+export interface RelationshipGraph {
+    nodes: GraphNode[];
+    edges: GraphEdge[];
+}
+
+export interface Zone {
+    id: string;
+    name: string;
+    description?: string;
+    x: number;
+    y: number;
+    neighbors: string[];
+    agents: string[];
+}
+
+export interface WorldMap {
+    zones: Zone[];
+}
+
 export interface WorldStatus {
     currentTick: number;
     simulationSpeed: number;
@@ -29,7 +29,7 @@ export const MessageList = ({ messages }: MessageListProps) => {
                 
                 const agentColor = message.color || '#26d0ce';
 
-                if (message.type === 'system') {
+                if (message.type === 'system' || (message.topic && message.type !== 'conversation')) {
                     return (
                         <div key={index} className="text-center">
                             <span className="px-3 py-1 bg-white/5 rounded-full text-[10px] text-white/30 uppercase tracking-[0.2em]">
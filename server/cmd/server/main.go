@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"milk/server/data"
 	"milk/server/internal/api"
+	"milk/server/internal/moderation"
 	"milk/server/internal/storage"
 	"milk/server/internal/world"
 	"milk/server/pkg/llm"
@@ -21,30 +26,69 @@ func main() {
 	// База данных
 	data.DbConnection()
 	repo := storage.NewRepository(data.Db)
+	if err := repo.Migrate(); err != nil {
+		log.Fatal("migrate: ", err)
+	}
 
-	// LLM клиент (Ollama)
-	llmClient := llm.NewClient()
-	fmt.Printf("LLM: %s @ %s\n", llmClient.Model, llmClient.BaseURL)
-
-	// SSE Hub
+	// SSE Hub и шина событий: все события сохраняются в events
+	// и через шину попадают в SSE
 	hub := api.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := world.NewEventBus(repo, envInt("EVENT_QUEUE_SIZE", 1024))
+	bus.Start(ctx)
+	world.StreamToHub(ctx, bus, hub)
+	hub.UseReplaySource(world.EventReplay(repo))
+
+	// LLM клиент: провайдер + ретраи, circuit breaker и резервная цепочка,
+	// учёт токенов в llm_calls, выбор модели по задаче и агенту,
+	// перед ними — общий планировщик с лимитом LLM_MAX_INFLIGHT
+	llmClient := llm.NewUsageRecorder(newLLMClient(world.LLMHealthReporter(bus)), world.LLMUsageSink(repo))
+	router := newRouter(repo, llmClient)
+	maxInflight := envInt("LLM_MAX_INFLIGHT", 1)
+	scheduler := llm.NewScheduler(router, maxInflight)
 
 	// HTTP Handler
 	handler := api.NewHandler(repo, hub)
+	handler.UseScheduler(scheduler)
+	handler.UseRouter(router)
+	handler.UsePublisher(world.APIPublisher(bus))
+	moderator := newModerator(bus, scheduler)
+	handler.UseModerator(moderator)
 	mux := api.NewMux(handler)
 
 	origin := os.Getenv("ALLOWED_ORIGIN")
 	h := cors.New(cors.Options{
 		AllowedOrigins: []string{origin},
-		AllowedMethods: []string{"GET", "POST", "PATCH", "OPTIONS", "DELETE"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "OPTIONS", "DELETE"},
 		AllowedHeaders: []string{"Content-type"},
 		Debug:          false,
 	}).Handler(mux)
 	// Оркестратор
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	orch := world.NewOrchestrator(repo, llmClient, hub)
+	orch := world.NewOrchestrator(repo, scheduler, hub, bus)
+	orch.UseModerator(moderator)
+	// LLM_SEED — то же зерно для выборки LLM и для случайных решений
+	// симуляции: с кассетой прогон повторяется целиком
+	if v, err := strconv.ParseInt(os.Getenv("LLM_SEED"), 10, 64); err == nil {
+		orch.UseSeed(v)
+	}
+	// PARTNER_POLICY — выбор собеседников: "affinity" (по умолчанию) или "random"
+	policy, err := world.NewPartnerPolicy(os.Getenv("PARTNER_POLICY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	orch.UsePartnerPolicy(policy)
+	// Разговоров одновременно — по числу слотов LLM;
+	// CONVERSATIONS_PER_TICK — пар за тик (по умолчанию столько же)
+	orch.UseConcurrency(maxInflight, envInt("CONVERSATIONS_PER_TICK", 0))
+	// GROUP_MAX_SIZE — сколько агентов может собраться в одном разговоре (2 — только пары)
+	orch.UseGroupSize(envInt("GROUP_MAX_SIZE", 4))
+	// CONVERSATION_MIN_TURNS/CONVERSATION_MAX_TURNS — границы длины разговора в репликах
+	orch.UseConversationLength(world.ConversationLength{
+		Min: envInt("CONVERSATION_MIN_TURNS", world.DefaultConversationLength.Min),
+		Max: envInt("CONVERSATION_MAX_TURNS", world.DefaultConversationLength.Max),
+	})
+	handler.UseSimulation(orch)
 	go orch.Start(ctx)
 
 	// Graceful shutdown
@@ -62,3 +106,183 @@ func main() {
 		fmt.Println("server error:", err)
 	}
 }
+
+// newLLMClient собирает LLM-слой из env-переменных.
+//
+// LLM_PROVIDER — основной провайдер: "ollama" (default, /api/chat) или
+// "openai" (/v1/chat/completions: llama.cpp server, vLLM и т.д.).
+// LLM_FALLBACKS — упорядоченный список резервов через запятую в формате
+// provider:model, например "ollama:gemma3:1b,openai:qwen2.5-3b".
+//
+// Каждый провайдер оборачивается в RetryClient (повторы временных ошибок)
+// и CircuitBreaker; поверх них FallbackClient перебирает цепочку.
+//
+// LLM_CASSETTE — путь к кассете записи/воспроизведения. В режиме
+// LLM_CASSETTE_MODE=replay провайдеры не создаются вовсе (демо без Ollama),
+// в режиме record цепочка провайдеров оборачивается записью.
+// LLM_CASSETTE_FALLBACK=scripted отвечает заготовками на unmatched-запросы.
+func newLLMClient(onHealth llm.HealthFunc) llm.Completer {
+	path := os.Getenv("LLM_CASSETTE")
+	if path == "" {
+		return newProviderChain(onHealth)
+	}
+
+	mode := llm.CassetteMode(os.Getenv("LLM_CASSETTE_MODE"))
+	if mode == "" {
+		mode = llm.CassetteReplay
+	}
+	var next llm.Completer
+	if mode == llm.CassetteRecord {
+		next = newProviderChain(onHealth)
+	}
+
+	c, err := llm.NewRecordReplayClient(path, mode, next)
+	if err != nil {
+		log.Fatalf("llm cassette: %v", err)
+	}
+	if os.Getenv("LLM_CASSETTE_FALLBACK") == "scripted" {
+		c.Fallback = &llm.ScriptedResponder{Lines: scriptedLines}
+	}
+	fmt.Printf("LLM: cassette %s (%s)\n", path, mode)
+	return c
+}
+
+// newRouter создаёт маршрутизатор моделей. Правила читаются из
+// LLM_ROUTING_FILE (JSON, см. llm.RoutingConfig), переопределения,
+// заданные через PUT /world/routing, — из world_state.
+func newRouter(repo *storage.Repository, next llm.Completer) *llm.Router {
+	var base llm.RoutingConfig
+	if path := os.Getenv("LLM_ROUTING_FILE"); path != "" {
+		cfg, err := llm.LoadRoutingConfig(path)
+		if err != nil {
+			log.Fatalf("llm routing: %v", err)
+		}
+		base = cfg
+		fmt.Printf("LLM: routing rules from %s\n", path)
+	}
+	router := llm.NewRouter(next, base)
+
+	state, err := repo.GetWorldState()
+	if err != nil {
+		log.Fatalf("llm routing: %v", err)
+	}
+	if raw := state[storage.WorldStateLLMRouting]; raw != "" {
+		var overrides llm.RoutingConfig
+		if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+			log.Printf("llm routing: ignoring saved overrides: %v", err)
+		} else {
+			router.SetOverrides(overrides)
+		}
+	}
+	return router
+}
+
+// newModerator собирает модерацию из env-переменных.
+//
+// MODERATION=off — отключить модерацию (nil).
+// MODERATION_RULES_FILE — JSON-массив moderation.Rule вместо встроенного набора.
+// MODERATION_LLM_JUDGE — включить LLM-судью: "input", "output" или "all".
+func newModerator(bus *world.EventBus, client llm.Completer) *moderation.Moderator {
+	if os.Getenv("MODERATION") == "off" {
+		return nil
+	}
+
+	rules := moderation.DefaultRules
+	if path := os.Getenv("MODERATION_RULES_FILE"); path != "" {
+		loaded, err := moderation.LoadRules(path)
+		if err != nil {
+			log.Fatalf("moderation: %v", err)
+		}
+		rules = loaded
+	}
+	rulesClassifier, err := moderation.NewRulesClassifier(rules)
+	if err != nil {
+		log.Fatalf("moderation: %v", err)
+	}
+	classifiers := []moderation.Classifier{rulesClassifier}
+
+	switch os.Getenv("MODERATION_LLM_JUDGE") {
+	case "":
+	case "input":
+		classifiers = append(classifiers, moderation.NewLLMJudge(client, moderation.KindInput))
+	case "output":
+		classifiers = append(classifiers, moderation.NewLLMJudge(client, moderation.KindOutput))
+	case "all":
+		classifiers = append(classifiers, moderation.NewLLMJudge(client))
+	default:
+		log.Fatalf("moderation: unknown MODERATION_LLM_JUDGE %q (expected input, output or all)", os.Getenv("MODERATION_LLM_JUDGE"))
+	}
+
+	return moderation.New(world.ModerationRecorder(bus), classifiers...)
+}
+
+// scriptedLines — заготовки для unmatched-запросов в режиме replay.
+var scriptedLines = []string{
+	"Привет! Давно хотел с тобой поговорить.",
+	"Интересная мысль. Расскажи подробнее, что ты имеешь в виду?",
+	"Не уверен, что согласен, но звучит любопытно.",
+	"Знаешь, я как раз думал о чём-то похожем.",
+	"Ладно, мне пора. Ещё увидимся!",
+}
+
+// newProviderChain собирает основной провайдер и резервы в FallbackClient.
+func newProviderChain(onHealth llm.HealthFunc) llm.Completer {
+	primary := os.Getenv("LLM_PROVIDER")
+	if primary == "" {
+		primary = "ollama"
+	}
+
+	entries := []llm.FallbackEntry{newLLMEntry(primary, "", onHealth)}
+	for _, spec := range strings.Split(os.Getenv("LLM_FALLBACKS"), ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		provider, model, _ := strings.Cut(spec, ":")
+		entries = append(entries, newLLMEntry(provider, model, onHealth))
+	}
+	return llm.NewFallbackClient(onHealth, entries...)
+}
+
+// newLLMEntry создаёт одного провайдера цепочки. Пустой model = модель из env.
+func newLLMEntry(provider, model string, onHealth llm.HealthFunc) llm.FallbackEntry {
+	var (
+		client llm.Completer
+		name   string
+	)
+	switch provider {
+	case "ollama":
+		c := llm.NewClient()
+		if model != "" {
+			c.Model = model
+		}
+		name = "ollama/" + c.Model
+		fmt.Printf("LLM: %s @ %s\n", name, c.BaseURL)
+		client = c
+	case "openai":
+		c := llm.NewOpenAIClient()
+		if model != "" {
+			c.Model = model
+		}
+		name = "openai/" + c.Model
+		fmt.Printf("LLM: %s @ %s\n", name, c.BaseURL)
+		client = c
+	default:
+		log.Fatalf("unknown LLM provider %q (expected ollama or openai)", provider)
+	}
+
+	retry := llm.NewRetryClient(name, client)
+	return llm.FallbackEntry{
+		Name:   name,
+		Client: llm.NewCircuitBreaker(name, retry, onHealth),
+	}
+}
+
+// envInt читает целое из env-переменной, def — если не задана или невалидна.
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
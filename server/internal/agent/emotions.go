@@ -201,6 +201,28 @@ type MoodInfluence struct {
 	AssertivenessModifier float64
 }
 
+// GetMoodInfluence переводит PAD-состояние в поведенческие модификаторы.
+func GetMoodInfluence(pad PADState) MoodInfluence {
+	return MoodInfluence{
+		ImpulsivityModifier:   clampUnit(pad.Arousal),
+		SociabilityModifier:   clampUnit(pad.Pleasure*0.7 + pad.Arousal*0.3),
+		RiskModifier:          clampUnit(pad.Pleasure*0.6 + pad.Dominance*0.4),
+		AssertivenessModifier: clampUnit(pad.Dominance),
+	}
+}
+
+// clampUnit ограничивает значение диапазоном -1.0 .. +1.0.
+func clampUnit(v float64) float64 {
+	switch {
+	case v < -1:
+		return -1
+	case v > 1:
+		return 1
+	default:
+		return v
+	}
+}
+
 // -----------------------------------------------------------------------------
 // AppraisalResult — результат когнитивной оценки стимула (теория Лазаруса)
 // -----------------------------------------------------------------------------
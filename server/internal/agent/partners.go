@@ -0,0 +1,113 @@
+// Package agent — собеседники в промпте диалога.
+//
+// PartnerContext — всё, что агент знает о собеседнике: связь (тип и
+// измерения), как они познакомились, последние общие воспоминания и
+// выводы о нём. BuildPartnerPrompt превращает это в раздел системного
+// промпта Think и подсказывает, как здороваться: со старым другом — тепло,
+// с соперником — настороженно, с незнакомцем — представиться.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PartnerContext — что агент знает о собеседнике.
+type PartnerContext struct {
+	// ID, Name — id и имя собеседника.
+	ID   string
+	Name string
+
+	// Type — кем собеседник приходится агенту ("friend", "rival",
+	// "mentor"...). Пусто — связи нет.
+	Type string
+
+	// Strength, Trust, Affection, Respect — сила и измерения отношения
+	// агента к собеседнику, -1.0..+1.0.
+	Strength  float64
+	Trust     float64
+	Affection float64
+	Respect   float64
+
+	// Met — как познакомились, одной фразой. Пусто — не встречались.
+	Met string
+
+	// Note — заметка оператора о связи.
+	Note string
+
+	// Memories — последние общие воспоминания, старые первыми.
+	Memories []string
+
+	// Beliefs — выводы агента о собеседнике (семантическая память).
+	Beliefs []string
+}
+
+// Stranger — агент видит собеседника впервые.
+func (p PartnerContext) Stranger() bool {
+	return p.Type == "" && p.Met == "" && len(p.Memories) == 0
+}
+
+// Пороги силы, после которых знакомый считается другом или недругом,
+// если тип связи ничего не говорит.
+const (
+	partnerWarm = 0.35
+	partnerCold = -0.35
+)
+
+// BuildPartnerPrompt описывает собеседников для системного промпта Think.
+// Пустой список — пустая строка.
+func BuildPartnerPrompt(partners []PartnerContext) string {
+	if len(partners) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nТвои собеседники:\n")
+	for _, p := range partners {
+		sb.WriteString(fmt.Sprintf("\n%s.\n", p.Name))
+		if !p.Stranger() {
+			if p.Type != "" {
+				sb.WriteString(fmt.Sprintf("- Кем приходится тебе: %s (доверие %+.2f, симпатия %+.2f, уважение %+.2f).\n",
+					p.Type, p.Trust, p.Affection, p.Respect))
+			}
+			if p.Met != "" {
+				sb.WriteString("- " + p.Met + "\n")
+			}
+			if p.Note != "" {
+				sb.WriteString("- " + p.Note + "\n")
+			}
+			for _, m := range p.Memories {
+				sb.WriteString("- Ты помнишь: " + m + "\n")
+			}
+			for _, b := range p.Beliefs {
+				sb.WriteString("- Твой вывод: " + b + "\n")
+			}
+		}
+		sb.WriteString("- " + greeting(p) + "\n")
+	}
+	return sb.String()
+}
+
+// greeting — как держаться с собеседником при встрече.
+func greeting(p PartnerContext) string {
+	switch {
+	case p.Stranger():
+		return fmt.Sprintf("Вы не знакомы: представься и присмотрись к %s.", p.Name)
+	case p.Type == "rival" || p.Strength <= partnerCold:
+		return "Вы не ладите: держись настороженно и холодно, не делай вид, что рад встрече."
+	case p.Type == "mentor":
+		return "Это твой наставник: обращайся с уважением, спроси совета."
+	case p.Type == "student":
+		return "Это твой ученик: говори покровительственно, но по-доброму."
+	case p.Type == "family":
+		return "Вы родные: говори по-домашнему, без церемоний."
+	case p.Type == "romantic":
+		return "Вы близки: поздоровайся нежно."
+	case p.Type == "colleague":
+		return "Вы коллеги: поздоровайся по-деловому."
+	case p.Type == "friend" || p.Type == "close" || p.Strength >= partnerWarm:
+		return "Вы старые друзья: поздоровайся тепло и по-свойски, вспомни что-нибудь из общего прошлого."
+	default:
+		return "Вы уже встречались, но близко не знакомы: поздоровайся вежливо, как со знакомым."
+	}
+}
@@ -114,14 +114,19 @@ func (h *Handler) GetAgent(w http.ResponseWriter, r *http.Request) {
 func recordToSummary(rec storage.AgentRecord) AgentSummary {
 	mood, intensity := moodFromJSON(rec.MoodState.String)
 	personality := parsePersonality(rec.Personality)
-	return AgentSummary{
+	summary := AgentSummary{
 		ID:              rec.ID,
 		Name:            rec.Name,
 		PersonalityType: personalityType(personality),
 		CurrentMood:     mood,
 		MoodIntensity:   intensity,
 		IsActive:        rec.IsActive,
+		Location:        storage.DefaultZone,
 	}
+	if rec.Location.Valid && rec.Location.String != "" {
+		summary.Location = rec.Location.String
+	}
+	return summary
 }
 
 func personalityType(p PersonalityDTO) string {
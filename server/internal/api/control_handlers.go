@@ -41,6 +41,10 @@ func (h *Handler) SpawnAgent(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to create agent")
 		return
 	}
+	h.publishEvent("system", "agent_joined", []string{rec.ID}, map[string]any{
+		"agentId": rec.ID,
+		"message": rec.Name + " joined the society",
+	})
 
 	writeJSON(w, http.StatusCreated, AgentDetailResponse{
 		ID:          rec.ID,
@@ -64,6 +68,7 @@ func (h *Handler) DeactivateAgentHandler(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusNotFound, ErrCodeNotFound, "agent not found")
 		return
 	}
+	h.publishEvent("system", "agent_left", []string{id}, map[string]any{"agentId": id})
 
 	writeJSON(w, http.StatusOK, SuccessResponse{
 		Success: true,
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"milk/server/internal/storage"
+)
+
+// ListConversations — GET /conversations
+// Query params: ?agent=<id>&from=100&to=200&limit=50
+// from/to — тики: разговоры, пересекающиеся с диапазоном.
+func (h *Handler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := storage.ConversationFilter{
+		AgentID: q.Get("agent"),
+		Limit:   parseIntQuery(r, "limit", 50),
+	}
+	if filter.Limit > 200 {
+		filter.Limit = 200
+	}
+	for _, p := range []struct {
+		key string
+		dst **int64
+	}{{"from", &filter.FromTick}, {"to", &filter.ToTick}} {
+		v := q.Get(p.key)
+		if v == "" {
+			continue
+		}
+		tick, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, p.key+" must be a tick number")
+			return
+		}
+		*p.dst = &tick
+	}
+
+	records, err := h.repo.ListConversations(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to list conversations")
+		return
+	}
+
+	names := h.agentNames()
+	resp := ConversationListResponse{Conversations: make([]ConversationDTO, 0, len(records))}
+	for _, rec := range records {
+		resp.Conversations = append(resp.Conversations, conversationToDTO(rec, names))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetConversation — GET /conversations/{id}
+// Разговор с упорядоченной стенограммой.
+func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, err := h.repo.GetConversation(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get conversation")
+		return
+	}
+	if rec == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "conversation not found")
+		return
+	}
+	turns, err := h.repo.GetConversationTurns(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get transcript")
+		return
+	}
+
+	names := h.agentNames()
+	resp := ConversationDetailResponse{
+		ConversationDTO: conversationToDTO(*rec, names),
+		Transcript:      make([]ConversationTurnDTO, 0, len(turns)),
+	}
+	for _, t := range turns {
+		dto := ConversationTurnDTO{
+			Seq:       t.Seq,
+			SpeakerID: t.SpeakerID,
+			Speaker:   names(t.SpeakerID),
+			Content:   t.Content,
+			Tick:      t.Tick,
+			Timestamp: t.CreatedAt,
+		}
+		if t.TargetID.Valid {
+			dto.TargetID = t.TargetID.String
+			dto.Target = names(t.TargetID.String)
+		}
+		resp.Transcript = append(resp.Transcript, dto)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// agentNames возвращает функцию id → имя агента с кэшем на время запроса.
+// Неизвестный агент — пустое имя.
+func (h *Handler) agentNames() func(id string) string {
+	cache := map[string]string{}
+	return func(id string) string {
+		if name, ok := cache[id]; ok {
+			return name
+		}
+		var name string
+		if rec, err := h.repo.GetAgentByID(id); err == nil && rec != nil {
+			name = rec.Name
+		}
+		cache[id] = name
+		return name
+	}
+}
+
+func conversationToDTO(rec storage.ConversationRecord, names func(string) string) ConversationDTO {
+	dto := ConversationDTO{
+		ID:           rec.ID,
+		InitiatorID:  rec.InitiatorID,
+		Participants: make([]ParticipantDTO, 0, len(rec.Participants)),
+		StartTick:    rec.StartTick,
+		EndReason:    rec.EndReason.String,
+		Topic:        rec.Topic.String,
+		Summary:      rec.Summary.String,
+		StartedAt:    rec.StartedAt,
+		Turns:        rec.Turns,
+	}
+	for _, id := range rec.Participants {
+		dto.Participants = append(dto.Participants, ParticipantDTO{ID: id, Name: names(id)})
+	}
+	if rec.EndTick.Valid {
+		dto.EndTick = &rec.EndTick.Int64
+	}
+	if rec.EndedAt.Valid {
+		dto.EndedAt = &rec.EndedAt.Time
+	}
+	return dto
+}
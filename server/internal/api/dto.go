@@ -56,6 +56,9 @@ type AgentSummary struct {
 
 	// IsActive — активен ли агент в симуляции (false = soft-deleted).
 	IsActive bool `json:"isActive"`
+
+	// Location — id зоны, где находится агент ("square", "market").
+	Location string `json:"location"`
 }
 
 // AgentDetailResponse — полный профиль агента, ответ на GET /api/v1/agents/:id.
@@ -179,6 +182,7 @@ type MemoryEntryDTO struct {
 
 // RelationshipGraphResponse — граф отношений, ответ на GET /api/v1/relationships.
 // Формат совместим с библиотеками визуализации графов (D3.js, vis.js, Sigma.js).
+// Рёбра направленные: у пары может быть два ребра с разными типами.
 type RelationshipGraphResponse struct {
 	Nodes []GraphNodeDTO `json:"nodes"`
 	Edges []GraphEdgeDTO `json:"edges"`
@@ -199,30 +203,115 @@ type GraphNodeDTO struct {
 	Size int `json:"size"`
 }
 
-// GraphEdgeDTO — ребро графа (связь между двумя агентами).
+// GraphEdgeDTO — ребро графа: отношение Source к Target.
 type GraphEdgeDTO struct {
-	// Source — UUID агента-источника.
+	// Source — UUID агента, который относится.
 	Source string `json:"source"`
 
-	// Target — UUID целевого агента.
+	// Target — UUID агента, к которому относятся.
 	Target string `json:"target"`
 
-	// Type — тип отношений: "friend", "rival", "neutral", "romantic".
+	// Type — кем Target приходится Source: "friend", "rival", "neutral",
+	// "romantic", "mentor", "student", "family", "colleague"...
 	Type string `json:"type"`
 
 	// Strength — сила связи: -1.0 (враждебная) до +1.0 (тесная).
 	// Знак определяет цвет ребра, абсолютное значение — толщину.
 	Strength float64 `json:"strength"`
 
+	// Trust, Affection, Respect — измерения отношения, -1.0..+1.0.
+	Trust     float64 `json:"trust"`
+	Affection float64 `json:"affection"`
+	Respect   float64 `json:"respect"`
+
+	// Mutual — есть ли обратное ребро (клиент рисует пару дугами).
+	Mutual bool `json:"mutual"`
+
 	// Label — описание связи ("bonded over shared values").
 	Label string `json:"label,omitempty"`
 }
 
 // CreateRelationshipRequest — создание/изменение связи, POST /api/v1/relationships.
+// Задаёт отношение agent1 к agent2.
 type CreateRelationshipRequest struct {
 	Agent1ID string `json:"agent1" binding:"required"`
 	Agent2ID string `json:"agent2" binding:"required"`
 	Type     string `json:"type" binding:"required"`
+
+	// Strength — задаёт все три измерения сразу. nil = типичные для Type.
+	Strength *float64 `json:"strength,omitempty"`
+
+	// Trust, Affection, Respect — отдельные измерения, важнее Strength.
+	Trust     *float64 `json:"trust,omitempty"`
+	Affection *float64 `json:"affection,omitempty"`
+	Respect   *float64 `json:"respect,omitempty"`
+
+	// Note — заметка оператора; агенты видят её в разговорах друг с другом.
+	Note string `json:"note,omitempty"`
+
+	// Reciprocal — записать и обратное отношение agent2 к agent1
+	// (для mentor — student и наоборот, для остальных типов — тот же тип).
+	Reciprocal bool `json:"reciprocal,omitempty"`
+
+	// Force — перезаписать существующую связь. Без него — 409.
+	Force bool `json:"force,omitempty"`
+}
+
+// AgentRelationshipsResponse — связи агента, GET /api/v1/relationships/{agentId}.
+type AgentRelationshipsResponse struct {
+	Agent         AgentSummary      `json:"agent"`
+	Relationships []RelationshipDTO `json:"relationships"`
+}
+
+// RelationshipDTO — связь агента с другим агентом в обе стороны.
+type RelationshipDTO struct {
+	// With — другой агент.
+	With AgentSummary `json:"with"`
+
+	// Outgoing — как агент относится к With. nil — никак.
+	Outgoing *RelationshipEdgeDTO `json:"outgoing,omitempty"`
+
+	// Incoming — как With относится к агенту. nil — никак.
+	Incoming *RelationshipEdgeDTO `json:"incoming,omitempty"`
+
+	// History — смены типа и правки оператора, новые первыми.
+	History []RelationshipChangeDTO `json:"history,omitempty"`
+
+	// Conversations — последние разговоры пары, новые первыми.
+	Conversations []ConversationDTO `json:"conversations,omitempty"`
+}
+
+// RelationshipEdgeDTO — одно направленное отношение.
+type RelationshipEdgeDTO struct {
+	// ID — UUID связи.
+	ID string `json:"id"`
+
+	Type      string  `json:"type"`
+	Strength  float64 `json:"strength"`
+	Trust     float64 `json:"trust"`
+	Affection float64 `json:"affection"`
+	Respect   float64 `json:"respect"`
+	Note      string  `json:"note,omitempty"`
+
+	InteractionCount int        `json:"interactionCount"`
+	LastInteraction  *time.Time `json:"lastInteraction,omitempty"`
+}
+
+// RelationshipChangeDTO — событие в истории связи.
+type RelationshipChangeDTO struct {
+	// Type — "relationship_changed" (динамика) или "relationship_set" (оператор).
+	Type string `json:"type"`
+
+	// Direction — чьё отношение изменилось: "outgoing" (агента),
+	// "incoming" (собеседника) или "mutual" (оба).
+	Direction string `json:"direction"`
+
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Strength  float64   `json:"strength"`
+	Message   string    `json:"message"`
+	Tick      int64     `json:"tick,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // =============================================================================
@@ -252,6 +341,147 @@ type InjectEventRequest struct {
 	AffectedAgents []string `json:"affectedAgents"`
 }
 
+// StreamClientsResponse — подключённые SSE-клиенты, GET /events/clients.
+type StreamClientsResponse struct {
+	Clients []StreamClientDTO `json:"clients"`
+
+	// ReplayOldest, ReplayLatest — диапазон номеров событий в кольце досылки.
+	// Last-Event-ID старше ReplayOldest досылается из БД.
+	ReplayOldest int64 `json:"replayOldest"`
+	ReplayLatest int64 `json:"replayLatest"`
+}
+
+// StreamClientDTO — один SSE-клиент.
+type StreamClientDTO struct {
+	ID          string    `json:"id"`
+	Remote      string    `json:"remote"`
+	Topics      []string  `json:"topics"`
+	Agents      []string  `json:"agents"`
+	ConnectedAt time.Time `json:"connectedAt"`
+
+	// Sent — отправлено событий (включая досланные).
+	Sent int64 `json:"sent"`
+
+	// Dropped — потеряно событий из-за переполненного буфера
+	// (клиент отключается и досылает их при переподключении).
+	Dropped int64 `json:"dropped"`
+
+	// LastEventID — номер последнего отправленного события.
+	LastEventID int64 `json:"lastEventId"`
+}
+
+// =============================================================================
+// CONVERSATION DTOs
+// =============================================================================
+
+// ConversationListResponse — ответ на GET /conversations.
+type ConversationListResponse struct {
+	Conversations []ConversationDTO `json:"conversations"`
+}
+
+// ConversationDTO — разговор без стенограммы.
+type ConversationDTO struct {
+	ID          string `json:"id"`
+	InitiatorID string `json:"initiatorId"`
+
+	// Participants — все участники, включая ушедших.
+	Participants []ParticipantDTO `json:"participants"`
+
+	StartTick int64  `json:"startTick"`
+	EndTick   *int64 `json:"endTick,omitempty"` // nil = разговор идёт
+
+	// EndReason — "farewell", "signalled", "repetition", "max_length"...
+	EndReason string `json:"endReason,omitempty"`
+	Topic     string `json:"topic,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+
+	// Turns — число реплик.
+	Turns int `json:"turns"`
+}
+
+// ParticipantDTO — участник разговора.
+type ParticipantDTO struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ConversationDetailResponse — разговор со стенограммой, GET /conversations/{id}.
+type ConversationDetailResponse struct {
+	ConversationDTO
+	Transcript []ConversationTurnDTO `json:"transcript"`
+}
+
+// ConversationTurnDTO — реплика стенограммы.
+type ConversationTurnDTO struct {
+	Seq       int    `json:"seq"`
+	SpeakerID string `json:"speakerId"`
+	Speaker   string `json:"speaker"`
+
+	// TargetID, Target — к кому обращена реплика. Пусто = ко всем.
+	TargetID string `json:"targetId,omitempty"`
+	Target   string `json:"target,omitempty"`
+
+	Content   string    `json:"content"`
+	Tick      int64     `json:"tick"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// =============================================================================
+// MODERATION DTOs
+// =============================================================================
+
+// ModerationDecisionDTO — решение модерации, GET /moderation/decisions.
+type ModerationDecisionDTO struct {
+	// ID — ID события решения.
+	ID string `json:"id"`
+
+	// Kind — "input" (сообщение человека) или "output" (реплика агента).
+	Kind string `json:"kind"`
+
+	// AgentID — адресат инъекции или автор реплики.
+	AgentID string `json:"agentId,omitempty"`
+
+	// Action — "allow", "flag", "redact", "block".
+	Action string `json:"action"`
+
+	// Category — "jailbreak", "prompt_injection", "pii", "unsafe".
+	Category string `json:"category,omitempty"`
+
+	// Reason — сработавшее правило или объяснение LLM-судьи.
+	Reason string `json:"reason,omitempty"`
+
+	// Classifier — "rules" или "llm_judge".
+	Classifier string `json:"classifier,omitempty"`
+
+	// Text — исходный текст.
+	Text string `json:"text"`
+
+	// Redacted — отредактированный текст (для redact).
+	Redacted string `json:"redacted,omitempty"`
+
+	// Status — "pending" (ждёт ревью), "completed", "approved", "rejected".
+	Status string `json:"status"`
+
+	// Tick — тик симуляции (0 для инъекций вне тика).
+	Tick int64 `json:"tick,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ModerationDecisionsResponse — список решений.
+type ModerationDecisionsResponse struct {
+	Decisions []ModerationDecisionDTO `json:"decisions"`
+}
+
+// ModerationReviewRequest — ревью решения, POST /moderation/decisions/{id}/review.
+type ModerationReviewRequest struct {
+	// Verdict — "approved" (решение верное) или "rejected" (ложное срабатывание).
+	Verdict string `json:"verdict"`
+}
+
 // =============================================================================
 // WORLD DTOs
 // =============================================================================
@@ -286,6 +516,67 @@ type WorldControlRequest struct {
 	Value *float64 `json:"value,omitempty"`
 }
 
+// WorldTasksResponse — задачи на часах симуляции, GET /world/tasks.
+type WorldTasksResponse struct {
+	// CurrentTick — текущий тик, относительно которого считается NextRun.
+	CurrentTick int64 `json:"currentTick"`
+
+	Tasks []ScheduledTaskDTO `json:"tasks"`
+}
+
+// ScheduledTaskDTO — одна запланированная задача.
+type ScheduledTaskDTO struct {
+	// Label — имя задачи: "reflection", "memory_forgetting", "once@120".
+	Label string `json:"label"`
+
+	// Kind — "recurring" или "once".
+	Kind string `json:"kind"`
+
+	// Interval — период в тиках (только для recurring).
+	Interval int64 `json:"interval,omitempty"`
+
+	// LastRun — тик последнего запуска. 0 = ещё не запускалась.
+	LastRun int64 `json:"lastRun"`
+
+	// NextRun — тик следующего запуска. 0 = больше не запустится.
+	NextRun int64 `json:"nextRun"`
+
+	// Running — выполняется прямо сейчас.
+	Running bool `json:"running"`
+
+	Runs      int64  `json:"runs"`
+	Failures  int64  `json:"failures"`
+	Skipped   int64  `json:"skipped"`
+	LastError string `json:"lastError,omitempty"`
+
+	// Длительности запусков (wall-clock).
+	LastDurationMs float64 `json:"lastDurationMs"`
+	AvgDurationMs  float64 `json:"avgDurationMs"`
+	MaxDurationMs  float64 `json:"maxDurationMs"`
+}
+
+// WorldMapResponse — карта города, GET /world/map.
+type WorldMapResponse struct {
+	Zones []ZoneDTO `json:"zones"`
+}
+
+// ZoneDTO — зона города с соседями и агентами в ней.
+type ZoneDTO struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// X, Y — положение на карте, 0..1.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+
+	// Neighbors — id смежных зон; переходить можно только в них.
+	Neighbors []string `json:"neighbors"`
+
+	// Agents — id активных агентов в зоне.
+	Agents []string `json:"agents"`
+}
+
 // WorldStatisticsResponse — агрегированная статистика мира, GET /api/v1/world/statistics.
 type WorldStatisticsResponse struct {
 	// MoodDistribution — распределение настроений: {"happy": 3, "anxious": 1, ...}.
@@ -315,6 +606,102 @@ type ActivityMetricsDTO struct {
 	EventsLastHour         int `json:"eventsLastHour"`
 }
 
+// LLMStatsResponse — состояние планировщика LLM, GET /world/llm.
+type LLMStatsResponse struct {
+	// MaxInFlight — лимит одновременных запросов к модели.
+	MaxInFlight int `json:"maxInFlight"`
+
+	// InFlight — сколько запросов выполняется прямо сейчас.
+	InFlight int `json:"inFlight"`
+
+	// QueueDepth — сколько запросов ждут слота.
+	QueueDepth int `json:"queueDepth"`
+
+	// ByPurpose — статистика по назначению вызова ("dialogue", "reflection").
+	ByPurpose map[string]LLMPurposeStatsDTO `json:"byPurpose"`
+}
+
+// LLMPurposeStatsDTO — очередь и ожидание для одного назначения вызова.
+type LLMPurposeStatsDTO struct {
+	Queued    int     `json:"queued"`
+	Granted   int64   `json:"granted"`
+	Canceled  int64   `json:"canceled"`
+	AvgWaitMs float64 `json:"avgWaitMs"`
+	MaxWaitMs float64 `json:"maxWaitMs"`
+}
+
+// UsageResponse — расход LLM, ответ на GET /agents/{id}/usage и GET /world/usage.
+type UsageResponse struct {
+	// Totals — суммарные токены и задержка.
+	Totals UsageTotalsDTO `json:"totals"`
+
+	// ByPurpose — разбивка по когнитивным задачам ("dialogue", "reflection").
+	ByPurpose []UsageGroupDTO `json:"byPurpose"`
+
+	// ByModel — разбивка по моделям.
+	ByModel []UsageGroupDTO `json:"byModel"`
+
+	// ByAgent — разбивка по агентам (только для /world/usage).
+	ByAgent []UsageGroupDTO `json:"byAgent,omitempty"`
+}
+
+// UsageTotalsDTO — агрегат по набору вызовов LLM.
+type UsageTotalsDTO struct {
+	Calls            int     `json:"calls"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	TotalTokens      int64   `json:"totalTokens"`
+	TotalLatencyMs   int64   `json:"totalLatencyMs"`
+	AvgLatencyMs     float64 `json:"avgLatencyMs"`
+}
+
+// UsageGroupDTO — агрегат по одному значению измерения.
+type UsageGroupDTO struct {
+	// Key — назначение, модель или ID агента.
+	Key string `json:"key"`
+
+	// Name — имя агента (только в byAgent).
+	Name string `json:"name,omitempty"`
+
+	UsageTotalsDTO
+}
+
+// RoutingResponse — правила выбора модели, GET /world/routing.
+type RoutingResponse struct {
+	// File — правила из LLM_ROUTING_FILE.
+	File RoutingConfigDTO `json:"file"`
+
+	// Overrides — правила, заданные через API. Важнее правил файла.
+	Overrides RoutingConfigDTO `json:"overrides"`
+}
+
+// RoutingConfigDTO — набор правил маршрутизации.
+type RoutingConfigDTO struct {
+	// Default — модель для вызовов без более точного правила.
+	Default string `json:"default,omitempty"`
+
+	// Purposes — модель по назначению: {"reflection": "gemma3:12b"}.
+	Purposes map[string]string `json:"purposes,omitempty"`
+
+	// Agents — правила по ID агента.
+	Agents map[string]AgentRoutingDTO `json:"agents,omitempty"`
+}
+
+// AgentRoutingDTO — правила одного агента.
+type AgentRoutingDTO struct {
+	Default  string            `json:"default,omitempty"`
+	Purposes map[string]string `json:"purposes,omitempty"`
+}
+
+// SetRoutingRequest — задать или снять правило, PUT /world/routing.
+// Без agentId и purpose правило становится default; пустой model снимает правило.
+type SetRoutingRequest struct {
+	AgentID string `json:"agentId,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	Model   string `json:"model"`
+}
+
 // ResetRequest — сброс мира, POST /api/v1/control/reset.
 type ResetRequest struct {
 	Confirm        bool `json:"confirm" binding:"required"`
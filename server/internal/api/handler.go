@@ -1,15 +1,80 @@
 // server/internal/api/handler.go
 package api
 
-import "milk/server/internal/storage"
+import (
+	"milk/server/internal/moderation"
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
 
 // Handler — HTTP-обработчики с доступом к хранилищу и SSE hub.
 type Handler struct {
 	repo *storage.Repository
 	hub  *Hub
+
+	// scheduler — планировщик LLM для GET /world/llm. nil = статистика недоступна.
+	scheduler *llm.Scheduler
+
+	// router — маршрутизатор моделей для /world/routing. nil = правила недоступны.
+	router *llm.Router
+
+	// moderator — модерация инъекций человека. nil = без проверки.
+	moderator *moderation.Moderator
+
+	// sim — управление часами симуляции для POST /world/control.
+	sim     SimulationController
+	publish PublishFunc
+}
+
+// SimulationController — управление часами симуляции.
+// Реализуется world.Orchestrator; api не импортирует world.
+type SimulationController interface {
+	Pause() error
+	Resume() error
+	Step() error
+	SetSpeed(speed float64) error
+
+	// ScheduledTasks — снимок задач на часах симуляции.
+	ScheduledTasks() (tick int64, tasks []ScheduledTaskDTO)
 }
 
+// PublishFunc публикует событие в шину мира (world.EventBus).
+// topic — имя топика ("interaction", "system"); payload["message"] попадает в SSE-ленту.
+type PublishFunc func(topic, eventType string, affectedAgents []string, payload map[string]any)
+
 // NewHandler создаёт Handler с инъекцией зависимости Repository и SSE Hub.
 func NewHandler(repo *storage.Repository, hub *Hub) *Handler {
 	return &Handler{repo: repo, hub: hub}
 }
+
+// UseScheduler подключает планировщик LLM для отдачи статистики очереди.
+func (h *Handler) UseScheduler(s *llm.Scheduler) {
+	h.scheduler = s
+}
+
+// UseRouter подключает маршрутизатор моделей для чтения и правки правил.
+func (h *Handler) UseRouter(r *llm.Router) {
+	h.router = r
+}
+
+// UseModerator подключает модерацию сообщений человека.
+func (h *Handler) UseModerator(m *moderation.Moderator) {
+	h.moderator = m
+}
+
+// UsePublisher подключает публикацию событий API в шину мира.
+func (h *Handler) UsePublisher(p PublishFunc) {
+	h.publish = p
+}
+
+// publishEvent публикует событие, если шина подключена.
+func (h *Handler) publishEvent(topic, eventType string, affectedAgents []string, payload map[string]any) {
+	if h.publish != nil {
+		h.publish(topic, eventType, affectedAgents, payload)
+	}
+}
+
+// UseSimulation подключает управление симуляцией.
+func (h *Handler) UseSimulation(c SimulationController) {
+	h.sim = c
+}
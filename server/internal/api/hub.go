@@ -2,24 +2,138 @@ package api
 
 import (
 	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // SSEEvent — событие, отправляемое клиентам через SSE.
+// Topic уходит в строку "event:", остальное — JSON в "data:".
+// Speaker/Target/Content заполнены для реплик диалога; Content у прочих
+// событий — текст для ленты (payload "message"), если он есть.
 type SSEEvent struct {
-	Type    string `json:"type"`
-	Speaker string `json:"speaker,omitempty"`
-	Target  string `json:"target,omitempty"`
-	Content string `json:"content"`
-	AgentID string `json:"agentId,omitempty"`
-	Tick    int64  `json:"tick"`
+	// Seq — монотонный номер события (строка "id:"), 0 = без номера.
+	Seq int64 `json:"seq,omitempty"`
+
+	ID             string         `json:"id,omitempty"`
+	Topic          string         `json:"topic"`
+	Type           string         `json:"type"`
+	Speaker        string         `json:"speaker,omitempty"`
+	Target         string         `json:"target,omitempty"`
+	Content        string         `json:"content"`
+	AgentID        string         `json:"agentId,omitempty"`
+	AffectedAgents []string       `json:"affectedAgents,omitempty"`
+	Payload        map[string]any `json:"payload,omitempty"`
+	Tick           int64          `json:"tick"`
+
+	// ConversationID — разговор, к которому относится событие.
+	ConversationID string `json:"conversationId,omitempty"`
+}
+
+// StreamFilter — фильтр SSE-клиента (?topics=...&agents=...).
+// Пустое множество = без фильтра.
+type StreamFilter struct {
+	Topics map[string]bool
+	Agents map[string]bool
+}
+
+// ParseStreamFilter разбирает списки через запятую.
+func ParseStreamFilter(topics, agents string) StreamFilter {
+	return StreamFilter{Topics: csvSet(topics), Agents: csvSet(agents)}
+}
+
+func csvSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+// Match — проходит ли событие фильтр. Фильтр по агентам применяется
+// только к событиям, затрагивающим агентов: общемировые события
+// (пауза, статистика) видны всем.
+func (f StreamFilter) Match(evt SSEEvent) bool {
+	if len(f.Topics) > 0 && !f.Topics[evt.Topic] {
+		return false
+	}
+	if len(f.Agents) == 0 || (evt.AgentID == "" && len(evt.AffectedAgents) == 0) {
+		return true
+	}
+	if f.Agents[evt.AgentID] {
+		return true
+	}
+	for _, id := range evt.AffectedAgents {
+		if f.Agents[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// Размеры буферов SSE.
+const (
+	replayRingSize = 1000 // последних событий в памяти для досылки
+	replayLimit    = 1000 // максимум событий за одну досылку из БД
+	clientBuffer   = 64   // буфер канала клиента
+)
+
+// ReplaySource досылает события из постоянного хранилища: до limit
+// событий с Seq > after, старые первыми.
+type ReplaySource func(after int64, limit int) ([]SSEEvent, error)
+
+// sseFrame — готовый кадр SSE и событие, из которого он собран (для фильтров).
+type sseFrame struct {
+	seq  int64
+	evt  SSEEvent
+	data []byte
+}
+
+// StreamClient — подключённый SSE-клиент.
+type StreamClient struct {
+	ID          string
+	Remote      string
+	Filter      StreamFilter
+	ConnectedAt time.Time
+
+	// Sent, Dropped — отправленные и отброшенные (буфер полон) события.
+	Sent    atomic.Int64
+	Dropped atomic.Int64
+
+	// LastSeq — номер последнего отправленного события.
+	LastSeq atomic.Int64
+
+	ch       chan sseFrame
+	overflow chan struct{}
+	once     sync.Once
+}
+
+// Overflow закрывается при первой потере события: обработчик рвёт
+// соединение, и клиент переподключается с Last-Event-ID.
+func (c *StreamClient) Overflow() <-chan struct{} {
+	return c.overflow
 }
 
 // Hub — in-memory SSE broadcast hub.
-// Хранит подписчиков (каналы) и очередь инъекций от человека.
+// Хранит подписчиков с фильтрами, кольцо последних событий для досылки
+// после переподключения и очередь инъекций от человека.
 type Hub struct {
 	mu          sync.RWMutex
-	subscribers map[chan []byte]struct{}
+	subscribers map[*StreamClient]struct{}
+
+	// ring — последние события с Seq > 0, по возрастанию Seq.
+	ring []sseFrame
+
+	// replay — досылка из БД для Last-Event-ID старше кольца. nil = только кольцо.
+	replay ReplaySource
 
 	injMu      sync.Mutex
 	injections map[string][]string // agentID → []message
@@ -28,45 +142,151 @@ type Hub struct {
 // NewHub создаёт Hub.
 func NewHub() *Hub {
 	return &Hub{
-		subscribers: make(map[chan []byte]struct{}),
+		subscribers: make(map[*StreamClient]struct{}),
 		injections:  make(map[string][]string),
 	}
 }
 
-// Subscribe регистрирует нового SSE-клиента и возвращает его канал.
-func (h *Hub) Subscribe() chan []byte {
-	ch := make(chan []byte, 32)
+// UseReplaySource подключает досылку из БД.
+func (h *Hub) UseReplaySource(src ReplaySource) {
 	h.mu.Lock()
-	h.subscribers[ch] = struct{}{}
+	h.replay = src
 	h.mu.Unlock()
-	return ch
 }
 
-// Unsubscribe удаляет клиента и закрывает его канал.
-func (h *Hub) Unsubscribe(ch chan []byte) {
+// Subscribe регистрирует нового SSE-клиента с фильтром.
+func (h *Hub) Subscribe(filter StreamFilter, remote string) *StreamClient {
+	c := &StreamClient{
+		ID:          uuid.New().String(),
+		Remote:      remote,
+		Filter:      filter,
+		ConnectedAt: time.Now(),
+		ch:          make(chan sseFrame, clientBuffer),
+		overflow:    make(chan struct{}),
+	}
 	h.mu.Lock()
-	delete(h.subscribers, ch)
+	h.subscribers[c] = struct{}{}
 	h.mu.Unlock()
-	close(ch)
+	return c
 }
 
-// Broadcast пушит событие всем подключённым клиентам.
+// Unsubscribe удаляет клиента.
+func (h *Hub) Unsubscribe(c *StreamClient) {
+	h.mu.Lock()
+	delete(h.subscribers, c)
+	h.mu.Unlock()
+}
+
+// Broadcast пушит событие клиентам, чей фильтр оно проходит,
+// и запоминает его в кольце досылки.
 func (h *Hub) Broadcast(evt SSEEvent) {
 	data, err := json.Marshal(evt)
 	if err != nil {
 		return
 	}
-	msg := append([]byte("data: "), data...)
-	msg = append(msg, '\n', '\n')
+	f := sseFrame{seq: evt.Seq, evt: evt, data: formatSSE(evt.Topic, evt.Seq, data)}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	for ch := range h.subscribers {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if f.seq > 0 {
+		h.remember(f)
+	}
+	for c := range h.subscribers {
+		if !c.Filter.Match(evt) {
+			continue
+		}
 		select {
-		case ch <- msg:
+		case c.ch <- f:
 		default:
+			c.Dropped.Add(1)
+			c.once.Do(func() { close(c.overflow) })
+		}
+	}
+}
+
+// remember вставляет кадр в кольцо по его Seq: шина может разослать
+// события не по порядку (приоритет). Вызывается под h.mu.
+func (h *Hub) remember(f sseFrame) {
+	i := sort.Search(len(h.ring), func(i int) bool { return h.ring[i].seq > f.seq })
+	h.ring = slices.Insert(h.ring, i, f)
+	if len(h.ring) > replayRingSize {
+		h.ring = h.ring[len(h.ring)-replayRingSize:]
+	}
+}
+
+// framesAfter возвращает кадры событий с Seq > after, проходящие фильтр:
+// из кольца, если оно покрывает after, иначе из ReplaySource.
+func (h *Hub) framesAfter(after int64, filter StreamFilter) ([]sseFrame, error) {
+	h.mu.RLock()
+	covered := len(h.ring) > 0 && h.ring[0].seq <= after+1
+	var frames []sseFrame
+	if covered {
+		for _, f := range h.ring {
+			if f.seq > after && filter.Match(f.evt) {
+				frames = append(frames, f)
+			}
 		}
 	}
+	src := h.replay
+	h.mu.RUnlock()
+
+	if covered || src == nil {
+		return frames, nil
+	}
+	events, err := src(after, replayLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, evt := range events {
+		if !filter.Match(evt) {
+			continue
+		}
+		data, err := json.Marshal(evt)
+		if err != nil {
+			continue
+		}
+		frames = append(frames, sseFrame{seq: evt.Seq, evt: evt, data: formatSSE(evt.Topic, evt.Seq, data)})
+	}
+	return frames, nil
+}
+
+// Clients возвращает снимок подключённых клиентов.
+func (h *Hub) Clients() []*StreamClient {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]*StreamClient, 0, len(h.subscribers))
+	for c := range h.subscribers {
+		out = append(out, c)
+	}
+	return out
+}
+
+// RingBounds — Seq самого старого и самого нового события в кольце (0, 0 — пусто).
+func (h *Hub) RingBounds() (oldest, latest int64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.ring) == 0 {
+		return 0, 0
+	}
+	return h.ring[0].seq, h.ring[len(h.ring)-1].seq
+}
+
+// formatSSE собирает кадр SSE с номером и именем события.
+func formatSSE(name string, seq int64, data []byte) []byte {
+	msg := make([]byte, 0, len(name)+len(data)+32)
+	if seq > 0 {
+		msg = append(msg, "id: "...)
+		msg = strconv.AppendInt(msg, seq, 10)
+		msg = append(msg, '\n')
+	}
+	if name != "" {
+		msg = append(msg, "event: "...)
+		msg = append(msg, name...)
+		msg = append(msg, '\n')
+	}
+	msg = append(msg, "data: "...)
+	msg = append(msg, data...)
+	return append(msg, '\n', '\n')
 }
 
 // Inject добавляет сообщение человека в очередь агента.
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamFilterMatch(t *testing.T) {
+	f := ParseStreamFilter("interaction, system", "alice,,bob")
+	tests := []struct {
+		evt  SSEEvent
+		want bool
+	}{
+		{SSEEvent{Topic: "interaction", AffectedAgents: []string{"carol", "bob"}}, true},
+		{SSEEvent{Topic: "interaction", AgentID: "alice"}, true},
+		{SSEEvent{Topic: "interaction", AffectedAgents: []string{"carol"}}, false},
+		// Общемировые события проходят фильтр по агентам.
+		{SSEEvent{Topic: "system", Type: "pause"}, true},
+		{SSEEvent{Topic: "mood_change", AgentID: "alice"}, false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.evt); got != tt.want {
+			t.Errorf("Match(%+v) = %v, want %v", tt.evt, got, tt.want)
+		}
+	}
+	if !ParseStreamFilter("", "").Match(SSEEvent{Topic: "memory", AgentID: "x"}) {
+		t.Error("empty filter rejected an event")
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	h := NewHub()
+	all := h.Subscribe(StreamFilter{}, "test")
+	moods := h.Subscribe(ParseStreamFilter("mood_change", ""), "test")
+	defer h.Unsubscribe(all)
+	defer h.Unsubscribe(moods)
+
+	h.Broadcast(SSEEvent{Seq: 7, Topic: "interaction", Type: "conversation", Speaker: "Алиса", Content: "Привет", Tick: 3})
+
+	msg := string((<-all.ch).data)
+	if !strings.HasPrefix(msg, "id: 7\nevent: interaction\ndata: {") || !strings.HasSuffix(msg, "}\n\n") {
+		t.Errorf("frame = %q", msg)
+	}
+	if !strings.Contains(msg, `"speaker":"Алиса"`) || !strings.Contains(msg, `"tick":3`) {
+		t.Errorf("frame data = %q", msg)
+	}
+	select {
+	case f := <-moods.ch:
+		t.Errorf("filtered client got %q", f.data)
+	default:
+	}
+}
+
+func TestHubFramesAfter(t *testing.T) {
+	h := NewHub()
+	var asked []int64
+	h.UseReplaySource(func(after int64, limit int) ([]SSEEvent, error) {
+		asked = append(asked, after)
+		var out []SSEEvent
+		for seq := after + 1; seq <= 6; seq++ {
+			out = append(out, SSEEvent{Seq: seq, Topic: "interaction"})
+		}
+		return out, nil
+	})
+	// Шина может разослать события не по порядку — кольцо упорядочено по Seq.
+	for _, seq := range []int64{4, 6, 5} {
+		h.Broadcast(SSEEvent{Seq: seq, Topic: "interaction"})
+	}
+	h.Broadcast(SSEEvent{Topic: "system", Type: "stats"}) // без номера — не в кольце
+
+	seqs := func(frames []sseFrame) (out []int64) {
+		for _, f := range frames {
+			out = append(out, f.seq)
+		}
+		return out
+	}
+
+	// Кольцо покрывает разрыв: БД не читается.
+	frames, err := h.framesAfter(3, StreamFilter{})
+	if err != nil || len(asked) != 0 || !equalSeqs(seqs(frames), 4, 5, 6) {
+		t.Errorf("ring replay = %v, %v; db asked %v", seqs(frames), err, asked)
+	}
+	// Разрыв старше кольца досылается из БД.
+	frames, _ = h.framesAfter(1, StreamFilter{})
+	if !equalSeqs(asked, 1) || !equalSeqs(seqs(frames), 2, 3, 4, 5, 6) {
+		t.Errorf("db replay = %v; db asked %v", seqs(frames), asked)
+	}
+	if frames, _ := h.framesAfter(3, ParseStreamFilter("system", "")); len(frames) != 0 {
+		t.Errorf("filtered replay = %v", seqs(frames))
+	}
+	if oldest, latest := h.RingBounds(); oldest != 4 || latest != 6 {
+		t.Errorf("ring bounds = %d..%d, want 4..6", oldest, latest)
+	}
+}
+
+func equalSeqs(got []int64, want ...int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEventsStreamResume(t *testing.T) {
+	hub := NewHub()
+	for seq := int64(1); seq <= 3; seq++ {
+		hub.Broadcast(SSEEvent{Seq: seq, Topic: "interaction"})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(NewHandler(nil, hub).EventsStream))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	frames := bufio.NewReader(resp.Body)
+
+	// nextID читает кадры до следующего с номером и возвращает строку "id:".
+	nextID := func() string {
+		for {
+			var frame []string
+			for {
+				line, err := frames.ReadString('\n')
+				if err != nil {
+					t.Fatalf("read stream: %v", err)
+				}
+				if line == "\n" {
+					break
+				}
+				frame = append(frame, strings.TrimSpace(line))
+			}
+			if strings.HasPrefix(frame[0], "id: ") {
+				return frame[0]
+			}
+		}
+	}
+
+	for _, want := range []string{"id: 2", "id: 3"} {
+		if got := nextID(); got != want {
+			t.Fatalf("replayed %q, want %q", got, want)
+		}
+	}
+	// Событие, уже отданное досылкой, не повторяется из живого потока,
+	// а события не по порядку Seq не отбрасываются.
+	hub.Broadcast(SSEEvent{Seq: 3, Topic: "interaction"})
+	hub.Broadcast(SSEEvent{Seq: 5, Topic: "interaction"})
+	hub.Broadcast(SSEEvent{Seq: 4, Topic: "interaction"})
+	for _, want := range []string{"id: 5", "id: 4"} {
+		if got := nextID(); got != want {
+			t.Errorf("live frame = %q, want %q", got, want)
+		}
+	}
+
+	bad, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	bad.Header.Set("Last-Event-ID", "abc")
+	resp, err = http.DefaultClient.Do(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID = %d, want 400", resp.StatusCode)
+	}
+}
@@ -35,6 +35,7 @@ const (
 	ErrCodeBadRequest     = "BAD_REQUEST"      // 400 — невалидный JSON, отсутствуют поля
 	ErrCodeNotFound       = "NOT_FOUND"        // 404 — агент/событие/связь не найдены
 	ErrCodeConflict       = "CONFLICT"         // 409 — дублирование (например, связь уже есть)
+	ErrCodeContentBlocked = "CONTENT_BLOCKED"  // 422 — текст заблокирован модерацией
 	ErrCodeRateLimited    = "RATE_LIMITED"      // 429 — превышен лимит запросов
 	ErrCodeInternalError  = "INTERNAL_ERROR"    // 500 — внутренняя ошибка сервера
 )
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"milk/server/internal/storage"
+)
+
+// moderationTopic — топик событий с решениями модерации.
+const moderationTopic = "moderation"
+
+// ListModerationDecisions — GET /moderation/decisions
+// Query params: ?status=pending&limit=50
+func (h *Handler) ListModerationDecisions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	events, err := h.repo.GetEvents(storage.EventFilter{
+		Topic:  moderationTopic,
+		Status: q.Get("status"),
+		Limit:  limit,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to list moderation decisions")
+		return
+	}
+
+	resp := ModerationDecisionsResponse{Decisions: make([]ModerationDecisionDTO, 0, len(events))}
+	for _, e := range events {
+		resp.Decisions = append(resp.Decisions, moderationDecisionToDTO(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ReviewModerationDecision — POST /moderation/decisions/{id}/review
+// Оператор подтверждает решение или отмечает ложное срабатывание.
+func (h *Handler) ReviewModerationDecision(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req ModerationReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Verdict != "approved" && req.Verdict != "rejected" {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "verdict must be approved or rejected")
+		return
+	}
+
+	rec, err := h.repo.GetEventByID(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get decision")
+		return
+	}
+	if rec == nil || rec.Topic != moderationTopic {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "moderation decision not found")
+		return
+	}
+
+	if err := h.repo.UpdateEventStatus(id, req.Verdict); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to save review")
+		return
+	}
+	rec.Status = req.Verdict
+	writeJSON(w, http.StatusOK, moderationDecisionToDTO(*rec))
+}
+
+func moderationDecisionToDTO(e storage.EventRecord) ModerationDecisionDTO {
+	dto := ModerationDecisionDTO{
+		ID:        e.ID,
+		Status:    e.Status,
+		Timestamp: e.CreatedAt,
+	}
+	if e.Tick.Valid {
+		dto.Tick = e.Tick.Int64
+	}
+	// Payload записан ModerationRecorder с теми же JSON-ключами, что у DTO.
+	if e.Payload.Valid {
+		json.Unmarshal([]byte(e.Payload.String), &dto)
+	}
+	return dto
+}
@@ -0,0 +1,383 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"milk/server/internal/storage"
+)
+
+// relationshipDims — измерения связи по умолчанию для типа.
+type relationshipDims struct {
+	trust, affection, respect float64
+}
+
+// relationshipTypes — допустимые типы связи и их измерения по умолчанию.
+var relationshipTypes = map[string]relationshipDims{
+	"neutral":   {0, 0, 0},
+	"friend":    {0.5, 0.5, 0.4},
+	"close":     {0.8, 0.8, 0.6},
+	"rival":     {-0.5, -0.6, -0.2},
+	"romantic":  {0.6, 0.9, 0.5},
+	"mentor":    {0.6, 0.3, 0.8}, // agent2 — наставник agent1
+	"student":   {0.4, 0.4, 0.3}, // agent2 — ученик agent1
+	"family":    {0.6, 0.7, 0.4},
+	"colleague": {0.3, 0.1, 0.4},
+}
+
+// reciprocalTypes — тип обратного отношения, если он отличается.
+var reciprocalTypes = map[string]string{
+	"mentor":  "student",
+	"student": "mentor",
+}
+
+// Сколько событий и разговоров поднимать для истории связей агента.
+const (
+	relationshipHistoryEvents = 200
+	relationshipConversations = 5
+)
+
+// ListRelationshipGraph — GET /relationships
+// Направленный граф отношений активных агентов.
+func (h *Handler) ListRelationshipGraph(w http.ResponseWriter, r *http.Request) {
+	graph, err := h.repo.GetRelationshipGraph()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to load relationship graph")
+		return
+	}
+
+	resp := RelationshipGraphResponse{
+		Nodes: make([]GraphNodeDTO, 0, len(graph.Nodes)),
+		Edges: make([]GraphEdgeDTO, 0, len(graph.Edges)),
+	}
+	for _, n := range graph.Nodes {
+		resp.Nodes = append(resp.Nodes, GraphNodeDTO{
+			ID:    n.ID,
+			Label: n.Name,
+			Type:  personalityType(parsePersonality(n.Personality)),
+			Size:  n.RelationCount,
+		})
+	}
+	edges := make(map[[2]string]bool, len(graph.Edges))
+	for _, e := range graph.Edges {
+		edges[[2]string{e.Source, e.Target}] = true
+	}
+	for _, e := range graph.Edges {
+		resp.Edges = append(resp.Edges, GraphEdgeDTO{
+			Source:    e.Source,
+			Target:    e.Target,
+			Type:      e.Type,
+			Strength:  e.Strength,
+			Trust:     e.Trust,
+			Affection: e.Affection,
+			Respect:   e.Respect,
+			Mutual:    edges[[2]string{e.Target, e.Source}],
+			Label:     e.Label,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetAgentRelationships — GET /relationships/{agentId}
+// Связи агента в обе стороны с историей: смены типа, правки оператора
+// и последние разговоры с каждым из собеседников.
+func (h *Handler) GetAgentRelationships(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("agentId")
+	rec, err := h.repo.GetAgentByID(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get agent")
+		return
+	}
+	if rec == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "agent not found")
+		return
+	}
+
+	rels, err := h.repo.ListRelationshipsByAgent(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to list relationships")
+		return
+	}
+	events, err := h.repo.GetEvents(storage.EventFilter{Topic: "relationship", AgentID: id, Limit: relationshipHistoryEvents})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to load relationship history")
+		return
+	}
+	conversations, err := h.repo.ListConversations(storage.ConversationFilter{AgentID: id, Limit: 100})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to list conversations")
+		return
+	}
+
+	// Связи по id собеседника, в порядке самой сильной из двух сторон
+	byOther := make(map[string]*RelationshipDTO)
+	var order []string
+	for _, rel := range rels {
+		other, outgoing := rel.Agent2ID, true
+		if rel.Agent2ID == id {
+			other, outgoing = rel.Agent1ID, false
+		}
+		dto, ok := byOther[other]
+		if !ok {
+			dto = &RelationshipDTO{}
+			byOther[other] = dto
+			order = append(order, other)
+		}
+		edge := relationshipToDTO(rel)
+		if outgoing {
+			dto.Outgoing = &edge
+		} else {
+			dto.Incoming = &edge
+		}
+	}
+
+	for _, e := range events {
+		other, change, ok := relationshipChange(e, id)
+		if dto := byOther[other]; ok && dto != nil {
+			dto.History = append(dto.History, change)
+		}
+	}
+	// Собеседники и участники разговоров — одним запросом
+	ids := append([]string(nil), order...)
+	for _, c := range conversations {
+		ids = append(ids, c.Participants...)
+	}
+	agents, err := h.repo.GetAgentsByIDs(ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get agents")
+		return
+	}
+	names := func(id string) string { return agents[id].Name }
+	for _, c := range conversations {
+		conv := conversationToDTO(c, names)
+		for _, other := range c.Participants {
+			if dto := byOther[other]; dto != nil && len(dto.Conversations) < relationshipConversations {
+				dto.Conversations = append(dto.Conversations, conv)
+			}
+		}
+	}
+
+	resp := AgentRelationshipsResponse{
+		Agent:         recordToSummary(*rec),
+		Relationships: make([]RelationshipDTO, 0, len(order)),
+	}
+	for _, other := range order {
+		dto := byOther[other]
+		if o, ok := agents[other]; ok {
+			dto.With = recordToSummary(o)
+		} else {
+			dto.With = AgentSummary{ID: other}
+		}
+		resp.Relationships = append(resp.Relationships, *dto)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateRelationship — POST /relationships
+// Оператор задаёт отношение agent1 к agent2 (и, с reciprocal, обратное).
+// Связь уже есть — 409, если не указан force. Заметка оператора попадает
+// в промпты агентов при их следующей встрече.
+func (h *Handler) CreateRelationship(w http.ResponseWriter, r *http.Request) {
+	var req CreateRelationshipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Agent1ID == "" || req.Agent2ID == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "agent1 and agent2 are required")
+		return
+	}
+	if req.Agent1ID == req.Agent2ID {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "agent1 and agent2 must differ")
+		return
+	}
+	dims, ok := relationshipTypes[req.Type]
+	if !ok {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "type must be one of "+relationshipTypeList())
+		return
+	}
+	if req.Strength != nil {
+		dims = relationshipDims{*req.Strength, *req.Strength, *req.Strength}
+	}
+	for _, d := range []struct {
+		name string
+		v    *float64
+		dst  *float64
+	}{
+		{"strength", req.Strength, nil},
+		{"trust", req.Trust, &dims.trust},
+		{"affection", req.Affection, &dims.affection},
+		{"respect", req.Respect, &dims.respect},
+	} {
+		if d.v == nil {
+			continue
+		}
+		if *d.v < -1 || *d.v > 1 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, d.name+" must be between -1 and 1")
+			return
+		}
+		if d.dst != nil {
+			*d.dst = *d.v
+		}
+	}
+
+	var agents [2]*storage.AgentRecord
+	for i, id := range []string{req.Agent1ID, req.Agent2ID} {
+		rec, err := h.repo.GetAgentByID(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get agent")
+			return
+		}
+		if rec == nil {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "agent not found: "+id)
+			return
+		}
+		agents[i] = rec
+	}
+
+	meta, _ := json.Marshal(map[string]any{
+		"note":  req.Note,
+		"setBy": "operator",
+		"setAt": time.Now().UTC(),
+	})
+	edge := storage.RelationshipRecord{
+		Agent1ID:  req.Agent1ID,
+		Agent2ID:  req.Agent2ID,
+		Type:      req.Type,
+		Trust:     dims.trust,
+		Affection: dims.affection,
+		Respect:   dims.respect,
+		Metadata:  sql.NullString{String: string(meta), Valid: true},
+	}
+	edges := []storage.RelationshipRecord{edge}
+	if req.Reciprocal {
+		back := edge
+		back.Agent1ID, back.Agent2ID = edge.Agent2ID, edge.Agent1ID
+		if t, ok := reciprocalTypes[req.Type]; ok {
+			back.Type = t
+			if req.Strength == nil && req.Trust == nil && req.Affection == nil && req.Respect == nil {
+				d := relationshipTypes[t]
+				back.Trust, back.Affection, back.Respect = d.trust, d.affection, d.respect
+			}
+		}
+		edges = append(edges, back)
+	}
+
+	after, before, err := h.repo.SetRelationships(edges, req.Force)
+	switch {
+	case errors.Is(err, storage.ErrRelationshipExists):
+		for _, b := range before {
+			if b != nil {
+				writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf(
+					"relationship already exists (%s, %.2f); set force to overwrite", b.Type, b.Strength))
+				return
+			}
+		}
+		writeError(w, http.StatusConflict, ErrCodeConflict, "relationship already exists; set force to overwrite")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to save relationship")
+		return
+	}
+
+	status, from := http.StatusCreated, ""
+	if before[0] != nil {
+		status, from = http.StatusOK, before[0].Type
+	}
+	message := fmt.Sprintf("Operator set %s's view of %s to %s", agents[0].Name, agents[1].Name, after[0].Type)
+	if req.Reciprocal {
+		message = fmt.Sprintf("Operator set %s and %s as %s / %s", agents[0].Name, agents[1].Name, after[0].Type, after[1].Type)
+	}
+	h.publishEvent("relationship", "relationship_set", []string{req.Agent1ID, req.Agent2ID}, map[string]any{
+		"relationshipId": after[0].ID,
+		"agent1":         req.Agent1ID,
+		"agent2":         req.Agent2ID,
+		"from":           from,
+		"to":             after[0].Type,
+		"strength":       after[0].Strength,
+		"reciprocal":     req.Reciprocal,
+		"note":           req.Note,
+		"message":        message,
+	})
+
+	out := edgePtr(relationshipToDTO(after[0]))
+	resp := RelationshipDTO{With: recordToSummary(*agents[1]), Outgoing: out}
+	if len(after) > 1 {
+		resp.Incoming = edgePtr(relationshipToDTO(after[1]))
+	}
+	writeJSON(w, status, resp)
+}
+
+func relationshipToDTO(rel storage.RelationshipRecord) RelationshipEdgeDTO {
+	dto := RelationshipEdgeDTO{
+		ID:               rel.ID,
+		Type:             rel.Type,
+		Strength:         rel.Strength,
+		Trust:            rel.Trust,
+		Affection:        rel.Affection,
+		Respect:          rel.Respect,
+		Note:             storage.RelationshipNote(rel.Metadata),
+		InteractionCount: rel.InteractionCount,
+	}
+	if rel.LastInteraction.Valid {
+		dto.LastInteraction = &rel.LastInteraction.Time
+	}
+	return dto
+}
+
+func edgePtr(e RelationshipEdgeDTO) *RelationshipEdgeDTO {
+	return &e
+}
+
+// relationshipTypeList — допустимые типы через запятую, по алфавиту.
+func relationshipTypeList() string {
+	types := make([]string, 0, len(relationshipTypes))
+	for t := range relationshipTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// relationshipChange разбирает событие топика "relationship" с точки
+// зрения агента id: возвращает собеседника и запись истории.
+func relationshipChange(e storage.EventRecord, id string) (string, RelationshipChangeDTO, bool) {
+	var p struct {
+		Agent1     string  `json:"agent1"`
+		Agent2     string  `json:"agent2"`
+		From       string  `json:"from"`
+		To         string  `json:"to"`
+		Strength   float64 `json:"strength"`
+		Reciprocal bool    `json:"reciprocal"`
+		Message    string  `json:"message"`
+	}
+	if !e.Payload.Valid || json.Unmarshal([]byte(e.Payload.String), &p) != nil {
+		return "", RelationshipChangeDTO{}, false
+	}
+	other, direction := p.Agent2, "outgoing"
+	if other == id {
+		other, direction = p.Agent1, "incoming"
+	}
+	if other == "" || other == id {
+		return "", RelationshipChangeDTO{}, false
+	}
+	if p.Reciprocal {
+		direction = "mutual"
+	}
+	return other, RelationshipChangeDTO{
+		Type:      e.Type,
+		Direction: direction,
+		From:      p.From,
+		To:        p.To,
+		Strength:  p.Strength,
+		Message:   p.Message,
+		Tick:      e.Tick.Int64,
+		Timestamp: e.CreatedAt,
+	}, true
+}
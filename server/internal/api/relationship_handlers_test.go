@@ -0,0 +1,121 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"milk/server/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestHandler — Handler над мигрированной базой с агентами a и b.
+func newTestHandler(t *testing.T) (*Handler, *storage.Repository) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "society.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo := storage.NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	for _, a := range []struct{ id, name string }{{"a", "Alice"}, {"b", "Bob"}} {
+		if err := repo.CreateAgent(storage.AgentRecord{ID: a.id, Name: a.name, Personality: "{}", State: "idle", IsActive: true}); err != nil {
+			t.Fatalf("CreateAgent: %v", err)
+		}
+	}
+	return NewHandler(repo, NewHub()), repo
+}
+
+func TestCreateRelationship(t *testing.T) {
+	h, repo := newTestHandler(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /relationships", h.CreateRelationship)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/relationships", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"agent1":"a","agent2":"b","type":"friend"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s, want 201", rec.Code, rec.Body)
+	}
+
+	// Повторное отношение a к b без force — 409, существующее не меняется.
+	rec := post(`{"agent1":"a","agent2":"b","type":"rival"}`)
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusConflict || apiErr.Code != ErrCodeConflict {
+		t.Fatalf("duplicate = %d %s, want 409 %s", rec.Code, rec.Body, ErrCodeConflict)
+	}
+	if rel, _ := repo.GetRelationship("a", "b"); rel == nil || rel.Type != "friend" {
+		t.Errorf("a → b after 409 = %+v", rel)
+	}
+
+	// Обратное отношение — отдельное ребро.
+	if rec := post(`{"agent1":"b","agent2":"a","type":"rival"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("reverse = %d %s, want 201", rec.Code, rec.Body)
+	}
+
+	if rec := post(`{"agent1":"a","agent2":"b","type":"rival","strength":-0.4,"force":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("forced update = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if rel, _ := repo.GetRelationship("a", "b"); rel == nil || rel.Type != "rival" || rel.Trust != -0.4 {
+		t.Errorf("a → b after force = %+v", rel)
+	}
+
+	for body, want := range map[string]int{
+		`{"agent1":"a","agent2":"a","type":"friend"}`:                http.StatusBadRequest,
+		`{"agent1":"a","agent2":"b","type":"enemy"}`:                 http.StatusBadRequest,
+		`{"agent1":"a","agent2":"b","type":"friend","strength":1.5}`: http.StatusBadRequest,
+		`{"agent1":"a","agent2":"zed","type":"friend"}`:              http.StatusNotFound,
+	} {
+		if rec := post(body); rec.Code != want {
+			t.Errorf("POST %s = %d, want %d", body, rec.Code, want)
+		}
+	}
+}
+
+func TestGetAgentRelationships(t *testing.T) {
+	h, _ := newTestHandler(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /relationships/{agentId}", h.GetAgentRelationships)
+	mux.HandleFunc("POST /relationships", h.CreateRelationship)
+
+	for _, body := range []string{
+		`{"agent1":"a","agent2":"b","type":"friend"}`,
+		`{"agent1":"b","agent2":"a","type":"rival"}`,
+	} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/relationships", strings.NewReader(body)))
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/relationships/a", nil))
+	var resp AgentRelationshipsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET = %d %s", rec.Code, rec.Body)
+	}
+	if len(resp.Relationships) != 1 {
+		t.Fatalf("relationships = %+v, want one pair", resp.Relationships)
+	}
+	rel := resp.Relationships[0]
+	if rel.With.Name != "Bob" || rel.Outgoing == nil || rel.Outgoing.Type != "friend" || rel.Incoming == nil || rel.Incoming.Type != "rival" {
+		t.Errorf("pair = %+v", rel)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/relationships/zed", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown agent = %d, want 404", rec.Code)
+	}
+}
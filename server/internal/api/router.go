@@ -22,23 +22,40 @@ func NewMux(h *Handler) *http.ServeMux {
 	mux.HandleFunc("GET /agents", h.ListAgents)
 	mux.HandleFunc("GET /agents/{id}/memory", TODO)
 	mux.HandleFunc("GET /agents/{id}/thoughts", TODO)
+	mux.HandleFunc("GET /agents/{id}/usage", h.GetAgentUsage)
 	mux.HandleFunc("GET /agents/{id}", h.GetAgent)
 	mux.HandleFunc("POST /agents/{id}/inject", h.InjectMessage)
 
 	// RELATIONSHIPS
-	mux.HandleFunc("GET /relationships", TODO)
-	mux.HandleFunc("GET /relationships/{agentId}", TODO)
-	mux.HandleFunc("POST /relationships", TODO)
+	mux.HandleFunc("GET /relationships", h.ListRelationshipGraph)
+	mux.HandleFunc("GET /relationships/{agentId}", h.GetAgentRelationships)
+	mux.HandleFunc("POST /relationships", h.CreateRelationship)
 
 	// EVENTS
 	mux.HandleFunc("GET /events", TODO)
 	mux.HandleFunc("POST /events", TODO)
 	mux.HandleFunc("GET /events/stream", h.EventsStream)
+	mux.HandleFunc("GET /events/clients", h.ListStreamClients)
+
+	// CONVERSATIONS
+	mux.HandleFunc("GET /conversations", h.ListConversations)
+	mux.HandleFunc("GET /conversations/{id}", h.GetConversation)
+
+	// MODERATION
+	mux.HandleFunc("GET /moderation/decisions", h.ListModerationDecisions)
+	mux.HandleFunc("POST /moderation/decisions/{id}/review", h.ReviewModerationDecision)
 
 	// WORLD
 	mux.HandleFunc("GET /world/status", h.GetWorldStatus)
-	mux.HandleFunc("POST /world/control", TODO)
+	mux.HandleFunc("POST /world/control", h.ControlWorld)
+	mux.HandleFunc("GET /world/tasks", h.GetWorldTasks)
+	mux.HandleFunc("GET /world/map", h.GetWorldMap)
 	mux.HandleFunc("GET /world/statistics", TODO)
+	mux.HandleFunc("GET /world/llm", h.GetLLMStats)
+	mux.HandleFunc("GET /world/usage", h.GetWorldUsage)
+	mux.HandleFunc("GET /world/routing", h.GetRouting)
+	mux.HandleFunc("PUT /world/routing", h.SetRouting)
+	mux.HandleFunc("DELETE /world/routing", h.ResetRouting)
 
 	// CONTROL PANEL
 	mux.HandleFunc("POST /control/spawn", h.SpawnAgent)
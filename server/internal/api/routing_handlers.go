@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
+
+// GetRouting — GET /world/routing
+// Правила из файла и переопределения из API.
+func (h *Handler) GetRouting(w http.ResponseWriter, r *http.Request) {
+	if h.router == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "LLM router is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, RoutingResponse{
+		File:      routingToDTO(h.router.Base()),
+		Overrides: routingToDTO(h.router.Overrides()),
+	})
+}
+
+// SetRouting — PUT /world/routing
+// Задаёт или (model = "") снимает одно правило. Переопределения
+// сохраняются в world_state и переживают рестарт.
+func (h *Handler) SetRouting(w http.ResponseWriter, r *http.Request) {
+	if h.router == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "LLM router is not configured")
+		return
+	}
+
+	var req SetRoutingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid JSON body")
+		return
+	}
+	purpose := llm.Purpose(req.Purpose)
+	if req.Purpose != "" && !purpose.Valid() {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "unknown purpose: "+req.Purpose)
+		return
+	}
+	if req.AgentID != "" {
+		rec, err := h.repo.GetAgentByID(req.AgentID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get agent")
+			return
+		}
+		if rec == nil {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "agent not found")
+			return
+		}
+	}
+
+	// Сначала сохранение, потом применение: при ошибке записи правило
+	// не начинает действовать
+	overrides, err := h.router.SetOverride(req.AgentID, purpose, req.Model, h.saveRouting)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to save routing")
+		return
+	}
+	writeJSON(w, http.StatusOK, RoutingResponse{
+		File:      routingToDTO(h.router.Base()),
+		Overrides: routingToDTO(overrides),
+	})
+}
+
+// ResetRouting — DELETE /world/routing
+// Снимает все переопределения, остаются правила файла.
+func (h *Handler) ResetRouting(w http.ResponseWriter, r *http.Request) {
+	if h.router == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "LLM router is not configured")
+		return
+	}
+	if err := h.router.ReplaceOverrides(llm.RoutingConfig{}, h.saveRouting); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to save routing")
+		return
+	}
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: true, Message: "routing overrides cleared"})
+}
+
+// saveRouting сохраняет переопределения в world_state (llm.SaveFunc).
+func (h *Handler) saveRouting(cfg llm.RoutingConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("saveRouting: %w", err)
+	}
+	return h.repo.SetWorldState(storage.WorldStateLLMRouting, string(data))
+}
+
+func routingToDTO(cfg llm.RoutingConfig) RoutingConfigDTO {
+	out := RoutingConfigDTO{Default: cfg.Default, Purposes: purposesToDTO(cfg.Purposes)}
+	if len(cfg.Agents) > 0 {
+		out.Agents = make(map[string]AgentRoutingDTO, len(cfg.Agents))
+		for id, a := range cfg.Agents {
+			out.Agents[id] = AgentRoutingDTO{Default: a.Default, Purposes: purposesToDTO(a.Purposes)}
+		}
+	}
+	return out
+}
+
+func purposesToDTO(m map[llm.Purpose]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for p, model := range m {
+		out[string(p)] = model
+	}
+	return out
+}
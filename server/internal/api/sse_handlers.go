@@ -2,11 +2,28 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"milk/server/internal/moderation"
 )
 
-// EventsStream — GET /events/stream
-// SSE-эндпоинт, держит соединение и пушит события.
+// heartbeatInterval — период комментариев ": ping", чтобы прокси
+// не закрывали простаивающее соединение.
+const heartbeatInterval = 15 * time.Second
+
+// EventsStream — GET /events/stream?topics=interaction,system&agents=id1,id2
+// SSE-эндпоинт, держит соединение и пушит события. Имя SSE-события —
+// топик шины ("interaction", "mood_change", "system"...), поэтому клиент
+// слушает их через addEventListener, а не onmessage.
+//
+// Каждое сохранённое событие несёт "id:" — его номер events.seq.
+// При переподключении заголовок Last-Event-ID (или ?lastEventId=) досылает
+// пропущенное: из кольца в памяти, а если оно не покрывает разрыв — из БД.
+// Клиент, не успевающий читать, отключается и досылает пропущенное так же.
 func (h *Handler) EventsStream(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -14,31 +31,112 @@ func (h *Handler) EventsStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q := r.URL.Query()
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("lastEventId")
+	}
+	var after int64
+	if lastID != "" {
+		v, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || v < 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Last-Event-ID must be a non-negative integer")
+			return
+		}
+		after = v
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	ch := h.hub.Subscribe()
-	defer h.hub.Unsubscribe(ch)
+	filter := ParseStreamFilter(q.Get("topics"), q.Get("agents"))
+	client := h.hub.Subscribe(filter, r.RemoteAddr)
+	defer h.hub.Unsubscribe(client)
 
-	w.Write([]byte("data: {\"type\":\"connected\"}\n\n"))
+	// Подписка до досылки: события, пришедшие во время чтения БД,
+	// не теряются, а повторы отсекаются по номерам досланных. Шина
+	// рассылает события не строго по Seq, поэтому сравнивать с последним
+	// досланным номером нельзя — отсекаются только сами досланные.
+	w.Write(formatSSE("connected", 0, []byte(`{"type":"connected"}`)))
+	replayed := make(map[int64]bool)
+	if after > 0 {
+		frames, err := h.hub.framesAfter(after, filter)
+		if err != nil {
+			log.Printf("sse: replay after %d: %v", after, err)
+		}
+		for _, f := range frames {
+			w.Write(f.data)
+			client.Sent.Add(1)
+			client.LastSeq.Store(f.seq)
+			replayed[f.seq] = true
+		}
+	}
 	flusher.Flush()
 
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
 	for {
 		select {
-		case msg, ok := <-ch:
-			if !ok {
-				return
+		case f := <-client.ch:
+			if replayed[f.seq] {
+				delete(replayed, f.seq)
+				continue
+			}
+			w.Write(f.data)
+			flusher.Flush()
+			client.Sent.Add(1)
+			if f.seq > 0 {
+				client.LastSeq.Store(f.seq)
 			}
-			w.Write(msg)
+		case <-heartbeat.C:
+			w.Write([]byte(": ping\n\n"))
 			flusher.Flush()
+		case <-client.Overflow():
+			log.Printf("sse: client %s (%s) fell behind, %d dropped; closing for resume",
+				client.ID, client.Remote, client.Dropped.Load())
+			return
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
 
+// ListStreamClients — GET /events/clients
+// Подключённые SSE-клиенты: фильтры, отправленные и потерянные события.
+func (h *Handler) ListStreamClients(w http.ResponseWriter, r *http.Request) {
+	oldest, latest := h.hub.RingBounds()
+	resp := StreamClientsResponse{
+		Clients:      []StreamClientDTO{},
+		ReplayOldest: oldest,
+		ReplayLatest: latest,
+	}
+	for _, c := range h.hub.Clients() {
+		resp.Clients = append(resp.Clients, StreamClientDTO{
+			ID:          c.ID,
+			Remote:      c.Remote,
+			Topics:      setKeys(c.Filter.Topics),
+			Agents:      setKeys(c.Filter.Agents),
+			ConnectedAt: c.ConnectedAt,
+			Sent:        c.Sent.Load(),
+			Dropped:     c.Dropped.Load(),
+			LastEventID: c.LastSeq.Load(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // InjectMessage — POST /agents/{id}/inject
 // Добавляет сообщение человека в очередь агента.
 func (h *Handler) InjectMessage(w http.ResponseWriter, r *http.Request) {
@@ -64,10 +162,36 @@ func (h *Handler) InjectMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.hub.Inject(id, req.Content)
+	content := req.Content
+	message := "message injected — agent will respond on next tick"
+	if h.moderator != nil {
+		d := h.moderator.Check(r.Context(), moderation.Item{
+			Kind:    moderation.KindInput,
+			AgentID: id,
+			Text:    req.Content,
+		})
+		switch d.Action {
+		case moderation.ActionBlock:
+			writeJSON(w, http.StatusUnprocessableEntity, APIError{
+				Code:    ErrCodeContentBlocked,
+				Message: "message blocked by moderation",
+				Details: map[string]string{"category": d.Category, "reason": d.Reason},
+			})
+			return
+		case moderation.ActionFlag:
+			message = "message injected and flagged for review — agent will respond on next tick"
+		}
+		content = d.Text()
+	}
+
+	h.hub.Inject(id, content)
+	h.publishEvent("interaction", "human_message", []string{id}, map[string]any{
+		"agentId": id,
+		"content": content,
+	})
 
 	writeJSON(w, http.StatusOK, SuccessResponse{
 		Success: true,
-		Message: "message injected — agent will respond on next tick",
+		Message: message,
 	})
 }
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"milk/server/internal/storage"
+)
+
+// GetAgentUsage — GET /agents/{id}/usage
+// Query params: ?fromTick=100&toTick=200
+func (h *Handler) GetAgentUsage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, err := h.repo.GetAgentByID(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get agent")
+		return
+	}
+	if rec == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "agent not found")
+		return
+	}
+
+	filter := usageFilterFromQuery(r)
+	filter.AgentID = id
+	resp, err := h.buildUsage(filter, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to aggregate usage")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetWorldUsage — GET /world/usage
+// Query params: ?fromTick=100&toTick=200
+func (h *Handler) GetWorldUsage(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.buildUsage(usageFilterFromQuery(r), true)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to aggregate usage")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) buildUsage(filter storage.UsageFilter, byAgent bool) (UsageResponse, error) {
+	totals, err := h.repo.GetUsageTotals(filter)
+	if err != nil {
+		return UsageResponse{}, err
+	}
+	resp := UsageResponse{Totals: usageTotalsToDTO(totals)}
+
+	if resp.ByPurpose, err = h.usageGroups(filter, storage.UsageByPurpose); err != nil {
+		return UsageResponse{}, err
+	}
+	if resp.ByModel, err = h.usageGroups(filter, storage.UsageByModel); err != nil {
+		return UsageResponse{}, err
+	}
+	if byAgent {
+		if resp.ByAgent, err = h.usageGroups(filter, storage.UsageByAgent); err != nil {
+			return UsageResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+func (h *Handler) usageGroups(filter storage.UsageFilter, dim storage.UsageDimension) ([]UsageGroupDTO, error) {
+	groups, err := h.repo.GetUsageBy(filter, dim)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]UsageGroupDTO, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, UsageGroupDTO{
+			Key:            g.Key,
+			Name:           g.Name,
+			UsageTotalsDTO: usageTotalsToDTO(g.UsageTotals),
+		})
+	}
+	return out, nil
+}
+
+func usageTotalsToDTO(t storage.UsageTotals) UsageTotalsDTO {
+	return UsageTotalsDTO{
+		Calls:            t.Calls,
+		Errors:           t.Errors,
+		PromptTokens:     t.PromptTokens,
+		CompletionTokens: t.CompletionTokens,
+		TotalTokens:      t.PromptTokens + t.CompletionTokens,
+		TotalLatencyMs:   t.TotalLatencyMs,
+		AvgLatencyMs:     t.AvgLatencyMs,
+	}
+}
+
+// usageFilterFromQuery читает диапазон тиков из ?fromTick=&toTick=.
+func usageFilterFromQuery(r *http.Request) storage.UsageFilter {
+	var f storage.UsageFilter
+	if v, err := strconv.ParseInt(r.URL.Query().Get("fromTick"), 10, 64); err == nil {
+		f.FromTick = &v
+	}
+	if v, err := strconv.ParseInt(r.URL.Query().Get("toTick"), 10, 64); err == nil {
+		f.ToTick = &v
+	}
+	return f
+}
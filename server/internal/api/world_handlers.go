@@ -1,51 +1,200 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
+
+	"milk/server/internal/storage"
 )
 
 // serverStartTime хранит время запуска для вычисления uptime.
 var serverStartTime = time.Now()
 
+// Границы множителя скорости для setSpeed (см. world.MinSpeed/MaxSpeed).
+const (
+	minSimulationSpeed = 0.1
+	maxSimulationSpeed = 10.0
+)
+
 // GetWorldStatus — GET /world/status
 func (h *Handler) GetWorldStatus(w http.ResponseWriter, r *http.Request) {
-	activeAgents, err := h.repo.CountActiveAgents()
+	resp, err := h.worldStatus()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to count agents")
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
 		return
 	}
+	writeJSON(w, http.StatusOK, resp)
+}
 
-	totalEvents, err := h.repo.CountTotalEvents()
+// ControlWorld — POST /world/control
+// Actions: "pause", "resume", "step" (только на паузе и когда тик не идёт; иначе 409),
+// "setSpeed" (value 0.1–10).
+func (h *Handler) ControlWorld(w http.ResponseWriter, r *http.Request) {
+	if h.sim == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "simulation is not running")
+		return
+	}
+
+	var req WorldControlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid JSON body")
+		return
+	}
+
+	var err error
+	switch req.Action {
+	case "pause":
+		err = h.sim.Pause()
+	case "resume":
+		err = h.sim.Resume()
+	case "step":
+		if err := h.sim.Step(); err != nil {
+			writeError(w, http.StatusConflict, ErrCodeConflict, err.Error())
+			return
+		}
+	case "setSpeed":
+		if req.Value == nil || *req.Value < minSimulationSpeed || *req.Value > maxSimulationSpeed {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "value must be between 0.1 and 10")
+			return
+		}
+		err = h.sim.SetSpeed(*req.Value)
+	default:
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "action must be pause, resume, step or setSpeed")
+		return
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to count events")
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to apply control action")
 		return
 	}
 
-	state, err := h.repo.GetWorldState()
+	resp, err := h.worldStatus()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to get world state")
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
 		return
 	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetWorldTasks — GET /world/tasks
+// Задачи на часах симуляции: последний и следующий запуск, длительности, сбои.
+func (h *Handler) GetWorldTasks(w http.ResponseWriter, r *http.Request) {
+	if h.sim == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "simulation is not running")
+		return
+	}
+	tick, tasks := h.sim.ScheduledTasks()
+	if tasks == nil {
+		tasks = []ScheduledTaskDTO{}
+	}
+	writeJSON(w, http.StatusOK, WorldTasksResponse{CurrentTick: tick, Tasks: tasks})
+}
 
-	currentTick, _ := strconv.ParseInt(state["current_tick"], 10, 64)
+// GetWorldMap — GET /world/map
+// Зоны города со смежностью и активными агентами в каждой.
+func (h *Handler) GetWorldMap(w http.ResponseWriter, r *http.Request) {
+	zones, err := h.repo.ListZones()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to load zones")
+		return
+	}
+	agents, err := h.repo.GetActiveAgents()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to load agents")
+		return
+	}
+	byZone := make(map[string][]string)
+	for _, a := range agents {
+		zone := recordToSummary(a).Location
+		byZone[zone] = append(byZone[zone], a.ID)
+	}
+
+	resp := WorldMapResponse{Zones: make([]ZoneDTO, 0, len(zones))}
+	for _, z := range zones {
+		dto := ZoneDTO{
+			ID:          z.ID,
+			Name:        z.Name,
+			Description: z.Description,
+			X:           z.X,
+			Y:           z.Y,
+			Neighbors:   z.Neighbors,
+			Agents:      byZone[z.ID],
+		}
+		if dto.Neighbors == nil {
+			dto.Neighbors = []string{}
+		}
+		if dto.Agents == nil {
+			dto.Agents = []string{}
+		}
+		resp.Zones = append(resp.Zones, dto)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// worldStatus собирает WorldStatusResponse из БД.
+// Текст ошибки безопасен для ответа клиенту.
+func (h *Handler) worldStatus() (WorldStatusResponse, error) {
+	activeAgents, err := h.repo.CountActiveAgents()
+	if err != nil {
+		return WorldStatusResponse{}, errors.New("failed to count agents")
+	}
+
+	totalEvents, err := h.repo.CountTotalEvents()
+	if err != nil {
+		return WorldStatusResponse{}, errors.New("failed to count events")
+	}
+
+	state, err := h.repo.GetWorldState()
+	if err != nil {
+		return WorldStatusResponse{}, errors.New("failed to get world state")
+	}
+
+	currentTick, _ := strconv.ParseInt(state[storage.WorldStateCurrentTick], 10, 64)
 	simSpeed := 1.0
-	if s, ok := state["simulation_speed"]; ok {
+	if s, ok := state[storage.WorldStateSimulationSpeed]; ok {
 		if v, err := strconv.ParseFloat(s, 64); err == nil {
 			simSpeed = v
 		}
 	}
-	isPaused := state["is_paused"] == "true"
+	isPaused := state[storage.WorldStateIsPaused] == "true"
 
-	writeJSON(w, http.StatusOK, WorldStatusResponse{
+	return WorldStatusResponse{
 		CurrentTick:     currentTick,
 		SimulationSpeed: simSpeed,
 		IsPaused:        isPaused,
 		ActiveAgents:    activeAgents,
 		TotalEvents:     totalEvents,
 		Uptime:          formatUptime(time.Since(serverStartTime)),
-	})
+	}, nil
+}
+
+// GetLLMStats — GET /world/llm
+// Глубина очереди, занятые слоты и время ожидания по назначению вызова.
+func (h *Handler) GetLLMStats(w http.ResponseWriter, r *http.Request) {
+	if h.scheduler == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "LLM scheduler is not configured")
+		return
+	}
+
+	stats := h.scheduler.Stats()
+	resp := LLMStatsResponse{
+		MaxInFlight: stats.MaxInFlight,
+		InFlight:    stats.InFlight,
+		QueueDepth:  stats.QueueDepth,
+		ByPurpose:   make(map[string]LLMPurposeStatsDTO, len(stats.ByPurpose)),
+	}
+	for purpose, ps := range stats.ByPurpose {
+		resp.ByPurpose[string(purpose)] = LLMPurposeStatsDTO{
+			Queued:    ps.Queued,
+			Granted:   ps.Granted,
+			Canceled:  ps.Canceled,
+			AvgWaitMs: float64(ps.AvgWait) / float64(time.Millisecond),
+			MaxWaitMs: float64(ps.MaxWait) / float64(time.Millisecond),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // formatUptime форматирует duration в строку "2h15m30s".
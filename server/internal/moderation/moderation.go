@@ -0,0 +1,161 @@
+// Package moderation provides content moderation for human input and agent output.
+//
+// Moderator прогоняет текст через цепочку классификаторов (правила/regex,
+// опционально LLM-судья) и выбирает самое строгое решение:
+//   - входящие сообщения человека (POST /agents/{id}/inject): попытки
+//     переписать системный промпт помечаются или блокируются;
+//   - реплики агентов: небезопасный текст редактируется или задерживается
+//     до ревью, вместо того чтобы уйти в SSE.
+//
+// Каждое решение отдаётся в OnDecision — main сохраняет его как событие
+// топика "moderation", которое дашборд может просмотреть.
+
+package moderation
+
+import (
+	"context"
+	"log"
+)
+
+// Kind — направление проверяемого текста.
+type Kind string
+
+const (
+	KindInput  Kind = "input"  // Сообщение человека агенту
+	KindOutput Kind = "output" // Реплика агента
+)
+
+// Action — решение модерации. Порядок констант = строгость.
+// Редактирование не мешает флагу: отредактированный текст с флагом
+// пропускается в отредактированном виде и попадает на ревью.
+type Action string
+
+const (
+	ActionAllow  Action = "allow"  // Пропустить как есть
+	ActionRedact Action = "redact" // Пропустить отредактированный текст
+	ActionFlag   Action = "flag"   // Пропустить, но отметить для ревью
+	ActionBlock  Action = "block"  // Не пропускать (реплика агента задерживается)
+)
+
+// severity возвращает строгость действия для сравнения.
+func (a Action) severity() int {
+	switch a {
+	case ActionRedact:
+		return 1
+	case ActionFlag:
+		return 2
+	case ActionBlock:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Item — проверяемый текст с контекстом.
+type Item struct {
+	Kind    Kind
+	AgentID string // агент-адресат (input) или автор (output)
+	Tick    int64  // тик симуляции, 0 = вне тика
+	Text    string
+}
+
+// Verdict — ответ одного классификатора.
+type Verdict struct {
+	// Action — предлагаемое действие.
+	Action Action
+
+	// Category — категория нарушения: "jailbreak", "prompt_injection", "pii", "unsafe".
+	Category string
+
+	// Reason — человекочитаемое объяснение (имя правила, ответ судьи).
+	Reason string
+
+	// Redacted — текст после редактирования. Пусто = текст не менялся.
+	Redacted string
+}
+
+// Classifier — подключаемый классификатор.
+type Classifier interface {
+	// Name — имя для журнала решений ("rules", "llm_judge").
+	Name() string
+
+	// Classify оценивает текст. Ошибка не блокирует текст: Moderator
+	// записывает её в решение и продолжает с остальными классификаторами.
+	Classify(ctx context.Context, item Item) (Verdict, error)
+}
+
+// Decision — итоговое решение по одному тексту.
+type Decision struct {
+	Item
+
+	// Verdict — самое строгое из решений классификаторов.
+	Verdict
+
+	// Classifier — классификатор, чьё решение выбрано. Пусто для allow.
+	Classifier string
+
+	// Errors — ошибки классификаторов (например, недоступный LLM-судья).
+	Errors []string
+}
+
+// Text возвращает текст, который можно пропускать дальше:
+// оригинал, отредактированную версию или "" при блокировке.
+func (d Decision) Text() string {
+	switch {
+	case d.Action == ActionBlock:
+		return ""
+	case d.Redacted != "":
+		return d.Redacted
+	default:
+		return d.Item.Text
+	}
+}
+
+// Moderator — цепочка классификаторов.
+type Moderator struct {
+	// Classifiers — классификаторы в порядке вызова. Дешёвые — первыми:
+	// после блокировки остальные не вызываются.
+	Classifiers []Classifier
+
+	// OnDecision — получатель всех решений, включая allow. nil = не записывать.
+	OnDecision func(Decision)
+}
+
+// New создаёт Moderator.
+func New(onDecision func(Decision), classifiers ...Classifier) *Moderator {
+	return &Moderator{Classifiers: classifiers, OnDecision: onDecision}
+}
+
+// Check прогоняет текст через классификаторы и возвращает самое строгое решение.
+// Отредактированный текст передаётся следующим классификаторам.
+func (m *Moderator) Check(ctx context.Context, item Item) Decision {
+	d := Decision{Item: item, Verdict: Verdict{Action: ActionAllow}}
+
+	current := item
+	for _, c := range m.Classifiers {
+		v, err := c.Classify(ctx, current)
+		if err != nil {
+			log.Printf("moderation: %s: %v", c.Name(), err)
+			d.Errors = append(d.Errors, c.Name()+": "+err.Error())
+			continue
+		}
+		redacted := d.Redacted
+		if v.Redacted != "" {
+			current.Text = v.Redacted
+			redacted = v.Redacted
+		}
+		if v.Action.severity() > d.Action.severity() {
+			d.Verdict = v
+			d.Classifier = c.Name()
+		}
+		d.Redacted = redacted
+		if d.Action == ActionBlock {
+			break
+		}
+	}
+
+	if m.OnDecision != nil {
+		m.OnDecision(d)
+	}
+	return d
+}
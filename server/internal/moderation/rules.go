@@ -0,0 +1,144 @@
+// Package moderation — локальный классификатор на правилах.
+//
+// RulesClassifier проверяет текст списком регулярных выражений.
+// Встроенный набор ловит типичные попытки переписать системный промпт
+// (на английском и русском), утечки служебной разметки промпта и
+// персональные данные. Набор можно заменить JSON-файлом
+// (MODERATION_RULES_FILE).
+
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Rule — одно правило.
+type Rule struct {
+	// Name — имя правила для журнала решений.
+	Name string `json:"name"`
+
+	// Pattern — регулярное выражение (синтаксис Go RE2).
+	Pattern string `json:"pattern"`
+
+	// Kind — к чему применяется: "input", "output" или "" (к обоим).
+	Kind Kind `json:"kind,omitempty"`
+
+	// Category — категория нарушения.
+	Category string `json:"category"`
+
+	// Action — действие при совпадении. Для redact совпадения
+	// заменяются на Replacement.
+	Action Action `json:"action"`
+
+	// Replacement — замена для redact ("" = удалить совпадение).
+	Replacement string `json:"replacement,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// DefaultRules — встроенный набор правил.
+var DefaultRules = []Rule{
+	// Попытки переписать инструкции агента.
+	{Name: "ignore_instructions", Kind: KindInput, Category: "jailbreak", Action: ActionBlock,
+		Pattern: `(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(previous|prior|above|all|your|system)\b.{0,20}\b(instructions?|prompts?|rules?|directives?)\b`},
+	{Name: "ignore_instructions_ru", Kind: KindInput, Category: "jailbreak", Action: ActionBlock,
+		Pattern: `(?i)(игнорируй|забудь|отбрось|не обращай внимания на).{0,30}(инструкци|правил|промпт|указани)`},
+	{Name: "reveal_system_prompt", Kind: KindInput, Category: "prompt_injection", Action: ActionBlock,
+		Pattern: `(?i)\b(reveal|show|print|repeat|output)\b.{0,30}\b(system|initial|hidden)\s+(prompt|instructions?|message)\b|(покажи|выведи|повтори).{0,30}системн\S*\s+(промпт|инструкци)`},
+	{Name: "role_override", Kind: KindInput, Category: "jailbreak", Action: ActionFlag,
+		Pattern: `(?i)\b(you are now|from now on you are|pretend (that )?you are no longer|act as (an? )?(ai|assistant|language model)|developer mode|jailbreak|\bDAN\b)|(теперь ты|с этого момента ты|представь, что ты больше не)`},
+	{Name: "fake_role_markup", Kind: KindInput, Category: "prompt_injection", Action: ActionBlock,
+		Pattern: `(?i)(<\|?(system|im_start|im_end)\|?>|\[/?(system|inst)\]|^\s*(system|assistant)\s*:)`},
+
+	// Утечки служебной разметки промпта в реплики.
+	{Name: "prompt_markup_leak", Kind: KindOutput, Category: "prompt_injection", Action: ActionRedact,
+		Pattern: `(?i)\[human says to you\]:?|<\|?(system|im_start|im_end)\|?>`},
+	{Name: "ai_self_disclosure", Kind: KindOutput, Category: "jailbreak", Action: ActionFlag,
+		Pattern: `(?i)\bas an? (ai|language model|large language model)\b|\bmy system prompt\b|я (всего лишь )?(языковая модель|ии-ассистент)`},
+
+	// Небезопасный контент в репликах.
+	{Name: "self_harm", Kind: KindOutput, Category: "unsafe", Action: ActionBlock,
+		Pattern: `(?i)\b(kill|hurt) yourself\b|\bhow to (commit suicide|kill yourself)\b|убей себя|покончи с собой`},
+	{Name: "weapons_instructions", Kind: "", Category: "unsafe", Action: ActionBlock,
+		Pattern: `(?i)\b(how to|instructions? (for|to)|steps? to)\b.{0,30}\b(make|build|synthesi[sz]e)\b.{0,20}\b(bomb|explosive|nerve agent|ricin)\b`},
+
+	// Персональные данные.
+	{Name: "email", Kind: "", Category: "pii", Action: ActionRedact,
+		Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, Replacement: "[email]"},
+	{Name: "phone", Kind: "", Category: "pii", Action: ActionRedact,
+		Pattern: `\+\d{1,3}[\s(.-]*\d{3}[\s).-]*\d{3}[\s.-]*\d{2}[\s.-]*\d{2}\b`, Replacement: "[phone]"},
+}
+
+// RulesClassifier — классификатор на регулярных выражениях.
+type RulesClassifier struct {
+	rules []Rule
+}
+
+// NewRulesClassifier компилирует правила. Ошибка — если шаблон невалиден.
+func NewRulesClassifier(rules []Rule) (*RulesClassifier, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("NewRulesClassifier %s: %w", r.Name, err)
+		}
+		if r.Action.severity() == 0 && r.Action != ActionAllow {
+			return nil, fmt.Errorf("NewRulesClassifier %s: unknown action %q", r.Name, r.Action)
+		}
+		r.re = re
+		compiled = append(compiled, r)
+	}
+	return &RulesClassifier{rules: compiled}, nil
+}
+
+// LoadRules читает правила из JSON-файла (массив Rule).
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadRules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("LoadRules decode %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Name реализует Classifier.
+func (c *RulesClassifier) Name() string { return "rules" }
+
+// Classify применяет все подходящие правила. Блокирующее правило
+// возвращается сразу; redact-правила применяются все по очереди,
+// отредактированный текст сохраняется и при более строгом флаге.
+func (c *RulesClassifier) Classify(ctx context.Context, item Item) (Verdict, error) {
+	best := Verdict{Action: ActionAllow}
+	text := item.Text
+	redacted := false
+
+	for _, r := range c.rules {
+		if r.Kind != "" && r.Kind != item.Kind {
+			continue
+		}
+		if !r.re.MatchString(text) {
+			continue
+		}
+		if r.Action == ActionRedact {
+			text = r.re.ReplaceAllLiteralString(text, r.Replacement)
+			redacted = true
+		}
+		if r.Action.severity() > best.Action.severity() {
+			best = Verdict{Action: r.Action, Category: r.Category, Reason: "rule " + r.Name}
+		}
+		if best.Action == ActionBlock {
+			return best, nil
+		}
+	}
+	if redacted {
+		best.Redacted = text
+	}
+	return best, nil
+}
@@ -0,0 +1,257 @@
+// Package storage — разговоры и их стенограммы.
+//
+// Разговор создаётся при старте, каждая реплика дописывается в
+// conversation_turns по порядку, при завершении записываются конечный тик,
+// причина и итоговый состав участников.
+
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ConversationRecord — строка из таблицы conversations.
+type ConversationRecord struct {
+	// ID — UUID разговора.
+	ID string
+
+	// InitiatorID — агент, начавший разговор.
+	InitiatorID string
+
+	// Participants — id всех участников, включая ушедших.
+	Participants []string
+
+	// StartTick, EndTick — тики начала и конца. EndTick NULL = идёт.
+	StartTick int64
+	EndTick   sql.NullInt64
+
+	// EndReason — почему разговор закончился ("farewell", "max_length"...).
+	EndReason sql.NullString
+
+	// Topic — тема, если она известна при выборе пары.
+	Topic sql.NullString
+
+	// Summary — краткое содержание.
+	Summary sql.NullString
+
+	// StartedAt, EndedAt — wall-clock начала и конца.
+	StartedAt time.Time
+	EndedAt   sql.NullTime
+
+	// Turns — число реплик (при чтении).
+	Turns int
+}
+
+// ConversationTurnRecord — реплика из conversation_turns.
+type ConversationTurnRecord struct {
+	ConversationID string
+
+	// Seq — номер реплики в разговоре, с 0.
+	Seq int
+
+	SpeakerID string
+
+	// TargetID — к кому обращена реплика. NULL = ко всем.
+	TargetID sql.NullString
+
+	Content   string
+	Tick      int64
+	CreatedAt time.Time
+}
+
+// ConversationFilter — параметры ListConversations.
+type ConversationFilter struct {
+	// AgentID — только разговоры с участием агента.
+	AgentID string
+
+	// FromTick, ToTick — разговоры, пересекающиеся с диапазоном тиков
+	// (включительно). nil = без границы.
+	FromTick *int64
+	ToTick   *int64
+
+	// Limit — максимум результатов (по умолчанию 50).
+	Limit int
+}
+
+// CreateConversation вставляет начатый разговор.
+func (r *Repository) CreateConversation(rec ConversationRecord) error {
+	participants, _ := json.Marshal(rec.Participants)
+	if rec.StartedAt.IsZero() {
+		rec.StartedAt = time.Now().UTC()
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO conversations (id, initiator_id, participants, start_tick, topic, started_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		rec.ID, rec.InitiatorID, string(participants), rec.StartTick, rec.Topic, rec.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("CreateConversation: %w", err)
+	}
+	return nil
+}
+
+// SetConversationParticipants обновляет состав участников.
+func (r *Repository) SetConversationParticipants(id string, participants []string) error {
+	raw, _ := json.Marshal(participants)
+	if _, err := r.DB.Exec(`UPDATE conversations SET participants = ? WHERE id = ?`, string(raw), id); err != nil {
+		return fmt.Errorf("SetConversationParticipants: %w", err)
+	}
+	return nil
+}
+
+// AddConversationTurn дописывает реплику.
+func (r *Repository) AddConversationTurn(turn ConversationTurnRecord) error {
+	if turn.CreatedAt.IsZero() {
+		turn.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO conversation_turns (conversation_id, seq, speaker_id, target_id, content, tick, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		turn.ConversationID, turn.Seq, turn.SpeakerID, turn.TargetID, turn.Content, turn.Tick, turn.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("AddConversationTurn: %w", err)
+	}
+	return nil
+}
+
+// EndConversation записывает конец разговора: тик, причину и итоговый
+// состав участников.
+func (r *Repository) EndConversation(id string, endTick int64, reason string, participants []string) error {
+	raw, _ := json.Marshal(participants)
+	_, err := r.DB.Exec(
+		`UPDATE conversations SET end_tick = ?, end_reason = ?, participants = ?, ended_at = ? WHERE id = ?`,
+		endTick, reason, string(raw), time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("EndConversation: %w", err)
+	}
+	return nil
+}
+
+// SetConversationSummary сохраняет краткое содержание разговора.
+func (r *Repository) SetConversationSummary(id, summary string) error {
+	if _, err := r.DB.Exec(`UPDATE conversations SET summary = ? WHERE id = ?`, summary, id); err != nil {
+		return fmt.Errorf("SetConversationSummary: %w", err)
+	}
+	return nil
+}
+
+const conversationColumns = `c.id, c.initiator_id, c.participants, c.start_tick, c.end_tick, c.end_reason,
+	c.topic, c.summary, c.started_at, c.ended_at,
+	(SELECT COUNT(*) FROM conversation_turns t WHERE t.conversation_id = c.id)`
+
+// ListConversations возвращает разговоры по фильтру, новые первыми.
+func (r *Repository) ListConversations(f ConversationFilter) ([]ConversationRecord, error) {
+	query := `SELECT ` + conversationColumns + ` FROM conversations c WHERE 1=1`
+	args := []any{}
+	if f.AgentID != "" {
+		query += ` AND c.participants LIKE ?`
+		args = append(args, `%"`+f.AgentID+`"%`)
+	}
+	if f.FromTick != nil {
+		query += ` AND (c.end_tick IS NULL OR c.end_tick >= ?)`
+		args = append(args, *f.FromTick)
+	}
+	if f.ToTick != nil {
+		query += ` AND c.start_tick <= ?`
+		args = append(args, *f.ToTick)
+	}
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	query += ` ORDER BY c.start_tick DESC, c.started_at DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ListConversations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ConversationRecord
+	for rows.Next() {
+		rec, err := scanConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListConversations scan: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// GetConversation возвращает разговор по ID. nil — не найден.
+func (r *Repository) GetConversation(id string) (*ConversationRecord, error) {
+	row := r.DB.QueryRow(`SELECT `+conversationColumns+` FROM conversations c WHERE c.id = ?`, id)
+	rec, err := scanConversation(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetConversation: %w", err)
+	}
+	return &rec, nil
+}
+
+// FirstConversationBetween возвращает первый завершённый разговор
+// с участием обоих агентов. nil — они ещё не разговаривали.
+func (r *Repository) FirstConversationBetween(a, b string) (*ConversationRecord, error) {
+	row := r.DB.QueryRow(
+		`SELECT `+conversationColumns+` FROM conversations c
+		 WHERE c.participants LIKE ? AND c.participants LIKE ? AND c.end_tick IS NOT NULL
+		 ORDER BY c.start_tick, c.started_at LIMIT 1`,
+		`%"`+a+`"%`, `%"`+b+`"%`,
+	)
+	rec, err := scanConversation(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FirstConversationBetween: %w", err)
+	}
+	return &rec, nil
+}
+
+// GetConversationTurns возвращает реплики разговора по порядку.
+func (r *Repository) GetConversationTurns(id string) ([]ConversationTurnRecord, error) {
+	rows, err := r.DB.Query(
+		`SELECT conversation_id, seq, speaker_id, target_id, content, tick, created_at
+		 FROM conversation_turns WHERE conversation_id = ? ORDER BY seq`, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetConversationTurns: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ConversationTurnRecord
+	for rows.Next() {
+		var t ConversationTurnRecord
+		if err := rows.Scan(&t.ConversationID, &t.Seq, &t.SpeakerID, &t.TargetID, &t.Content, &t.Tick, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetConversationTurns scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// scanner — общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanConversation(s scanner) (ConversationRecord, error) {
+	var rec ConversationRecord
+	var participants string
+	err := s.Scan(
+		&rec.ID, &rec.InitiatorID, &participants, &rec.StartTick, &rec.EndTick, &rec.EndReason,
+		&rec.Topic, &rec.Summary, &rec.StartedAt, &rec.EndedAt, &rec.Turns,
+	)
+	if err != nil {
+		return rec, err
+	}
+	json.Unmarshal([]byte(participants), &rec.Participants)
+	return rec, nil
+}
@@ -0,0 +1,203 @@
+// Package storage — учёт вызовов LLM.
+//
+// Каждый вызов модели сохраняется в llm_calls с агентом, тиком,
+// назначением, токенами и задержкой. Агрегаты показывают, какие агенты
+// и какие когнитивные задачи съедают CPU-бюджет.
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LLMCallRecord — строка из таблицы llm_calls.
+type LLMCallRecord struct {
+	// ID — UUID вызова.
+	ID string
+
+	// AgentID — агент, от имени которого сделан вызов. NULL = системный вызов.
+	AgentID sql.NullString
+
+	// Tick — тик симуляции.
+	Tick sql.NullInt64
+
+	// Purpose — назначение: "dialogue", "human_reply", "reflection" и т.д.
+	Purpose string
+
+	// Model — модель, которая ответила.
+	Model string
+
+	// Provider — провайдер, который ответил ("ollama/gemma3:4b").
+	// Если это резерв из LLM_FALLBACKS, RoutedModel не применялась.
+	Provider sql.NullString
+
+	// RoutedModel — модель, выбранная маршрутизатором. NULL = модель провайдера.
+	RoutedModel sql.NullString
+
+	// RouteRule — сработавшее правило маршрутизации ("purpose:reflection").
+	RouteRule sql.NullString
+
+	// PromptTokens, CompletionTokens — токены по данным провайдера.
+	PromptTokens     int
+	CompletionTokens int
+
+	// LatencyMs — время вызова провайдера в миллисекундах.
+	LatencyMs int64
+
+	// Error — текст ошибки. NULL = успешный вызов.
+	Error sql.NullString
+
+	// CreatedAt — время вызова.
+	CreatedAt time.Time
+}
+
+// UsageFilter — параметры агрегации llm_calls.
+type UsageFilter struct {
+	// AgentID — только вызовы этого агента. Пустая строка = все.
+	AgentID string
+
+	// FromTick, ToTick — диапазон тиков (включительно). nil = без границы.
+	FromTick *int64
+	ToTick   *int64
+}
+
+// UsageTotals — агрегат по набору вызовов.
+type UsageTotals struct {
+	Calls            int
+	Errors           int
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalLatencyMs   int64
+	AvgLatencyMs     float64
+}
+
+// UsageGroup — агрегат по одному значению измерения (purpose, model, agent).
+type UsageGroup struct {
+	// Key — значение измерения ("dialogue", "gemma3:4b", agent ID).
+	Key string
+
+	// Name — имя агента для группировки по агентам, иначе пусто.
+	Name string
+
+	UsageTotals
+}
+
+// UsageDimension — измерение группировки для GetUsageBy.
+type UsageDimension string
+
+const (
+	UsageByPurpose UsageDimension = "purpose"
+	UsageByModel   UsageDimension = "model"
+	UsageByAgent   UsageDimension = "agent_id"
+)
+
+// SaveLLMCall вставляет запись о вызове LLM.
+func (r *Repository) SaveLLMCall(rec LLMCallRecord) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO llm_calls (id, agent_id, tick, purpose, model, provider, routed_model, route_rule,
+		                        prompt_tokens, completion_tokens, latency_ms, error, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, rec.AgentID, rec.Tick, rec.Purpose, rec.Model, rec.Provider, rec.RoutedModel, rec.RouteRule,
+		rec.PromptTokens, rec.CompletionTokens, rec.LatencyMs, rec.Error, rec.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("SaveLLMCall: %w", err)
+	}
+	return nil
+}
+
+// usageWhere строит WHERE-часть для UsageFilter.
+func usageWhere(f UsageFilter) (string, []any) {
+	where := "WHERE 1=1"
+	args := []any{}
+	if f.AgentID != "" {
+		where += " AND c.agent_id = ?"
+		args = append(args, f.AgentID)
+	}
+	if f.FromTick != nil {
+		where += " AND c.tick >= ?"
+		args = append(args, *f.FromTick)
+	}
+	if f.ToTick != nil {
+		where += " AND c.tick <= ?"
+		args = append(args, *f.ToTick)
+	}
+	return where, args
+}
+
+const usageColumns = `COUNT(*),
+	COALESCE(SUM(CASE WHEN c.error IS NOT NULL THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(c.prompt_tokens), 0),
+	COALESCE(SUM(c.completion_tokens), 0),
+	COALESCE(SUM(c.latency_ms), 0)`
+
+// GetUsageTotals возвращает суммарную статистику вызовов.
+func (r *Repository) GetUsageTotals(f UsageFilter) (UsageTotals, error) {
+	where, args := usageWhere(f)
+	var t UsageTotals
+	err := r.DB.QueryRow(
+		fmt.Sprintf(`SELECT %s FROM llm_calls c %s`, usageColumns, where), args...,
+	).Scan(&t.Calls, &t.Errors, &t.PromptTokens, &t.CompletionTokens, &t.TotalLatencyMs)
+	if err != nil {
+		return UsageTotals{}, fmt.Errorf("GetUsageTotals: %w", err)
+	}
+	t.fillAvg()
+	return t, nil
+}
+
+// GetUsageBy группирует вызовы по измерению dim, сортируя по расходу токенов.
+func (r *Repository) GetUsageBy(f UsageFilter, dim UsageDimension) ([]UsageGroup, error) {
+	switch dim {
+	case UsageByPurpose, UsageByModel, UsageByAgent:
+	default:
+		return nil, fmt.Errorf("GetUsageBy: unknown dimension %q", dim)
+	}
+
+	where, args := usageWhere(f)
+	query := fmt.Sprintf(
+		`SELECT COALESCE(c.%[1]s, ''), COALESCE(MAX(a.name), ''), %[2]s
+		 FROM llm_calls c LEFT JOIN agents a ON a.id = c.agent_id
+		 %[3]s
+		 GROUP BY c.%[1]s
+		 ORDER BY SUM(c.prompt_tokens + c.completion_tokens) DESC`,
+		dim, usageColumns, where,
+	)
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsageBy: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []UsageGroup
+	for rows.Next() {
+		var g UsageGroup
+		if err := rows.Scan(
+			&g.Key, &g.Name, &g.Calls, &g.Errors,
+			&g.PromptTokens, &g.CompletionTokens, &g.TotalLatencyMs,
+		); err != nil {
+			return nil, fmt.Errorf("GetUsageBy scan: %w", err)
+		}
+		if dim != UsageByAgent {
+			g.Name = ""
+		}
+		g.fillAvg()
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (t *UsageTotals) fillAvg() {
+	if t.Calls > 0 {
+		t.AvgLatencyMs = float64(t.TotalLatencyMs) / float64(t.Calls)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestUsageAggregates(t *testing.T) {
+	repo := newTestRepo(t)
+	if _, err := repo.DB.Exec(`INSERT INTO agents (id, name, personality) VALUES ('a', 'Alice', '{}'), ('b', 'Bob', '{}')`); err != nil {
+		t.Fatal(err)
+	}
+	agent := func(id string) sql.NullString { return sql.NullString{String: id, Valid: id != ""} }
+	tick := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	for _, rec := range []LLMCallRecord{
+		{AgentID: agent("a"), Tick: tick(1), Purpose: "dialogue", Model: "gemma3:4b", PromptTokens: 100, CompletionTokens: 20, LatencyMs: 1000},
+		{AgentID: agent("a"), Tick: tick(2), Purpose: "reflection", Model: "gemma3:4b", PromptTokens: 300, CompletionTokens: 50, LatencyMs: 3000},
+		{AgentID: agent("b"), Tick: tick(2), Purpose: "dialogue", Model: "gemma3:1b", PromptTokens: 80, CompletionTokens: 10, LatencyMs: 500},
+		{AgentID: agent("b"), Tick: tick(3), Purpose: "dialogue", LatencyMs: 100, Error: sql.NullString{String: "timeout", Valid: true}},
+		{Tick: tick(3), Purpose: "moderation", Model: "gemma3:1b", PromptTokens: 40, CompletionTokens: 2, LatencyMs: 400},
+	} {
+		if err := repo.SaveLLMCall(rec); err != nil {
+			t.Fatalf("SaveLLMCall: %v", err)
+		}
+	}
+
+	totals, err := repo.GetUsageTotals(UsageFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UsageTotals{Calls: 5, Errors: 1, PromptTokens: 520, CompletionTokens: 82, TotalLatencyMs: 5000, AvgLatencyMs: 1000}
+	if totals != want {
+		t.Errorf("totals = %+v, want %+v", totals, want)
+	}
+
+	from, to := int64(2), int64(2)
+	totals, err = repo.GetUsageTotals(UsageFilter{AgentID: "a", FromTick: &from, ToTick: &to})
+	if err != nil || totals.Calls != 1 || totals.PromptTokens != 300 {
+		t.Errorf("filtered totals = %+v, %v; want the reflection call", totals, err)
+	}
+
+	byAgent, err := repo.GetUsageBy(UsageFilter{}, UsageByAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// По убыванию токенов: Alice, Bob, системные вызовы.
+	if len(byAgent) != 3 || byAgent[0].Key != "a" || byAgent[0].Name != "Alice" || byAgent[1].Calls != 2 || byAgent[2].Key != "" {
+		t.Errorf("by agent = %+v", byAgent)
+	}
+
+	byPurpose, err := repo.GetUsageBy(UsageFilter{}, UsageByPurpose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byPurpose) != 3 || byPurpose[0].Key != "reflection" || byPurpose[1].Key != "dialogue" || byPurpose[1].Errors != 1 || byPurpose[1].Name != "" {
+		t.Errorf("by purpose = %+v", byPurpose)
+	}
+
+	if _, err := repo.GetUsageBy(UsageFilter{}, "name; DROP TABLE agents"); err == nil {
+		t.Error("unknown dimension accepted")
+	}
+}
@@ -0,0 +1,131 @@
+// Package storage — воспоминания агентов.
+//
+// Запись воспоминаний (рефлексия, сводки разговоров) и их забывание:
+// важность редко вспоминаемых записей снижается, записи ниже порога удаляются.
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SaveMemory вставляет воспоминание. Пустые ID и CreatedAt заполняются.
+func (r *Repository) SaveMemory(rec MemoryRecord) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO memories (id, agent_id, type, content, emotional_tag, importance,
+		                       access_count, last_accessed, related_agents, metadata, created_at, touched_tick)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, rec.AgentID, rec.Type, rec.Content, rec.EmotionalTag, rec.Importance,
+		rec.AccessCount, rec.LastAccessed, rec.RelatedAgents, rec.Metadata, rec.CreatedAt, rec.TouchedTick,
+	)
+	if err != nil {
+		return fmt.Errorf("SaveMemory: %w", err)
+	}
+	return nil
+}
+
+// RecallMemoriesAbout возвращает до limit последних воспоминаний агента
+// типа memType, связанных хотя бы с одним из others (related_agents),
+// от старых к новым, и отмечает их как вспомненные на тике tick:
+// access_count растёт, last_accessed и touched_tick обновляются — такие
+// записи не забываются.
+func (r *Repository) RecallMemoriesAbout(agentID, memType string, others []string, limit int, tick int64) ([]MemoryRecord, error) {
+	if len(others) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 3
+	}
+	likes := make([]string, 0, len(others))
+	args := []any{agentID, memType}
+	for _, id := range others {
+		likes = append(likes, "related_agents LIKE ?")
+		args = append(args, `%"`+id+`"%`)
+	}
+	args = append(args, limit)
+
+	rows, err := r.DB.Query(
+		`SELECT id, agent_id, type, content, emotional_tag, importance, access_count,
+		        last_accessed, related_agents, metadata, created_at, touched_tick
+		 FROM memories
+		 WHERE agent_id = ? AND type = ? AND (`+strings.Join(likes, " OR ")+`)
+		 ORDER BY created_at DESC LIMIT ?`, args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("RecallMemoriesAbout: %w", err)
+	}
+	var out []MemoryRecord
+	for rows.Next() {
+		var m MemoryRecord
+		if err := rows.Scan(
+			&m.ID, &m.AgentID, &m.Type, &m.Content, &m.EmotionalTag, &m.Importance, &m.AccessCount,
+			&m.LastAccessed, &m.RelatedAgents, &m.Metadata, &m.CreatedAt, &m.TouchedTick,
+		); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("RecallMemoriesAbout scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("RecallMemoriesAbout: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range out {
+		if _, err := r.DB.Exec(
+			`UPDATE memories SET access_count = access_count + 1, last_accessed = ?, touched_tick = ? WHERE id = ?`,
+			now, tick, out[i].ID,
+		); err != nil {
+			return nil, fmt.Errorf("RecallMemoriesAbout touch: %w", err)
+		}
+	}
+
+	// От старых к новым — в порядке событий
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+// DecayMemories снижает importance на rate у воспоминаний, которые
+// не создавались и не вспоминались с тика since, и удаляет записи с
+// importance ниже threshold. Возвращает число изменённых и удалённых записей.
+func (r *Repository) DecayMemories(rate, threshold float64, since int64) (decayed, forgotten int64, err error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("DecayMemories: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE memories SET importance = MAX(importance - ?, 0)
+		 WHERE COALESCE(touched_tick, 0) < ?`,
+		rate, since,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("DecayMemories update: %w", err)
+	}
+	decayed, _ = res.RowsAffected()
+
+	res, err = tx.Exec(`DELETE FROM memories WHERE importance < ?`, threshold)
+	if err != nil {
+		return 0, 0, fmt.Errorf("DecayMemories delete: %w", err)
+	}
+	forgotten, _ = res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("DecayMemories commit: %w", err)
+	}
+	return decayed, forgotten, nil
+}
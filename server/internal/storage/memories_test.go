@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestDecayMemoriesByTick(t *testing.T) {
+	repo := newTestRepo(t)
+	if err := repo.CreateAgent(AgentRecord{ID: "a", Name: "Alice", Personality: "{}", State: "idle", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	memory := func(id string, tick int64, importance float64) MemoryRecord {
+		return MemoryRecord{
+			ID: id, AgentID: "a", Type: "episodic", Content: id, Importance: importance,
+			RelatedAgents: sql.NullString{String: `["b"]`, Valid: true},
+			TouchedTick:   sql.NullInt64{Int64: tick, Valid: tick > 0},
+			CreatedAt:     time.Now().UTC(),
+		}
+	}
+	for _, m := range []MemoryRecord{
+		memory("old", 1, 0.5),
+		memory("faint", 2, 0.1),
+		memory("fresh", 10, 0.5),
+		memory("untracked", 0, 0.5), // запись до миграции: тика нет
+		memory("recalled", 1, 0.5),
+	} {
+		if err := repo.SaveMemory(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Вспоминание на тике 12 обновляет touched_tick.
+	if _, err := repo.RecallMemoriesAbout("a", "episodic", []string{"b"}, 1, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	decayed, forgotten, err := repo.DecayMemories(0.2, 0.05, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Свежие — fresh и recalled (последняя созданная, её вспомнили).
+	if decayed != 3 || forgotten != 1 {
+		t.Errorf("decayed, forgotten = %d, %d; want 3, 1", decayed, forgotten)
+	}
+	if n, _ := repo.CountMemoriesByAgent("a"); n != 4 {
+		t.Errorf("memories = %d, want 4", n)
+	}
+}
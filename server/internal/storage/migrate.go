@@ -0,0 +1,346 @@
+// Package storage — миграции схемы SQLite.
+//
+// Миграции версионируются в таблице schema_migrations и применяются
+// по порядку, каждая в своей транзакции. Версия 1 — исходная схема
+// (CREATE ... IF NOT EXISTS), поэтому существующая society.db проходит
+// её без изменений, а пустая база получает все таблицы.
+
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// migration — одна версия схемы.
+type migration struct {
+	Version int
+	Name    string
+	Stmts   []string
+}
+
+// migrations — упорядоченный список миграций. Новые добавляются в конец.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "base schema",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS agents (
+				id              TEXT PRIMARY KEY,
+				name            TEXT NOT NULL,
+				personality     TEXT NOT NULL,
+				mood_state      TEXT,
+				goals           TEXT,
+				state           TEXT DEFAULT 'idle',
+				is_active       BOOLEAN DEFAULT 1,
+				created_at      DATETIME NOT NULL DEFAULT (datetime('now')),
+				last_active     DATETIME,
+				snapshot        TEXT
+			)`,
+			`CREATE TABLE IF NOT EXISTS relationships (
+				id                TEXT PRIMARY KEY,
+				agent1_id         TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+				agent2_id         TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+				type              TEXT NOT NULL,
+				strength          REAL DEFAULT 0.0,
+				interaction_count INTEGER DEFAULT 0,
+				last_interaction  DATETIME,
+				metadata          TEXT,
+				UNIQUE(agent1_id, agent2_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS memories (
+				id              TEXT PRIMARY KEY,
+				agent_id        TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+				type            TEXT NOT NULL,
+				content         TEXT NOT NULL,
+				emotional_tag   TEXT,
+				importance      REAL DEFAULT 0.5,
+				access_count    INTEGER DEFAULT 0,
+				last_accessed   DATETIME,
+				related_agents  TEXT,
+				metadata        TEXT,
+				created_at      DATETIME NOT NULL DEFAULT (datetime('now'))
+			)`,
+			`CREATE TABLE IF NOT EXISTS events (
+				id              TEXT PRIMARY KEY,
+				topic           TEXT NOT NULL,
+				type            TEXT NOT NULL,
+				source          TEXT NOT NULL,
+				affected_agents TEXT,
+				payload         TEXT,
+				status          TEXT DEFAULT 'pending',
+				tick            INTEGER,
+				created_at      DATETIME NOT NULL DEFAULT (datetime('now'))
+			)`,
+			`CREATE TABLE IF NOT EXISTS world_state (
+				key             TEXT PRIMARY KEY,
+				value           TEXT NOT NULL,
+				updated_at      DATETIME NOT NULL DEFAULT (datetime('now'))
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_agents_is_active ON agents(is_active)`,
+			`CREATE INDEX IF NOT EXISTS idx_agents_state ON agents(state)`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_agent1 ON relationships(agent1_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_agent2 ON relationships(agent2_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_type ON relationships(type)`,
+			`CREATE INDEX IF NOT EXISTS idx_memories_agent_id ON memories(agent_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_memories_type ON memories(agent_id, type)`,
+			`CREATE INDEX IF NOT EXISTS idx_memories_importance ON memories(importance)`,
+			`CREATE INDEX IF NOT EXISTS idx_memories_created_at ON memories(created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_topic ON events(topic)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_source ON events(source)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_status ON events(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_tick ON events(tick)`,
+			`INSERT OR IGNORE INTO world_state (key, value) VALUES
+				('current_tick', '0'),
+				('simulation_speed', '1.0'),
+				('is_paused', 'false')`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "llm_calls",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS llm_calls (
+				id                TEXT PRIMARY KEY,
+				agent_id          TEXT,                     -- NULL для системных вызовов
+				tick              INTEGER,
+				purpose           TEXT NOT NULL,            -- dialogue, human_reply, reflection, ...
+				model             TEXT,
+				prompt_tokens     INTEGER DEFAULT 0,
+				completion_tokens INTEGER DEFAULT 0,
+				latency_ms        INTEGER DEFAULT 0,
+				error             TEXT,                     -- NULL при успехе
+				created_at        DATETIME NOT NULL DEFAULT (datetime('now'))
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_llm_calls_agent ON llm_calls(agent_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_llm_calls_tick ON llm_calls(tick)`,
+			`CREATE INDEX IF NOT EXISTS idx_llm_calls_purpose ON llm_calls(purpose)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "llm_calls routing",
+		Stmts: []string{
+			`ALTER TABLE llm_calls ADD COLUMN routed_model TEXT`, // модель, выбранная Router
+			`ALTER TABLE llm_calls ADD COLUMN route_rule TEXT`,   // сработавшее правило
+		},
+	},
+	{
+		Version: 4,
+		Name:    "conversations",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS conversations (
+				id            TEXT PRIMARY KEY,
+				initiator_id  TEXT NOT NULL,
+				participants  TEXT NOT NULL,            -- JSON-массив id всех участников
+				start_tick    INTEGER NOT NULL,
+				end_tick      INTEGER,                  -- NULL = разговор идёт
+				end_reason    TEXT,
+				topic         TEXT,
+				summary       TEXT,
+				started_at    DATETIME NOT NULL,
+				ended_at      DATETIME
+			)`,
+			`CREATE TABLE IF NOT EXISTS conversation_turns (
+				conversation_id TEXT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+				seq             INTEGER NOT NULL,       -- номер реплики с 0
+				speaker_id      TEXT NOT NULL,
+				target_id       TEXT,                   -- NULL = обращение ко всем
+				content         TEXT NOT NULL,
+				tick            INTEGER NOT NULL,
+				created_at      DATETIME NOT NULL,
+				PRIMARY KEY (conversation_id, seq)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_conversations_start_tick ON conversations(start_tick)`,
+			`CREATE INDEX IF NOT EXISTS idx_conversation_turns_speaker ON conversation_turns(speaker_id)`,
+		},
+	},
+	{
+		// Связь становится направленной: agent1_id относится к agent2_id.
+		// Каждая старая симметричная строка даёт два ребра, измерения
+		// trust/affection/respect стартуют с прежней силы.
+		Version: 5,
+		Name:    "directed relationships",
+		Stmts: []string{
+			`CREATE TABLE relationships_directed (
+				id                TEXT PRIMARY KEY,
+				agent1_id         TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE, -- кто относится
+				agent2_id         TEXT NOT NULL REFERENCES agents(id) ON DELETE CASCADE, -- к кому
+				type              TEXT NOT NULL,
+				strength          REAL DEFAULT 0.0,  -- среднее trust, affection, respect
+				trust             REAL DEFAULT 0.0,
+				affection         REAL DEFAULT 0.0,
+				respect           REAL DEFAULT 0.0,
+				interaction_count INTEGER DEFAULT 0,
+				last_interaction  DATETIME,
+				metadata          TEXT,
+				UNIQUE(agent1_id, agent2_id)
+			)`,
+			`INSERT INTO relationships_directed
+				(id, agent1_id, agent2_id, type, strength, trust, affection, respect, interaction_count, last_interaction, metadata)
+			 SELECT id, agent1_id, agent2_id, type, strength, strength, strength, strength, interaction_count, last_interaction, metadata
+			 FROM relationships`,
+			`INSERT OR IGNORE INTO relationships_directed
+				(id, agent1_id, agent2_id, type, strength, trust, affection, respect, interaction_count, last_interaction, metadata)
+			 SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+			              substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+			        agent2_id, agent1_id, type, strength, strength, strength, strength, interaction_count, last_interaction, metadata
+			 FROM relationships`,
+			`DROP TABLE relationships`,
+			`ALTER TABLE relationships_directed RENAME TO relationships`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_agent1 ON relationships(agent1_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_agent2 ON relationships(agent2_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_relationships_type ON relationships(type)`,
+		},
+	},
+	{
+		// Пространство: зоны города со смежностью и положение агентов.
+		// x, y — координаты для отрисовки карты, 0..1.
+		Version: 6,
+		Name:    "zones",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS zones (
+				id          TEXT PRIMARY KEY,
+				name        TEXT NOT NULL,
+				description TEXT,
+				x           REAL NOT NULL DEFAULT 0,
+				y           REAL NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS zone_links (
+				zone_id     TEXT NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+				neighbor_id TEXT NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+				PRIMARY KEY (zone_id, neighbor_id)
+			)`,
+			`INSERT OR IGNORE INTO zones (id, name, description, x, y) VALUES
+				('square',  'Town Square',    'The heart of town, where everyone passes by', 0.50, 0.50),
+				('market',  'Market',         'Busy stalls, haggling and gossip',             0.75, 0.50),
+				('east',    'Eastern Fields', 'Quiet fields at the eastern edge of town',     0.95, 0.45),
+				('park',    'Park',           'Shady paths and benches for long talks',       0.50, 0.20),
+				('library', 'Library',        'A calm place to read and think',               0.25, 0.45),
+				('harbor',  'Harbor',         'Boats, nets and travellers from afar',         0.60, 0.85)`,
+			`INSERT OR IGNORE INTO zone_links (zone_id, neighbor_id) VALUES
+				('square', 'market'), ('market', 'square'),
+				('market', 'east'), ('east', 'market'),
+				('square', 'park'), ('park', 'square'),
+				('square', 'library'), ('library', 'square'),
+				('library', 'park'), ('park', 'library'),
+				('square', 'harbor'), ('harbor', 'square'),
+				('market', 'harbor'), ('harbor', 'market')`,
+			`ALTER TABLE agents ADD COLUMN location TEXT REFERENCES zones(id) DEFAULT 'square'`,
+			`UPDATE agents SET location = 'square' WHERE location IS NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_agents_location ON agents(location)`,
+		},
+	},
+	{
+		// Явный номер события seq (SSE id:) вместо скрытого rowid: rowid
+		// может перенумеровать VACUUM, а номера удалённых строк — достаться
+		// новым. AUTOINCREMENT не выдаёт номер повторно; старые события
+		// сохраняют прежние номера, чтобы Last-Event-ID клиентов остался верен.
+		Version: 7,
+		Name:    "event seq",
+		Stmts: []string{
+			`CREATE TABLE events_seq (
+				seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+				id              TEXT NOT NULL UNIQUE,
+				topic           TEXT NOT NULL,
+				type            TEXT NOT NULL,
+				source          TEXT NOT NULL,
+				affected_agents TEXT,
+				payload         TEXT,
+				status          TEXT DEFAULT 'pending',
+				tick            INTEGER,
+				created_at      DATETIME NOT NULL DEFAULT (datetime('now'))
+			)`,
+			`INSERT INTO events_seq (seq, id, topic, type, source, affected_agents, payload, status, tick, created_at)
+			 SELECT rowid, id, topic, type, source, affected_agents, payload, status, tick, created_at
+			 FROM events ORDER BY rowid`,
+			`DROP TABLE events`,
+			`ALTER TABLE events_seq RENAME TO events`,
+			`CREATE INDEX IF NOT EXISTS idx_events_topic ON events(topic)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_source ON events(source)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_status ON events(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_events_tick ON events(tick)`,
+		},
+	},
+	{
+		// Свежесть воспоминаний по часам симуляции: тик создания или
+		// последнего обращения. Старые записи без тика считаются давними.
+		Version: 8,
+		Name:    "memory touched tick",
+		Stmts: []string{
+			`ALTER TABLE memories ADD COLUMN touched_tick INTEGER`,
+		},
+	},
+	{
+		// Провайдер, который ответил: при ответе резерва маршрут
+		// (routed_model) не применялся.
+		Version: 9,
+		Name:    "llm_calls provider",
+		Stmts: []string{
+			`ALTER TABLE llm_calls ADD COLUMN provider TEXT`,
+		},
+	},
+}
+
+// Migrate применяет все ещё не применённые миграции. Идемпотентен.
+func (r *Repository) Migrate() error {
+	if _, err := r.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := r.DB.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("Migrate list: %w", err)
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return fmt.Errorf("Migrate scan: %w", err)
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Migrate list: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := r.applyMigration(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) applyMigration(m migration) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("Migrate %d begin: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.Stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("Migrate %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("Migrate %d record: %w", m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Migrate %d commit: %w", m.Version, err)
+	}
+	return nil
+}
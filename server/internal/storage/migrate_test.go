@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB открывает пустую SQLite-базу во временном каталоге.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "society.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestRepo — Repository над пустой базой со всеми миграциями.
+func newTestRepo(t *testing.T) *Repository {
+	t.Helper()
+	repo := NewRepository(openTestDB(t))
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return repo
+}
+
+// TestMigrateBaseline прогоняет все миграции по базе в исходной схеме
+// (как society.db до появления schema_migrations) с данными.
+func TestMigrateBaseline(t *testing.T) {
+	db := openTestDB(t)
+	for _, stmt := range migrations[0].Stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("baseline schema: %v", err)
+		}
+	}
+	for _, stmt := range []string{
+		`INSERT INTO agents (id, name, personality) VALUES ('a', 'Alice', '{}'), ('b', 'Bob', '{}')`,
+		`INSERT INTO relationships (id, agent1_id, agent2_id, type, strength, interaction_count)
+		 VALUES ('r1', 'a', 'b', 'friend', 0.5, 3)`,
+		`INSERT INTO memories (id, agent_id, type, content) VALUES ('m1', 'a', 'episodic', 'Met Bob')`,
+		`INSERT INTO events (id, topic, type, source) VALUES ('e1', 'system', 'x', 'test'), ('e2', 'system', 'x', 'test'), ('e3', 'system', 'x', 'test')`,
+		`DELETE FROM events WHERE id = 'e2'`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	repo := NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+
+	var versions int
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions)
+	if versions != len(migrations) {
+		t.Errorf("applied migrations = %d, want %d", versions, len(migrations))
+	}
+
+	// Данные исходной схемы пережили миграции; симметричная связь
+	// стала двумя направленными.
+	for table, want := range map[string]int{"agents": 2, "relationships": 2, "memories": 1, "events": 2} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil || n != want {
+			t.Errorf("%s rows = %d, %v; want %d", table, n, err, want)
+		}
+	}
+
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "a"}} {
+		rel, err := repo.GetRelationship(pair[0], pair[1])
+		if err != nil || rel == nil || rel.Type != "friend" || rel.Trust != 0.5 || rel.InteractionCount != 3 {
+			t.Errorf("relationship %s → %s = %+v, %v", pair[0], pair[1], rel, err)
+		}
+	}
+
+	// Существующие агенты стоят на площади.
+	var located int
+	db.QueryRow(`SELECT COUNT(*) FROM agents WHERE location = 'square'`).Scan(&located)
+	if located != 2 {
+		t.Errorf("agents in square = %d, want 2", located)
+	}
+
+	// Номера старых событий сохранились, новые не занимают номер удалённого.
+	var seqs []int64
+	rows, err := db.Query(`SELECT seq FROM events ORDER BY seq`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var seq int64
+		rows.Scan(&seq)
+		seqs = append(seqs, seq)
+	}
+	rows.Close()
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 3 {
+		t.Errorf("event seqs = %v, want [1 3]", seqs)
+	}
+	db.Exec(`DELETE FROM events WHERE seq = 3`)
+	rec := &EventRecord{Topic: "system", Type: "x", Source: "test"}
+	if err := repo.SaveEvent(rec); err != nil || rec.Seq != 4 {
+		t.Errorf("new event seq = %d, %v; want 4", rec.Seq, err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package storage — отношения между агентами.
+//
+// Чтение таблицы relationships для выбора собеседников и графа отношений,
+// изменение связей по итогам разговоров и правки оператора. Связь
+// направленная: строка (agent1_id, agent2_id) — отношение agent1 к agent2
+// с измерениями trust/affection/respect, обратное отношение хранится
+// отдельной строкой. Strength — среднее измерений, пересчитывается при
+// каждой записи.
+
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RelationshipNeutral — тип новой связи.
+const RelationshipNeutral = "neutral"
+
+// ErrRelationshipExists — у пары уже есть связь.
+var ErrRelationshipExists = errors.New("relationship already exists")
+
+// RelationshipDelta — изменение измерений связи.
+type RelationshipDelta struct {
+	Trust     float64
+	Affection float64
+	Respect   float64
+}
+
+const relationshipColumns = `id, agent1_id, agent2_id, type, strength, trust, affection, respect,
+	interaction_count, last_interaction, metadata`
+
+// ListRelationships возвращает все связи.
+func (r *Repository) ListRelationships() ([]RelationshipRecord, error) {
+	rows, err := r.DB.Query(
+		`SELECT ` + relationshipColumns + ` FROM relationships`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListRelationships: %w", err)
+	}
+	defer rows.Close()
+
+	var rels []RelationshipRecord
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListRelationships scan: %w", err)
+		}
+		rels = append(rels, rel)
+	}
+	return rels, rows.Err()
+}
+
+// ListRelationshipsByAgent возвращает связи агента в обе стороны —
+// его отношения к другим и их отношения к нему, сильные первыми.
+func (r *Repository) ListRelationshipsByAgent(agentID string) ([]RelationshipRecord, error) {
+	rows, err := r.DB.Query(
+		`SELECT `+relationshipColumns+` FROM relationships
+		 WHERE agent1_id = ? OR agent2_id = ?
+		 ORDER BY ABS(strength) DESC`,
+		agentID, agentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListRelationshipsByAgent: %w", err)
+	}
+	defer rows.Close()
+
+	var rels []RelationshipRecord
+	for rows.Next() {
+		rel, err := scanRelationship(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListRelationshipsByAgent scan: %w", err)
+		}
+		rels = append(rels, rel)
+	}
+	return rels, rows.Err()
+}
+
+// GetRelationship возвращает отношение from к to. nil — связи нет.
+func (r *Repository) GetRelationship(from, to string) (*RelationshipRecord, error) {
+	rel, err := findRelationship(r.DB, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("GetRelationship: %w", err)
+	}
+	return rel, nil
+}
+
+// GetRelationshipGraph возвращает направленный граф отношений активных
+// агентов: узлы с числом собеседников и рёбра между ними.
+func (r *Repository) GetRelationshipGraph() (GraphData, error) {
+	var g GraphData
+	rows, err := r.DB.Query(
+		`SELECT a.id, a.name, a.personality,
+		        (SELECT COUNT(DISTINCT CASE WHEN r.agent1_id = a.id THEN r.agent2_id ELSE r.agent1_id END)
+		         FROM relationships r
+		         JOIN agents o ON o.id = CASE WHEN r.agent1_id = a.id THEN r.agent2_id ELSE r.agent1_id END
+		         WHERE (r.agent1_id = a.id OR r.agent2_id = a.id) AND o.is_active = 1)
+		 FROM agents a WHERE a.is_active = 1 ORDER BY a.name`,
+	)
+	if err != nil {
+		return g, fmt.Errorf("GetRelationshipGraph nodes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n GraphNode
+		if err := rows.Scan(&n.ID, &n.Name, &n.Personality, &n.RelationCount); err != nil {
+			return g, fmt.Errorf("GetRelationshipGraph nodes scan: %w", err)
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return g, fmt.Errorf("GetRelationshipGraph nodes: %w", err)
+	}
+
+	edges, err := r.DB.Query(
+		`SELECT r.agent1_id, r.agent2_id, r.type, r.strength, r.trust, r.affection, r.respect, r.metadata
+		 FROM relationships r
+		 JOIN agents a1 ON a1.id = r.agent1_id AND a1.is_active = 1
+		 JOIN agents a2 ON a2.id = r.agent2_id AND a2.is_active = 1`,
+	)
+	if err != nil {
+		return g, fmt.Errorf("GetRelationshipGraph edges: %w", err)
+	}
+	defer edges.Close()
+	for edges.Next() {
+		var e GraphEdge
+		var meta sql.NullString
+		if err := edges.Scan(&e.Source, &e.Target, &e.Type, &e.Strength, &e.Trust, &e.Affection, &e.Respect, &meta); err != nil {
+			return g, fmt.Errorf("GetRelationshipGraph edges scan: %w", err)
+		}
+		e.Label = RelationshipNote(meta)
+		g.Edges = append(g.Edges, e)
+	}
+	return g, edges.Err()
+}
+
+// SetRelationships записывает рёбра одной транзакцией. Если какое-то
+// ребро уже есть и overwrite = false, ничего не пишется и возвращается
+// ErrRelationshipExists. Существующие рёбра получают новые тип,
+// измерения и метаданные, счётчик взаимодействий сохраняется.
+// Возвращает рёбра после записи и до неё (nil — ребра не было).
+func (r *Repository) SetRelationships(rels []RelationshipRecord, overwrite bool) ([]RelationshipRecord, []*RelationshipRecord, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("SetRelationships: %w", err)
+	}
+	defer tx.Rollback()
+
+	before := make([]*RelationshipRecord, len(rels))
+	for i, rel := range rels {
+		if before[i], err = findRelationship(tx, rel.Agent1ID, rel.Agent2ID); err != nil {
+			return nil, nil, fmt.Errorf("SetRelationships find: %w", err)
+		}
+		if before[i] != nil && !overwrite {
+			return nil, before, ErrRelationshipExists
+		}
+	}
+
+	after := make([]RelationshipRecord, len(rels))
+	for i, rel := range rels {
+		rel.Trust, rel.Affection, rel.Respect = clampStrength(rel.Trust), clampStrength(rel.Affection), clampStrength(rel.Respect)
+		rel.Strength = relationshipStrength(rel)
+		if before[i] == nil {
+			if rel.ID == "" {
+				rel.ID = uuid.New().String()
+			}
+			_, err = tx.Exec(
+				`INSERT INTO relationships
+				 (id, agent1_id, agent2_id, type, strength, trust, affection, respect, interaction_count, last_interaction, metadata)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				rel.ID, rel.Agent1ID, rel.Agent2ID, rel.Type, rel.Strength, rel.Trust, rel.Affection, rel.Respect,
+				rel.InteractionCount, rel.LastInteraction, rel.Metadata,
+			)
+		} else {
+			rel.ID = before[i].ID
+			rel.InteractionCount, rel.LastInteraction = before[i].InteractionCount, before[i].LastInteraction
+			_, err = tx.Exec(
+				`UPDATE relationships SET type = ?, strength = ?, trust = ?, affection = ?, respect = ?, metadata = ?
+				 WHERE id = ?`,
+				rel.Type, rel.Strength, rel.Trust, rel.Affection, rel.Respect, rel.Metadata, rel.ID,
+			)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("SetRelationships write: %w", err)
+		}
+		after[i] = rel
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("SetRelationships commit: %w", err)
+	}
+	return after, before, nil
+}
+
+// RelationshipNote возвращает заметку оператора о связи (metadata.note).
+func RelationshipNote(meta sql.NullString) string {
+	if !meta.Valid {
+		return ""
+	}
+	var m struct {
+		Note string `json:"note"`
+	}
+	json.Unmarshal([]byte(meta.String), &m)
+	return m.Note
+}
+
+// ApplyRelationshipDelta добавляет delta к измерениям отношения from к to
+// (с ограничением -1..1), пересчитывает силу, увеличивает
+// interaction_count и обновляет last_interaction. Связи нет — создаётся
+// нейтральная. Возвращает связь до изменения (nil — её не было) и после.
+func (r *Repository) ApplyRelationshipDelta(from, to string, delta RelationshipDelta, at time.Time) (*RelationshipRecord, RelationshipRecord, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, RelationshipRecord{}, fmt.Errorf("ApplyRelationshipDelta: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := findRelationship(tx, from, to)
+	if err != nil {
+		return nil, RelationshipRecord{}, fmt.Errorf("ApplyRelationshipDelta find: %w", err)
+	}
+
+	rel := RelationshipRecord{ID: uuid.New().String(), Type: RelationshipNeutral}
+	if before != nil {
+		rel = *before
+	}
+	rel.Trust = clampStrength(rel.Trust + delta.Trust)
+	rel.Affection = clampStrength(rel.Affection + delta.Affection)
+	rel.Respect = clampStrength(rel.Respect + delta.Respect)
+	rel.Strength = relationshipStrength(rel)
+
+	if before == nil {
+		_, err = tx.Exec(
+			`INSERT INTO relationships
+			 (id, agent1_id, agent2_id, type, strength, trust, affection, respect, interaction_count, last_interaction)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?)`,
+			rel.ID, from, to, rel.Type, rel.Strength, rel.Trust, rel.Affection, rel.Respect, at.UTC(),
+		)
+	} else {
+		_, err = tx.Exec(
+			`UPDATE relationships
+			 SET strength = ?, trust = ?, affection = ?, respect = ?,
+			     interaction_count = interaction_count + 1, last_interaction = ?
+			 WHERE id = ?`,
+			rel.Strength, rel.Trust, rel.Affection, rel.Respect, at.UTC(), rel.ID,
+		)
+	}
+	if err != nil {
+		return nil, RelationshipRecord{}, fmt.Errorf("ApplyRelationshipDelta write: %w", err)
+	}
+
+	after, err := scanRelationship(tx.QueryRow(`SELECT `+relationshipColumns+` FROM relationships WHERE id = ?`, rel.ID))
+	if err != nil {
+		return nil, RelationshipRecord{}, fmt.Errorf("ApplyRelationshipDelta read: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, RelationshipRecord{}, fmt.Errorf("ApplyRelationshipDelta commit: %w", err)
+	}
+	return before, after, nil
+}
+
+// SetRelationshipType меняет тип связи.
+func (r *Repository) SetRelationshipType(id, relType string) error {
+	if _, err := r.DB.Exec(`UPDATE relationships SET type = ? WHERE id = ?`, relType, id); err != nil {
+		return fmt.Errorf("SetRelationshipType: %w", err)
+	}
+	return nil
+}
+
+// querier — общий интерфейс *sql.DB и *sql.Tx для чтения.
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// findRelationship ищет отношение from к to. nil — связи нет.
+func findRelationship(q querier, from, to string) (*RelationshipRecord, error) {
+	rel, err := scanRelationship(q.QueryRow(
+		`SELECT `+relationshipColumns+` FROM relationships WHERE agent1_id = ? AND agent2_id = ?`,
+		from, to,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+func scanRelationship(s scanner) (RelationshipRecord, error) {
+	var rel RelationshipRecord
+	err := s.Scan(
+		&rel.ID, &rel.Agent1ID, &rel.Agent2ID, &rel.Type, &rel.Strength,
+		&rel.Trust, &rel.Affection, &rel.Respect,
+		&rel.InteractionCount, &rel.LastInteraction, &rel.Metadata,
+	)
+	return rel, err
+}
+
+// relationshipStrength — сила связи как среднее измерений.
+func relationshipStrength(rel RelationshipRecord) float64 {
+	return (rel.Trust + rel.Affection + rel.Respect) / 3
+}
+
+func clampStrength(v float64) float64 {
+	return min(max(v, -1), 1)
+}
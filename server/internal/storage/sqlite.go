@@ -8,6 +8,7 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -68,6 +69,9 @@ type AgentRecord struct {
 
 	// Snapshot — JSON-blob полного состояния для восстановления после рестарта.
 	Snapshot sql.NullString
+
+	// Location — зона, где агент находится (FK → zones.id).
+	Location sql.NullString
 }
 
 // AgentFilter — параметры фильтрации для ListAgents().
@@ -102,24 +106,34 @@ type RelationshipRecord struct {
 	// ID — UUID связи.
 	ID string
 
-	// Agent1ID, Agent2ID — UUID участников (FK → agents.id).
+	// Agent1ID, Agent2ID — UUID участников (FK → agents.id). Связь
+	// направленная: так Agent1 относится к Agent2. Обратное отношение —
+	// отдельная строка.
 	Agent1ID string
 	Agent2ID string
 
-	// Type — тип связи: "friend", "rival", "neutral", "romantic".
+	// Type — кем Agent2 приходится Agent1: "neutral", "friend", "close",
+	// "rival", "romantic", "mentor", "student", "family", "colleague".
 	Type string
 
 	// Strength — сила связи: -1.0 (враждебность) до +1.0 (тесная связь).
-	// Знак определяет характер, абсолютное значение — интенсивность.
+	// Среднее Trust, Affection и Respect; пересчитывается при записи.
 	Strength float64
 
+	// Trust, Affection, Respect — измерения отношения, -1.0..+1.0:
+	// доверие, симпатия и уважение Agent1 к Agent2.
+	Trust     float64
+	Affection float64
+	Respect   float64
+
 	// InteractionCount — сколько раз эти агенты взаимодействовали.
 	InteractionCount int
 
 	// LastInteraction — время последнего взаимодействия.
 	LastInteraction sql.NullTime
 
-	// Metadata — JSON-blob дополнительного контекста.
+	// Metadata — JSON-blob дополнительного контекста. Правки оператора
+	// пишут сюда {"note", "setBy", "setAt"}.
 	Metadata sql.NullString
 }
 
@@ -132,18 +146,27 @@ type GraphData struct {
 
 // GraphNode — узел графа (агент).
 type GraphNode struct {
-	ID              string
-	Name            string
-	PersonalityType string
-	RelationCount   int
+	ID   string
+	Name string
+
+	// Personality — JSON Big Five; тип личности вычисляет API.
+	Personality   string
+	RelationCount int
 }
 
-// GraphEdge — ребро графа (связь).
+// GraphEdge — ребро графа (связь), направленное: Source относится к Target.
 type GraphEdge struct {
 	Source   string
 	Target   string
 	Type     string
 	Strength float64
+
+	Trust     float64
+	Affection float64
+	Respect   float64
+
+	// Label — заметка оператора о связи.
+	Label string
 }
 
 // -----------------------------------------------------------------------------
@@ -175,6 +198,10 @@ type MemoryRecord struct {
 	// LastAccessed — время последнего обращения.
 	LastAccessed sql.NullTime
 
+	// TouchedTick — тик создания или последнего обращения (часы симуляции).
+	// По нему забывание отсчитывает период защиты свежих записей.
+	TouchedTick sql.NullInt64
+
 	// RelatedAgents — JSON-массив UUID агентов, вовлечённых в событие.
 	RelatedAgents sql.NullString
 
@@ -193,6 +220,10 @@ type EventRecord struct {
 	// ID — UUID события.
 	ID string
 
+	// Seq — колонка seq: монотонный номер события (SSE id:), номера
+	// не переиспользуются. Заполняется при вставке и чтении.
+	Seq int64
+
 	// Topic — топик маршрутизации: "global", "interaction", "mood_change" и т.д.
 	Topic string
 
@@ -223,12 +254,18 @@ type EventFilter struct {
 	// Topic — фильтр по топику. Пустая строка = все.
 	Topic string
 
+	// Type — фильтр по типу события ("conversation"). Пустая строка = все.
+	Type string
+
 	// Status — фильтр по статусу.
 	Status string
 
 	// Source — фильтр по источнику.
 	Source string
 
+	// AgentID — только события, затрагивающие агента (affected_agents).
+	AgentID string
+
 	// Limit — максимальное количество результатов.
 	Limit int
 }
@@ -269,7 +306,7 @@ func (r *Repository) ListAgents(filter AgentFilter) ([]AgentRecord, int, error)
 	}
 
 	query := fmt.Sprintf(
-		`SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot
+		`SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot, location
 		 FROM agents %s ORDER BY created_at DESC LIMIT ? OFFSET ?`,
 		where,
 	)
@@ -286,7 +323,7 @@ func (r *Repository) ListAgents(filter AgentFilter) ([]AgentRecord, int, error)
 		var a AgentRecord
 		if err := rows.Scan(
 			&a.ID, &a.Name, &a.Personality, &a.MoodState, &a.Goals,
-			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot,
+			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot, &a.Location,
 		); err != nil {
 			return nil, 0, fmt.Errorf("ListAgents scan: %w", err)
 		}
@@ -297,13 +334,13 @@ func (r *Repository) ListAgents(filter AgentFilter) ([]AgentRecord, int, error)
 
 // GetAgentByID возвращает агента по UUID. Если не найден — (nil, nil).
 func (r *Repository) GetAgentByID(id string) (*AgentRecord, error) {
-	query := `SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot
+	query := `SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot, location
               FROM agents WHERE id = ?`
 	row := r.DB.QueryRow(query, id)
 	var a AgentRecord
 	err := row.Scan(
 		&a.ID, &a.Name, &a.Personality, &a.MoodState, &a.Goals,
-		&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot,
+		&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot, &a.Location,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -314,6 +351,37 @@ func (r *Repository) GetAgentByID(id string) (*AgentRecord, error) {
 	return &a, nil
 }
 
+// GetAgentsByIDs возвращает агентов с данными id одним запросом, по id.
+// Неизвестные id пропускаются.
+func (r *Repository) GetAgentsByIDs(ids []string) (map[string]AgentRecord, error) {
+	out := make(map[string]AgentRecord, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := `SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot, location
+              FROM agents WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetAgentsByIDs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a AgentRecord
+		if err := rows.Scan(
+			&a.ID, &a.Name, &a.Personality, &a.MoodState, &a.Goals,
+			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot, &a.Location,
+		); err != nil {
+			return nil, fmt.Errorf("GetAgentsByIDs scan: %w", err)
+		}
+		out[a.ID] = a
+	}
+	return out, rows.Err()
+}
+
 // CreateAgent вставляет нового агента. rec.ID должен быть заполнен (UUID).
 func (r *Repository) CreateAgent(rec AgentRecord) error {
 	query := `INSERT INTO agents (id, name, personality, state, is_active, created_at)
@@ -354,12 +422,13 @@ func (r *Repository) CountMemoriesByAgent(agentID string) (int, error) {
 // MemoriesByAgent возвращает воспоминания агента
 // func (r *Repository) MemoriesByAgent(agentId string, memType string, limit int) ([]*MemoryRecord, error)
 
-// CountRelationshipsByAgent возвращает количество связей агента.
+// CountRelationshipsByAgent возвращает количество связей агента (исходящих:
+// к скольким агентам у него есть отношение).
 func (r *Repository) CountRelationshipsByAgent(agentID string) (int, error) {
 	var count int
 	err := r.DB.QueryRow(
-		`SELECT COUNT(*) FROM relationships WHERE agent1_id = ? OR agent2_id = ?`,
-		agentID, agentID,
+		`SELECT COUNT(*) FROM relationships WHERE agent1_id = ?`,
+		agentID,
 	).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("CountRelationshipsByAgent: %w", err)
@@ -371,8 +440,8 @@ func (r *Repository) CountRelationshipsByAgent(agentID string) (int, error) {
 func (r *Repository) CountInteractionsByAgent(agentID string) (int, error) {
 	var count int
 	err := r.DB.QueryRow(
-		`SELECT COALESCE(SUM(interaction_count), 0) FROM relationships WHERE agent1_id = ? OR agent2_id = ?`,
-		agentID, agentID,
+		`SELECT COALESCE(SUM(interaction_count), 0) FROM relationships WHERE agent1_id = ?`,
+		agentID,
 	).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("CountInteractionsByAgent: %w", err)
@@ -412,9 +481,56 @@ func (r *Repository) GetWorldState() (map[string]string, error) {
 	return state, rows.Err()
 }
 
+// Ключи world_state.
+const (
+	WorldStateCurrentTick     = "current_tick"     // номер последнего тика
+	WorldStateSimTime         = "sim_time"         // время симуляции последнего тика (RFC 3339)
+	WorldStateSimulationSpeed = "simulation_speed" // множитель скорости 0.1–10
+	WorldStateIsPaused        = "is_paused"        // "true" / "false"
+	WorldStateLLMRouting      = "llm_routing"      // переопределения маршрутов LLM (JSON)
+)
+
+// SetWorldState записывает одно значение в world_state (upsert).
+func (r *Repository) SetWorldState(key, value string) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO world_state (key, value, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, value, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("SetWorldState %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetActiveAgents возвращает всех активных агентов.
+func (r *Repository) GetActiveAgents() ([]AgentRecord, error) {
+	rows, err := r.DB.Query(
+		`SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot, location
+		 FROM agents WHERE is_active = true ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveAgents: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []AgentRecord
+	for rows.Next() {
+		var a AgentRecord
+		if err := rows.Scan(
+			&a.ID, &a.Name, &a.Personality, &a.MoodState, &a.Goals,
+			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot, &a.Location,
+		); err != nil {
+			return nil, fmt.Errorf("GetActiveAgents scan: %w", err)
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
 // GetRandomActiveAgents возвращает до n случайных активных агентов.
 func (r *Repository) GetRandomActiveAgents(n int) ([]AgentRecord, error) {
-	query := `SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot
+	query := `SELECT id, name, personality, mood_state, goals, state, is_active, created_at, last_active, snapshot, location
 	          FROM agents WHERE is_active = true ORDER BY RANDOM() LIMIT ?`
 	rows, err := r.DB.Query(query, n)
 	if err != nil {
@@ -427,7 +543,7 @@ func (r *Repository) GetRandomActiveAgents(n int) ([]AgentRecord, error) {
 		var a AgentRecord
 		if err := rows.Scan(
 			&a.ID, &a.Name, &a.Personality, &a.MoodState, &a.Goals,
-			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot,
+			&a.State, &a.IsActive, &a.CreatedAt, &a.LastActive, &a.Snapshot, &a.Location,
 		); err != nil {
 			return nil, fmt.Errorf("GetRandomActiveAgents scan: %w", err)
 		}
@@ -436,6 +552,154 @@ func (r *Repository) GetRandomActiveAgents(n int) ([]AgentRecord, error) {
 	return agents, rows.Err()
 }
 
+// SaveEvent вставляет произвольное событие в таблицу events.
+// Пустые ID, Status и CreatedAt заполняются значениями по умолчанию,
+// rec.Seq получает номер вставленной строки.
+func (r *Repository) SaveEvent(rec *EventRecord) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.Status == "" {
+		rec.Status = "completed"
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now().UTC()
+	}
+	seq, err := insertEvent(r.DB, *rec)
+	if err != nil {
+		return fmt.Errorf("SaveEvent: %w", err)
+	}
+	rec.Seq = seq
+	return nil
+}
+
+// execer — общий интерфейс *sql.DB и *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertEvent вставляет событие как есть, без значений по умолчанию.
+// Возвращает seq новой строки.
+func insertEvent(db execer, rec EventRecord) (int64, error) {
+	res, err := db.Exec(
+		`INSERT INTO events (id, topic, type, source, affected_agents, payload, status, tick, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, rec.Topic, rec.Type, rec.Source, rec.AffectedAgents, rec.Payload,
+		rec.Status, rec.Tick, rec.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetEvents возвращает события по фильтру, новые первыми.
+// filter.Limit = 0 → 50 по умолчанию.
+func (r *Repository) GetEvents(filter EventFilter) ([]EventRecord, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT seq, id, topic, type, source, affected_agents, payload, status, tick, created_at
+	          FROM events WHERE 1=1`
+	args := []any{}
+	if filter.Topic != "" {
+		query += ` AND topic = ?`
+		args = append(args, filter.Topic)
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Source != "" {
+		query += ` AND source = ?`
+		args = append(args, filter.Source)
+	}
+	if filter.AgentID != "" {
+		query += ` AND affected_agents LIKE ?`
+		args = append(args, fmt.Sprintf("%%%q%%", filter.AgentID))
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []EventRecord
+	for rows.Next() {
+		var e EventRecord
+		if err := rows.Scan(
+			&e.Seq, &e.ID, &e.Topic, &e.Type, &e.Source, &e.AffectedAgents,
+			&e.Payload, &e.Status, &e.Tick, &e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("GetEvents scan: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventsAfter возвращает до limit событий с Seq > after, старые первыми.
+// Используется для досылки пропущенных SSE-событий.
+func (r *Repository) GetEventsAfter(after int64, limit int) ([]EventRecord, error) {
+	rows, err := r.DB.Query(
+		`SELECT seq, id, topic, type, source, affected_agents, payload, status, tick, created_at
+		 FROM events WHERE seq > ? ORDER BY seq LIMIT ?`, after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetEventsAfter: %w", err)
+	}
+	defer rows.Close()
+
+	var events []EventRecord
+	for rows.Next() {
+		var e EventRecord
+		if err := rows.Scan(
+			&e.Seq, &e.ID, &e.Topic, &e.Type, &e.Source, &e.AffectedAgents,
+			&e.Payload, &e.Status, &e.Tick, &e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("GetEventsAfter scan: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventByID возвращает событие или nil, если его нет.
+func (r *Repository) GetEventByID(id string) (*EventRecord, error) {
+	var e EventRecord
+	err := r.DB.QueryRow(
+		`SELECT seq, id, topic, type, source, affected_agents, payload, status, tick, created_at
+		 FROM events WHERE id = ?`, id,
+	).Scan(
+		&e.Seq, &e.ID, &e.Topic, &e.Type, &e.Source, &e.AffectedAgents,
+		&e.Payload, &e.Status, &e.Tick, &e.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetEventByID: %w", err)
+	}
+	return &e, nil
+}
+
+// UpdateEventStatus меняет статус события.
+func (r *Repository) UpdateEventStatus(id, status string) error {
+	if _, err := r.DB.Exec(`UPDATE events SET status = ? WHERE id = ?`, status, id); err != nil {
+		return fmt.Errorf("UpdateEventStatus: %w", err)
+	}
+	return nil
+}
+
 // SaveConversationEvent сохраняет одну реплику диалога в таблицу events.
 func (r *Repository) SaveConversationEvent(speakerID, targetID, content string, tick int64) error {
 	payload := fmt.Sprintf(`{"speakerId":%q,"targetId":%q,"content":%q}`,
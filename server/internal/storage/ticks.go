@@ -0,0 +1,103 @@
+// Package storage — часы симуляции в world_state.
+//
+// Номер тика и время симуляции пишутся в одной транзакции с событиями
+// тика: в БД не бывает события с тиком больше сохранённого current_tick,
+// поэтому после рестарта нумерация продолжается без повторов.
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SaveTick сохраняет события тика и продвигает current_tick/sim_time до tick.
+// Часы не откатываются: если в world_state уже более поздний тик (события
+// долгого тика пишутся, когда часы ушли вперёд), сохраняются только события.
+// Нулевой simTime не меняет sim_time. Поля событий (ID, Seq, Tick…)
+// заполняются на месте, как в SaveEvent.
+func (r *Repository) SaveTick(tick int64, simTime time.Time, events ...*EventRecord) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("SaveTick: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, e := range events {
+		if e.ID == "" {
+			e.ID = uuid.New().String()
+		}
+		if e.Status == "" {
+			e.Status = "completed"
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now().UTC()
+		}
+		if !e.Tick.Valid {
+			e.Tick = sql.NullInt64{Int64: tick, Valid: true}
+		}
+		seq, err := insertEvent(tx, *e)
+		if err != nil {
+			return fmt.Errorf("SaveTick event: %w", err)
+		}
+		e.Seq = seq
+	}
+
+	var stored string
+	err = tx.QueryRow(`SELECT value FROM world_state WHERE key = ?`, WorldStateCurrentTick).Scan(&stored)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("SaveTick read: %w", err)
+	}
+	current, _ := strconv.ParseInt(stored, 10, 64)
+
+	now := time.Now().UTC()
+	upsert := `INSERT INTO world_state (key, value, updated_at) VALUES (?, ?, ?)
+	           ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+	if tick > current {
+		if _, err := tx.Exec(upsert, WorldStateCurrentTick, strconv.FormatInt(tick, 10), now); err != nil {
+			return fmt.Errorf("SaveTick tick: %w", err)
+		}
+	}
+	// Тик мог продвинуть событие без времени (задача часов того же тика)
+	if tick >= current && !simTime.IsZero() {
+		if _, err := tx.Exec(upsert, WorldStateSimTime, simTime.UTC().Format(time.RFC3339Nano), now); err != nil {
+			return fmt.Errorf("SaveTick sim time: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("SaveTick commit: %w", err)
+	}
+	return nil
+}
+
+// LoadClock возвращает последний сохранённый тик и время симуляции.
+// Тик не меньше максимального events.tick — на случай БД, записанной
+// до сохранения тиков. Нулевое время = sim_time ещё не сохранялся.
+func (r *Repository) LoadClock() (int64, time.Time, error) {
+	state, err := r.GetWorldState()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("LoadClock: %w", err)
+	}
+	tick, _ := strconv.ParseInt(state[WorldStateCurrentTick], 10, 64)
+
+	var maxEvent sql.NullInt64
+	if err := r.DB.QueryRow(`SELECT MAX(tick) FROM events`).Scan(&maxEvent); err != nil {
+		return 0, time.Time{}, fmt.Errorf("LoadClock events: %w", err)
+	}
+	if maxEvent.Valid && maxEvent.Int64 > tick {
+		tick = maxEvent.Int64
+	}
+
+	var simTime time.Time
+	if v := state[WorldStateSimTime]; v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			simTime = t
+		}
+	}
+	return tick, simTime, nil
+}
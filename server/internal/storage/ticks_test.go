@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// tickEvent — событие диалога на тике tick.
+func tickEvent(tick int64) *EventRecord {
+	return &EventRecord{Topic: "interaction", Type: "conversation", Source: "a", Tick: sql.NullInt64{Int64: tick, Valid: true}}
+}
+
+func TestSaveTickLoadClock(t *testing.T) {
+	repo := newTestRepo(t)
+	if tick, simTime, err := repo.LoadClock(); err != nil || tick != 0 || !simTime.IsZero() {
+		t.Fatalf("empty clock = %d, %v, %v", tick, simTime, err)
+	}
+
+	t5 := time.Date(2026, 1, 1, 8, 5, 0, 0, time.UTC)
+	if err := repo.SaveTick(5, t5, tickEvent(5)); err != nil {
+		t.Fatal(err)
+	}
+	// События долгого тика 4 пишутся после тика 5 — часы не откатываются.
+	if err := repo.SaveTick(4, t5.Add(-time.Minute), tickEvent(4)); err != nil {
+		t.Fatal(err)
+	}
+	tick, simTime, err := repo.LoadClock()
+	if err != nil || tick != 5 || !simTime.Equal(t5) {
+		t.Errorf("clock = %d, %v, %v; want 5, %v", tick, simTime, err, t5)
+	}
+	if n, _ := repo.CountTotalEvents(); n != 2 {
+		t.Errorf("events = %d, want 2", n)
+	}
+
+	// Событие с тиком новее world_state (БД до сохранения тиков) продвигает часы.
+	if err := repo.SaveEvent(tickEvent(9)); err != nil {
+		t.Fatal(err)
+	}
+	if tick, _, _ := repo.LoadClock(); tick != 9 {
+		t.Errorf("clock = %d, want 9 from events", tick)
+	}
+}
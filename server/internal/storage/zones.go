@@ -0,0 +1,80 @@
+// Package storage — зоны города и положение агентов.
+//
+// Мир разбит на именованные зоны (zones), соседние зоны связаны
+// в zone_links (каждая связь записана в обе стороны). Агент находится
+// ровно в одной зоне — agents.location; новые агенты появляются
+// в DefaultZone.
+
+package storage
+
+import (
+	"fmt"
+)
+
+// DefaultZone — зона, где появляются новые агенты.
+const DefaultZone = "square"
+
+// ZoneRecord — строка из таблицы zones с соседями.
+type ZoneRecord struct {
+	// ID — короткий идентификатор ("square", "market").
+	ID string
+
+	// Name — отображаемое имя.
+	Name string
+
+	Description string
+
+	// X, Y — положение на карте, 0..1.
+	X float64
+	Y float64
+
+	// Neighbors — id смежных зон.
+	Neighbors []string
+}
+
+// ListZones возвращает все зоны с соседями, по имени.
+func (r *Repository) ListZones() ([]ZoneRecord, error) {
+	rows, err := r.DB.Query(`SELECT id, name, COALESCE(description, ''), x, y FROM zones ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("ListZones: %w", err)
+	}
+	var zones []ZoneRecord
+	index := make(map[string]int)
+	for rows.Next() {
+		var z ZoneRecord
+		if err := rows.Scan(&z.ID, &z.Name, &z.Description, &z.X, &z.Y); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ListZones scan: %w", err)
+		}
+		index[z.ID] = len(zones)
+		zones = append(zones, z)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListZones: %w", err)
+	}
+
+	links, err := r.DB.Query(`SELECT zone_id, neighbor_id FROM zone_links ORDER BY neighbor_id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListZones links: %w", err)
+	}
+	defer links.Close()
+	for links.Next() {
+		var from, to string
+		if err := links.Scan(&from, &to); err != nil {
+			return nil, fmt.Errorf("ListZones links scan: %w", err)
+		}
+		if i, ok := index[from]; ok {
+			zones[i].Neighbors = append(zones[i].Neighbors, to)
+		}
+	}
+	return zones, links.Err()
+}
+
+// SetAgentLocation переносит агента в зону.
+func (r *Repository) SetAgentLocation(agentID, zoneID string) error {
+	if _, err := r.DB.Exec(`UPDATE agents SET location = ? WHERE id = ?`, zoneID, agentID); err != nil {
+		return fmt.Errorf("SetAgentLocation: %w", err)
+	}
+	return nil
+}
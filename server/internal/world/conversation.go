@@ -0,0 +1,579 @@
+// Package world — групповые разговоры.
+//
+// Conversation — разговор двух и более агентов. Следующего говорящего
+// выбирает TurnPolicy (по умолчанию WeightedTurns: к кому обратились,
+// экстраверсия, доминирование, давно ли молчит). У каждого участника своя
+// история для LLM, собранная из общей стенограммы: свои реплики — от
+// assistant, чужие — с именем говорящего. Свободные агенты могут
+// подойти к разговору, а заскучавшие — уйти, пока остаётся хотя бы двое.
+// Когда разговор заканчивается, см. endings.go.
+
+package world
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+
+	"github.com/google/uuid"
+)
+
+// Participant — участник разговора.
+type Participant struct {
+	matchAgent
+
+	// Joined — номер реплики, перед которой агент присоединился.
+	Joined int
+
+	// LastTurn — номер последней реплики агента, -1 — ещё не говорил.
+	LastTurn int
+
+	// Spoke — сколько реплик сказал.
+	Spoke int
+
+	brain    *agent.Brain
+	history  []llm.Message
+	partners []agent.PartnerContext // что он знает о собеседниках, см. remind
+	nudged   bool                   // получил подсказку закругляться
+}
+
+// Name — имя участника.
+func (p *Participant) Name() string { return p.rec.Name }
+
+// ID — id агента.
+func (p *Participant) ID() string { return p.rec.ID }
+
+// silence — сколько реплик подряд участник молчит.
+func (p *Participant) silence(turn int) int {
+	if p.LastTurn < 0 {
+		return turn - p.Joined
+	}
+	return turn - p.LastTurn - 1
+}
+
+// TranscriptLine — реплика общей стенограммы.
+type TranscriptLine struct {
+	SpeakerID string
+	Speaker   string
+	Content   string
+	Tick      int64
+}
+
+// Conversation — идущий разговор.
+type Conversation struct {
+	// ID — UUID разговора (conversations.id, conversationId в событиях).
+	ID string
+
+	// StartTick — тик начала.
+	StartTick int64
+
+	// Pairing — пара, с которой разговор начался.
+	Pairing Pairing
+
+	// Participants — текущие участники; первым говорит инициатор.
+	Participants []*Participant
+
+	// Members — все, кто участвовал, включая ушедших.
+	Members []*Participant
+
+	// Transcript — все реплики по порядку.
+	Transcript []TranscriptLine
+
+	// Turn — номер следующей реплики.
+	Turn int
+
+	// Zone — зона, где идёт разговор; присоединиться могут только
+	// агенты из неё.
+	Zone string
+}
+
+func newConversation(pair Pairing, tick int64) *Conversation {
+	c := &Conversation{ID: uuid.New().String(), StartTick: tick, Pairing: pair, Zone: location(pair.Initiator)}
+	initiator := c.add(pair.Initiator.ID, newMatchAgent(pair.Initiator))
+	c.add(pair.Partner.ID, newMatchAgent(pair.Partner))
+
+	initiator.history = append(initiator.history, llm.Message{
+		Role: "user",
+		Content: fmt.Sprintf(
+			"You notice %s nearby. Start a conversation naturally based on your personality and current mood.",
+			pair.Partner.Name),
+	})
+	return c
+}
+
+// add добавляет участника без уведомления остальных.
+func (c *Conversation) add(id string, m matchAgent) *Participant {
+	p := &Participant{
+		matchAgent: m,
+		Joined:     c.Turn,
+		LastTurn:   -1,
+		brain:      agent.NewBrain(&m.personality),
+	}
+	c.Participants = append(c.Participants, p)
+	c.Members = append(c.Members, p)
+	return p
+}
+
+// MemberIDs — id всех, кто участвовал.
+func (c *Conversation) MemberIDs() []string {
+	ids := make([]string, 0, len(c.Members))
+	for _, p := range c.Members {
+		ids = append(ids, p.ID())
+	}
+	return ids
+}
+
+// IDs — id текущих участников.
+func (c *Conversation) IDs() []string {
+	ids := make([]string, 0, len(c.Participants))
+	for _, p := range c.Participants {
+		ids = append(ids, p.ID())
+	}
+	return ids
+}
+
+// has — участвует ли агент в разговоре.
+func (c *Conversation) has(id string) bool {
+	for _, p := range c.Participants {
+		if p.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// last — последняя реплика или nil.
+func (c *Conversation) last() *TranscriptLine {
+	if len(c.Transcript) == 0 {
+		return nil
+	}
+	return &c.Transcript[len(c.Transcript)-1]
+}
+
+// record добавляет реплику в стенограмму и в истории всех участников.
+func (c *Conversation) record(speaker *Participant, content string, tick int64) {
+	c.Transcript = append(c.Transcript, TranscriptLine{
+		SpeakerID: speaker.ID(),
+		Speaker:   speaker.Name(),
+		Content:   content,
+		Tick:      tick,
+	})
+	for _, p := range c.Participants {
+		if p == speaker {
+			p.history = append(p.history, llm.Message{Role: "assistant", Content: content})
+		} else {
+			p.history = append(p.history, llm.Message{
+				Role:    "user",
+				Content: fmt.Sprintf("%s says: %s", speaker.Name(), content),
+			})
+		}
+	}
+	speaker.LastTurn = c.Turn
+	speaker.Spoke++
+	c.Turn++
+}
+
+// notify добавляет ремарку (кто пришёл, кто ушёл) в истории всех, кроме except.
+func (c *Conversation) notify(except *Participant, text string) {
+	for _, p := range c.Participants {
+		if p != except {
+			p.history = append(p.history, llm.Message{Role: "user", Content: text})
+		}
+	}
+}
+
+// join добавляет агента в разговор: новичок видит последние реплики,
+// остальные — ремарку о его приходе.
+func (c *Conversation) join(m matchAgent) *Participant {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You come up to %s, who are talking.", joinNames(c.Participants)))
+	if recent := c.Transcript[max(0, len(c.Transcript)-4):]; len(recent) > 0 {
+		sb.WriteString(" So far:\n")
+		for _, line := range recent {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", line.Speaker, line.Content))
+		}
+	}
+	sb.WriteString("Join the conversation naturally.")
+
+	p := c.add(m.rec.ID, m)
+	p.history = append(p.history, llm.Message{Role: "user", Content: sb.String()})
+	c.notify(p, fmt.Sprintf("%s joins the conversation.", m.rec.Name))
+	return p
+}
+
+// leave убирает участника; остальные видят ремарку о его уходе
+// и больше не получают его описание в промпте.
+func (c *Conversation) leave(p *Participant) {
+	for i, q := range c.Participants {
+		if q == p {
+			c.Participants = append(c.Participants[:i], c.Participants[i+1:]...)
+			break
+		}
+	}
+	for _, q := range c.Participants {
+		for i, pc := range q.partners {
+			if pc.ID == p.ID() {
+				q.partners = append(q.partners[:i], q.partners[i+1:]...)
+				break
+			}
+		}
+	}
+	c.notify(nil, fmt.Sprintf("%s leaves the conversation.", p.Name()))
+}
+
+// other — собеседник в паре.
+func (c *Conversation) other(p *Participant) *Participant {
+	for _, q := range c.Participants {
+		if q != p {
+			return q
+		}
+	}
+	return nil
+}
+
+// addressee — участник, к которому обращена реплика (по имени), или nil.
+func (c *Conversation) addressee(speaker *Participant, content string) *Participant {
+	lower := strings.ToLower(content)
+	for _, p := range c.Participants {
+		if p != speaker && strings.Contains(lower, strings.ToLower(p.Name())) {
+			return p
+		}
+	}
+	return nil
+}
+
+func joinNames(ps []*Participant) string {
+	names := make([]string, 0, len(ps))
+	for _, p := range ps {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// -----------------------------------------------------------------------------
+// TurnPolicy — очерёдность реплик
+// -----------------------------------------------------------------------------
+
+// TurnPolicy выбирает следующего говорящего.
+type TurnPolicy interface {
+	// Name — имя для логов.
+	Name() string
+
+	// Next возвращает следующего говорящего и причину выбора.
+	Next(c *Conversation, r *rand.Rand) (*Participant, string)
+}
+
+// WeightedTurns — взвешенная очередь: к кому обратились по имени, тот
+// отвечает вероятнее; экстраверты и доминирующие по настроению говорят
+// чаще; долгое молчание повышает шанс; дважды подряд никто не говорит.
+type WeightedTurns struct{}
+
+// Name реализует TurnPolicy.
+func (WeightedTurns) Name() string { return "weighted" }
+
+// Next реализует TurnPolicy.
+func (WeightedTurns) Next(c *Conversation, r *rand.Rand) (*Participant, string) {
+	last := c.last()
+	if last == nil {
+		return c.Participants[0], "starts the conversation"
+	}
+
+	var addressed *Participant
+	for _, p := range c.Participants {
+		if p.ID() != last.SpeakerID && strings.Contains(strings.ToLower(last.Content), strings.ToLower(p.Name())) {
+			addressed = p
+			break
+		}
+	}
+
+	candidates := make([]*Participant, 0, len(c.Participants))
+	weights := make([]float64, 0, len(c.Participants))
+	for _, p := range c.Participants {
+		if p.ID() == last.SpeakerID {
+			continue
+		}
+		w := 0.2 + p.personality.Extraversion + 0.5*p.influence.AssertivenessModifier
+		if w < 0.05 {
+			w = 0.05
+		}
+		w *= 1 + 0.3*float64(p.silence(c.Turn))
+		if p == addressed {
+			w *= 4
+		}
+		candidates = append(candidates, p)
+		weights = append(weights, w)
+	}
+	if len(candidates) == 0 {
+		return c.Participants[0], "only one left"
+	}
+
+	next := candidates[weightedIndex(r, weights)]
+	if next == addressed {
+		return next, fmt.Sprintf("addressed by %s", last.Speaker)
+	}
+	return next, fmt.Sprintf("takes the floor (extraversion %.2f, dominance %+.2f)",
+		next.personality.Extraversion, next.influence.AssertivenessModifier)
+}
+
+// -----------------------------------------------------------------------------
+// Ведение разговора
+// -----------------------------------------------------------------------------
+
+// startConversation сохраняет начатый разговор пары и публикует
+// conversation_started.
+func (o *Orchestrator) startConversation(pair Pairing, tick int64) *Conversation {
+	c := newConversation(pair, tick)
+	rec := storage.ConversationRecord{
+		ID:           c.ID,
+		InitiatorID:  pair.Initiator.ID,
+		Participants: c.IDs(),
+		StartTick:    tick,
+	}
+	if pair.Topic != "" {
+		rec.Topic = sql.NullString{String: pair.Topic, Valid: true}
+	}
+	if err := o.repo.CreateConversation(rec); err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+	for _, p := range c.Participants {
+		o.remind(c, p, tick)
+	}
+
+	log.Printf("orchestrator tick %d: %s <-> %s [%s: %s]",
+		tick, pair.Initiator.Name, pair.Partner.Name, pair.Policy, pair.Summary())
+	o.bus.Publish(WorldEvent{
+		Topic:          TopicInteraction,
+		Type:           "conversation_started",
+		Source:         pair.Initiator.ID,
+		AffectedAgents: c.IDs(),
+		Payload: map[string]any{
+			"conversationId": c.ID,
+			"pairing":        pairingPayload(pair),
+			"zone":           c.Zone,
+			"message":        fmt.Sprintf("%s approaches %s: %s", pair.Initiator.Name, pair.Partner.Name, pair.Summary()),
+		},
+		Tick: tick,
+	})
+	return c
+}
+
+// runConversation ведёт разговор, к которому могут присоединяться
+// другие агенты, до естественного завершения (см. endings.go). Участники
+// уже заняты (o.locks); по завершении освобождаются все участники,
+// включая ушедших раньше, — после того, как каждый запомнит разговор
+// и обновятся отношения.
+func (o *Orchestrator) runConversation(ctx context.Context, c *Conversation, tick int64) {
+	reason := EndMaxLength
+	defer func() {
+		o.publishEnded(c, reason, tick)
+		o.updateRelationships(c, o.summarize(ctx, c, tick), tick)
+		o.locks.Unlock(c.MemberIDs()...)
+	}()
+
+	rnd := o.newRand(tick, c.Pairing.Initiator.ID)
+	repeats := 0
+
+	for c.Turn < o.length.Max {
+		select {
+		case <-ctx.Done():
+			log.Println("orchestrator: conversation canceled")
+			reason = EndCanceled
+			return
+		default:
+		}
+
+		speaker, why := o.turnPolicy.Next(c, rnd)
+		if c.Turn >= naturalLength(c, o.length) && !speaker.nudged {
+			speaker.nudged = true
+			speaker.history = append(speaker.history, llm.Message{
+				Role:    "user",
+				Content: "(You feel the conversation has run its course. Wrap it up naturally.)",
+			})
+		}
+		human := o.injectHumanMessages(&speaker.history, speaker.ID())
+
+		reply, err := speaker.brain.Think(turnContext(ctx, human, speaker.ID(), tick),
+			o.llm, speaker.Name(), speaker.mood, speaker.goals, speaker.partners, speaker.history)
+		if err != nil {
+			o.logThinkError(tick, speaker.Name(), err)
+			reason = EndError
+			return // Выходим из диалога при любой ошибке LLM
+		}
+
+		reply, wantsEnd := agent.SplitEndSignal(reply)
+		if reply == "" {
+			// Агенту нечего сказать — разговор окончен
+			reason = EndSignalled
+			return
+		}
+		reply, ok := o.moderateReply(ctx, speaker.rec, reply, tick)
+		if !ok {
+			reason = EndModeration
+			return // Реплика задержана модерацией — разговор обрывается
+		}
+		if len(c.Participants) > 2 {
+			log.Printf("orchestrator tick %d: %s %s", tick, speaker.Name(), why)
+		}
+		repeated := isRepetition(c, reply)
+		target := c.addressee(speaker, reply)
+		o.publishReply(c, speaker, target, reply, tick)
+		o.saveTurn(c, speaker, target, reply, tick)
+		c.record(speaker, reply, tick)
+
+		if repeated {
+			repeats++
+		}
+		farewell := isFarewell(reply)
+		if c.Turn >= o.length.Min {
+			switch {
+			case repeats >= 2:
+				reason = EndRepetition
+				return
+			case (wantsEnd || farewell) && len(c.Participants) > 2:
+				// В группе прощающийся уходит, остальные продолжают
+				o.leave(c, speaker, tick)
+				continue
+			case wantsEnd:
+				reason = EndSignalled
+				return
+			case farewell:
+				reason = EndFarewell
+				return
+			}
+		}
+
+		o.maybeLeave(c, speaker, rnd, tick)
+		o.maybeJoin(c, rnd, tick)
+	}
+}
+
+// saveTurn дописывает реплику в conversation_turns. target nil — ко всем.
+func (o *Orchestrator) saveTurn(c *Conversation, speaker, target *Participant, reply string, tick int64) {
+	turn := storage.ConversationTurnRecord{
+		ConversationID: c.ID,
+		Seq:            c.Turn,
+		SpeakerID:      speaker.ID(),
+		Content:        reply,
+		Tick:           tick,
+	}
+	if target == nil && len(c.Participants) == 2 {
+		target = c.other(speaker)
+	}
+	if target != nil {
+		turn.TargetID = sql.NullString{String: target.ID(), Valid: true}
+	}
+	if err := o.repo.AddConversationTurn(turn); err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+}
+
+// publishEnded сохраняет и публикует завершение разговора с причиной.
+func (o *Orchestrator) publishEnded(c *Conversation, reason EndReason, tick int64) {
+	members := c.MemberIDs()
+	if err := o.repo.EndConversation(c.ID, tick, string(reason), members); err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+	log.Printf("orchestrator tick %d: conversation of %s ended: %s after %d replies",
+		tick, joinNames(c.Members), reason, c.Turn)
+
+	err := o.bus.Publish(WorldEvent{
+		Topic:          TopicInteraction,
+		Type:           "conversation_ended",
+		Source:         c.Pairing.Initiator.ID,
+		AffectedAgents: members,
+		Payload: map[string]any{
+			"conversationId": c.ID,
+			"reason":         string(reason),
+			"turns":          c.Turn,
+			"participants":   members,
+			"message": fmt.Sprintf("Conversation of %s ended: %s after %d replies",
+				joinNames(c.Members), reason, c.Turn),
+		},
+		Tick: tick,
+	})
+	if err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+}
+
+// maybeJoin приглашает в разговор свободного активного агента из той же
+// зоны. Шанс растёт с желанием общаться (экстраверсия, настроение).
+func (o *Orchestrator) maybeJoin(c *Conversation, rnd *rand.Rand, tick int64) {
+	if len(c.Participants) >= o.groupSize {
+		return
+	}
+	agents, err := o.repo.GetActiveAgents()
+	if err != nil {
+		log.Printf("orchestrator tick %d: getAgents error: %v", tick, err)
+		return
+	}
+
+	var free []matchAgent
+	var weights []float64
+	for _, a := range agents {
+		if c.has(a.ID) || location(a) != c.Zone || o.locks.IsBusy(a.ID) {
+			continue
+		}
+		m := newMatchAgent(a)
+		free = append(free, m)
+		weights = append(weights, m.drive())
+	}
+	if len(free) == 0 {
+		return
+	}
+	m := free[weightedIndex(rnd, weights)]
+	if rnd.Float64() > 0.1*m.drive() || !o.locks.TryLock(m.rec.ID) {
+		return
+	}
+
+	joined := c.join(m)
+	o.remind(c, joined, tick)
+	for _, p := range c.Participants {
+		if p != joined {
+			p.partners = append(p.partners, o.partnerContext(p, joined, tick))
+		}
+	}
+	if err := o.repo.SetConversationParticipants(c.ID, c.MemberIDs()); err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+	log.Printf("orchestrator tick %d: %s joins %s", tick, m.rec.Name, joinNames(c.Participants[:len(c.Participants)-1]))
+	o.publishMembership(c, "conversation_joined", m, tick,
+		fmt.Sprintf("%s joins the conversation", m.rec.Name))
+}
+
+// maybeLeave отпускает из группы (3+) молчащего участника: интроверты
+// уходят охотнее, шанс растёт с каждой пропущенной репликой.
+func (o *Orchestrator) maybeLeave(c *Conversation, speaker *Participant, rnd *rand.Rand, tick int64) {
+	if len(c.Participants) <= 2 {
+		return
+	}
+	for _, p := range c.Participants {
+		silence := p.silence(c.Turn)
+		if p == speaker || silence < 2 {
+			continue
+		}
+		chance := min(0.05+0.1*(1-p.personality.Extraversion)+0.05*float64(silence), 0.5)
+		if rnd.Float64() >= chance {
+			continue
+		}
+
+		o.leave(c, p, tick)
+		return // не больше одного ухода за реплику
+	}
+}
+
+// leave выводит участника из разговора. Занятым он остаётся до конца
+// разговора: пересказ и отношения пишутся и за него (см. runConversation).
+func (o *Orchestrator) leave(c *Conversation, p *Participant, tick int64) {
+	c.leave(p)
+	log.Printf("orchestrator tick %d: %s leaves %s", tick, p.Name(), joinNames(c.Participants))
+	o.publishMembership(c, "conversation_left", p.matchAgent, tick,
+		fmt.Sprintf("%s leaves the conversation", p.Name()))
+}
@@ -0,0 +1,192 @@
+// Package world — естественное завершение разговоров.
+//
+// Разговор идёт, пока участники хотят говорить: агент может закончить его
+// меткой agent.EndSignal или прощанием, разговор обрывается на повторах.
+// Длина ограничена ConversationLength.Min/Max, а между ними у каждого
+// разговора своя «естественная» длина: общительные и довольные агенты,
+// споры соперников и общие цели тянут разговор дольше. Дойдя до неё,
+// говорящий получает подсказку закругляться. Причина завершения
+// публикуется в событии conversation_ended.
+
+package world
+
+import (
+	"slices"
+	"strings"
+)
+
+// EndReason — почему разговор закончился.
+type EndReason string
+
+const (
+	EndSignalled  EndReason = "signalled"  // агент пометил реплику agent.EndSignal
+	EndFarewell   EndReason = "farewell"   // агент попрощался
+	EndRepetition EndReason = "repetition" // участники повторяются
+	EndMaxLength  EndReason = "max_length" // достигнут ConversationLength.Max
+	EndError      EndReason = "error"      // ошибка LLM
+	EndModeration EndReason = "moderation" // реплика задержана модерацией
+	EndCanceled   EndReason = "canceled"   // тик отменён или устарел
+)
+
+// ConversationLength — границы длины разговора в репликах.
+type ConversationLength struct {
+	// Min — раньше этого прощания и EndSignal не завершают разговор.
+	Min int
+
+	// Max — жёсткий предел.
+	Max int
+}
+
+// DefaultConversationLength — границы по умолчанию.
+var DefaultConversationLength = ConversationLength{Min: 2, Max: 12}
+
+// Прощания (EN/RU), по которым видно, что агент уходит. Ищутся целыми
+// словами в конце реплики (см. isFarewell).
+var farewellPhrases = [][]string{
+	{"goodbye"}, {"bye"}, {"see", "you"}, {"have", "to", "go"}, {"got", "to", "go"}, {"gotta", "go"}, {"take", "care"},
+	{"до", "свидания"}, {"увидимся"}, {"мне", "пора"}, {"до", "встречи"}, {"до", "скорого"}, {"всего", "доброго"},
+}
+
+// farewellTail — сколько последних предложений реплики проверяется
+// на прощание: «Мне пора. Рад был поговорить!» — тоже прощание.
+const farewellTail = 2
+
+// isFarewell — заканчивается ли реплика прощанием: фраза из
+// farewellPhrases в последних предложениях. «Пока» — ещё и «пока не
+// знаю», поэтому прощанием считается, только если им кончается реплика.
+// Реплика с вопросом в конце («Мне пора. А ты куда?») ждёт ответа
+// и прощанием не считается.
+func isFarewell(reply string) bool {
+	if strings.HasSuffix(strings.TrimRight(reply, " \t\n\"'»)!"), "?") {
+		return false
+	}
+	sentences := strings.FieldsFunc(strings.ToLower(reply), func(r rune) bool {
+		return r == '.' || r == '!' || r == '?' || r == '…' || r == '\n'
+	})
+	var tail [][]string
+	for i := len(sentences) - 1; i >= 0 && len(tail) < farewellTail; i-- {
+		if w := words(sentences[i]); len(w) > 0 {
+			tail = append(tail, w)
+		}
+	}
+	if len(tail) == 0 {
+		return false
+	}
+	if last := tail[0]; last[len(last)-1] == "пока" {
+		return true
+	}
+	for _, sentence := range tail {
+		for _, phrase := range farewellPhrases {
+			if hasPhrase(sentence, phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasPhrase — есть ли в words подряд все слова phrase.
+func hasPhrase(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepetition — почти совпадает ли реплика с одной из последних
+// (по сходству множеств слов).
+func isRepetition(c *Conversation, reply string) bool {
+	words := wordSet(reply)
+	if len(words) == 0 {
+		return false
+	}
+	for _, line := range c.Transcript[max(0, len(c.Transcript)-4):] {
+		if jaccard(words, wordSet(line.Content)) >= 0.8 {
+			return true
+		}
+	}
+	return false
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words(s) {
+		set[w] = true
+	}
+	return set
+}
+
+// words — слова s в нижнем регистре, по порядку.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'а' && r <= 'я' || r == 'ё' || r >= '0' && r <= '9')
+	})
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// naturalLength — сколько реплик разговор идёт, прежде чем участники
+// начнут закругляться. Зависит от общительности (характер и настроение),
+// конфликтности и общих целей участников.
+func naturalLength(c *Conversation, bounds ConversationLength) int {
+	var social float64
+	conflict := false
+	for _, p := range c.Participants {
+		social += p.personality.Extraversion + 0.5*p.influence.SociabilityModifier
+		conflict = conflict || p.conflictSeeking()
+	}
+	social /= float64(len(c.Participants))
+
+	span := float64(bounds.Max - bounds.Min)
+	n := float64(bounds.Min) + span*clamp01(0.2+0.5*social)
+	if conflict {
+		n += 2 // споры затягиваются
+	}
+	n += float64(sharedGoalWords(c.Participants))
+	n += 2 * float64(len(c.Participants)-2) // в группе каждому нужно высказаться
+
+	return min(max(int(n), bounds.Min), bounds.Max)
+}
+
+// sharedGoalWords — сколько значимых слов (до 3) встречается в целях
+// нескольких участников: общая тема поддерживает разговор.
+func sharedGoalWords(ps []*Participant) int {
+	seen := make(map[string]string) // слово → id первого участника
+	shared := 0
+	for _, p := range ps {
+		for _, g := range p.goals {
+			if g.IsCompleted {
+				continue
+			}
+			for w := range wordSet(g.Description) {
+				if len([]rune(w)) < 5 {
+					continue
+				}
+				if id, ok := seen[w]; !ok {
+					seen[w] = p.ID()
+				} else if id != p.ID() && id != "" {
+					seen[w] = "" // учитываем слово один раз
+					shared++
+				}
+			}
+		}
+	}
+	return min(shared, 3)
+}
+
+func clamp01(v float64) float64 {
+	return min(max(v, 0), 1)
+}
@@ -0,0 +1,103 @@
+package world
+
+import (
+	"testing"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+)
+
+func TestIsFarewell(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  bool
+	}{
+		{"Goodbye, friend!", true},
+		{"Well, I have to go. Nice talking to you!", true},
+		{"Take care of yourself", true},
+		{"Мне пора, до встречи!", true},
+		{"Ладно, пока", true},
+		{"I have to go check the market. Anyway, what about the weather? It's nice today.", false},
+		{"Пока не знаю, что сказать.", false},
+		{"Мне пора. А ты куда идёшь?", false},
+		{"I have to go. Are you coming to the festival?!", false},
+		{"Seeing you here is nice", false},
+		{"Nobody says goodbyes anymore", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isFarewell(tt.reply); got != tt.want {
+			t.Errorf("isFarewell(%q) = %v, want %v", tt.reply, got, tt.want)
+		}
+	}
+}
+
+func TestIsRepetition(t *testing.T) {
+	c := &Conversation{}
+	for _, line := range []string{
+		"The festival starts tomorrow at the square",
+		"I baked bread this morning",
+		"Did you see the new mural?",
+		"Yes, it is beautiful",
+		"The river is high after the rain",
+	} {
+		c.Transcript = append(c.Transcript, TranscriptLine{Content: line})
+	}
+
+	tests := []struct {
+		reply string
+		want  bool
+	}{
+		{"I baked bread this morning!", true},
+		{"yes it is beautiful", true},
+		{"The festival starts tomorrow at the square", false}, // старше последних четырёх
+		{"I baked a cake for my sister this evening", false},
+		{"...", false},
+	}
+	for _, tt := range tests {
+		if got := isRepetition(c, tt.reply); got != tt.want {
+			t.Errorf("isRepetition(%q) = %v, want %v", tt.reply, got, tt.want)
+		}
+	}
+}
+
+// testParticipant — участник с заданной экстраверсией, доброжелательностью и целями.
+func testParticipant(id string, extraversion, agreeableness float64, goals ...string) *Participant {
+	p := &Participant{matchAgent: matchAgent{
+		rec:         storage.AgentRecord{ID: id},
+		personality: agent.Personality{Extraversion: extraversion, Agreeableness: agreeableness},
+	}}
+	for _, g := range goals {
+		p.goals = append(p.goals, agent.Goal{Description: g})
+	}
+	return p
+}
+
+func TestNaturalLength(t *testing.T) {
+	bounds := ConversationLength{Min: 2, Max: 12}
+	tests := []struct {
+		name         string
+		participants []*Participant
+		want         int
+	}{
+		{"introverts", []*Participant{testParticipant("a", 0, 0.5), testParticipant("b", 0, 0.5)}, 4},
+		{"extraverts", []*Participant{testParticipant("a", 0.6, 0.5), testParticipant("b", 0.6, 0.5)}, 7},
+		{"conflict", []*Participant{testParticipant("a", 0, 0.2), testParticipant("b", 0, 0.5)}, 6},
+		{"shared goals", []*Participant{
+			testParticipant("a", 0, 0.5, "Organize the harvest festival"),
+			testParticipant("b", 0, 0.5, "Help with the harvest festival"),
+		}, 6},
+		{"group", []*Participant{testParticipant("a", 0, 0.5), testParticipant("b", 0, 0.5), testParticipant("c", 0, 0.5)}, 6},
+		{"capped at max", []*Participant{
+			testParticipant("a", 1, 0.1), testParticipant("b", 1, 0.5), testParticipant("c", 1, 0.5),
+		}, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conversation{Participants: tt.participants}
+			if got := naturalLength(c, bounds); got != tt.want {
+				t.Errorf("naturalLength = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
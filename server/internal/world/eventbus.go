@@ -7,8 +7,20 @@
 package world
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"milk/server/internal/storage"
+
+	"github.com/google/uuid"
 )
 
 // -----------------------------------------------------------------------------
@@ -24,9 +36,9 @@ import (
 type EventBus struct {
 	// subscribers — маппинг топик → список подписчиков.
 	// Каждый подписчик имеет канал доставки и опциональный фильтр.
-	Subscribers map[EventTopic][]Subscriber
+	Subscribers map[EventTopic][]*Subscriber
 
-	// eventLog — история всех событий для API-эндпоинтов и отладки.
+	// eventLog — последние события (до eventLogLimit) для API-эндпоинтов и отладки.
 	EventLog []WorldEvent
 
 	// queue — буферизованный канал для неблокирующего Publish().
@@ -35,6 +47,20 @@ type EventBus struct {
 
 	// mu — RWMutex для потокобезопасного доступа к subscribers и eventLog.
 	mu sync.RWMutex
+
+	// seqMu держится от сохранения события до постановки в очередь:
+	// события попадают в очередь в порядке их Seq.
+	seqMu sync.Mutex
+
+	// repo — write-through хранилище: Publish сохраняет событие в events
+	// до постановки в очередь. nil = без сохранения.
+	repo *storage.Repository
+
+	published     atomic.Int64
+	delivered     atomic.Int64
+	dropped       atomic.Int64 // подписчик не успевал читать
+	queueDropped  atomic.Int64 // очередь переполнена, событие только сохранено
+	persistErrors atomic.Int64
 }
 
 // Subscriber — подписчик на события определённого топика.
@@ -50,6 +76,15 @@ type Subscriber struct {
 	// Используется для тонкой настройки — например, агент подписан на TopicGlobal,
 	// но хочет только события типа "discovery".
 	Filter func(WorldEvent) bool
+
+	// Observer — наблюдатель (дашборд, SSE, логгер): получает события топика
+	// независимо от AffectedAgents. Обычный подписчик с непустым
+	// AffectedAgents получает событие, только если его ID есть в списке.
+	Observer bool
+
+	// Delivered, Dropped — доставленные и отброшенные (канал полон) события.
+	Delivered atomic.Int64
+	Dropped   atomic.Int64
 }
 
 // EventTopic — именованный канал маршрутизации событий.
@@ -83,6 +118,18 @@ const (
 	// TopicSystem — системные события (пауза, возобновление, сброс).
 	// Подписчики: все агенты + дашборд.
 	TopicSystem EventTopic = "system"
+
+	// TopicModeration — решения модерации (флаги, блокировки, редактирование).
+	// Подписчики: дашборд (ревью).
+	TopicModeration EventTopic = "moderation"
+
+	// TopicMovement — перемещения агентов между зонами.
+	// Подписчики: дашборд (карта).
+	TopicMovement EventTopic = "movement"
+
+	// TopicAll — подписка на все топики (SSE-мост, логгеры). Событий
+	// с этим топиком не бывает.
+	TopicAll EventTopic = "*"
 )
 
 // -----------------------------------------------------------------------------
@@ -95,6 +142,9 @@ type WorldEvent struct {
 	// ID — уникальный идентификатор события (UUID).
 	ID string
 
+	// Seq — events.seq (монотонный, SSE id:). 0 = не сохранено.
+	Seq int64
+
 	// Topic — топик маршрутизации (определяет, кто получит событие).
 	Topic EventTopic
 
@@ -127,4 +177,257 @@ type WorldEvent struct {
 	// 0 = обычный, 1+ = повышенный (доставляется первым).
 	// Системные события (pause/resume) имеют высокий приоритет.
 	Priority int
+
+	// Status — статус в таблице events: "completed" (по умолчанию) или
+	// "pending" для событий, ждущих ревью.
+	Status string
+}
+
+// Размеры буферов шины.
+const (
+	eventLogLimit   = 500 // событий в EventLog
+	subscriberQueue = 64  // буфер канала подписчика
+	deliveryBatch   = 64  // сколько событий очереди сортируется по приоритету за раз
+)
+
+// ErrQueueFull — очередь шины переполнена: событие сохранено, но не разослано.
+var ErrQueueFull = errors.New("event bus queue is full")
+
+// NewEventBus создаёт шину с очередью на queueSize событий.
+// repo != nil включает сохранение событий в таблицу events.
+func NewEventBus(repo *storage.Repository, queueSize int) *EventBus {
+	return &EventBus{
+		Subscribers: make(map[EventTopic][]*Subscriber),
+		Queue:       make(chan WorldEvent, queueSize),
+		repo:        repo,
+	}
+}
+
+// Start запускает processEvents до отмены ctx.
+func (b *EventBus) Start(ctx context.Context) {
+	go b.processEvents(ctx)
+}
+
+// Publish сохраняет событие в events и ставит его в очередь рассылки.
+// Не блокирует: при переполненной очереди событие остаётся только в БД
+// и возвращается ErrQueueFull. Пустые ID и Timestamp заполняются.
+func (b *EventBus) Publish(e WorldEvent) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	b.published.Add(1)
+
+	b.seqMu.Lock()
+	defer b.seqMu.Unlock()
+
+	var persistErr error
+	if b.repo != nil {
+		if e.Seq, persistErr = b.persist(e); persistErr != nil {
+			b.persistErrors.Add(1)
+			log.Printf("eventbus: %v", persistErr)
+		}
+	}
+
+	select {
+	case b.Queue <- e:
+	default:
+		b.queueDropped.Add(1)
+		return ErrQueueFull
+	}
+	return persistErr
+}
+
+// Subscribe подписывает id на топик (TopicAll — на все). События с непустым
+// AffectedAgents доставляются, только если id в списке. Повторная подписка
+// того же id на тот же топик заменяет прежнюю (старый канал закрывается).
+func (b *EventBus) Subscribe(topic EventTopic, id string, filter func(WorldEvent) bool) <-chan WorldEvent {
+	return b.subscribe(&Subscriber{ID: id, Filter: filter}, topic)
+}
+
+// Observe подписывает наблюдателя: он получает все события топика,
+// без учёта AffectedAgents.
+func (b *EventBus) Observe(topic EventTopic, id string, filter func(WorldEvent) bool) <-chan WorldEvent {
+	return b.subscribe(&Subscriber{ID: id, Filter: filter, Observer: true}, topic)
+}
+
+// Unsubscribe снимает подписку id с топика и закрывает её канал.
+func (b *EventBus) Unsubscribe(topic EventTopic, id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(topic, id)
+}
+
+func (b *EventBus) subscribe(sub *Subscriber, topic EventTopic) <-chan WorldEvent {
+	sub.Channel = make(chan WorldEvent, subscriberQueue)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(topic, sub.ID)
+	b.Subscribers[topic] = append(b.Subscribers[topic], sub)
+	return sub.Channel
+}
+
+// remove удаляет подписчика. Вызывается под b.mu.
+func (b *EventBus) remove(topic EventTopic, id string) {
+	subs := b.Subscribers[topic]
+	for i, sub := range subs {
+		if sub.ID == id {
+			close(sub.Channel)
+			b.Subscribers[topic] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// processEvents вычитывает очередь и раздаёт события подписчикам.
+// Всё, что накопилось в очереди, рассылается в порядке приоритета,
+// поэтому подписчики могут получить события не по порядку Seq.
+func (b *EventBus) processEvents(ctx context.Context) {
+	batch := make([]WorldEvent, 0, deliveryBatch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.Queue:
+			batch = append(batch[:0], e)
+		}
+	drain:
+		for len(batch) < deliveryBatch {
+			select {
+			case e := <-b.Queue:
+				batch = append(batch, e)
+			default:
+				break drain
+			}
+		}
+
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].Priority > batch[j].Priority })
+		for _, e := range batch {
+			b.deliver(e)
+		}
+	}
+}
+
+// deliver рассылает событие подписчикам топика и TopicAll без блокировки.
+func (b *EventBus) deliver(e WorldEvent) {
+	b.mu.Lock()
+	b.EventLog = append(b.EventLog, e)
+	if len(b.EventLog) > eventLogLimit {
+		b.EventLog = b.EventLog[len(b.EventLog)-eventLogLimit:]
+	}
+	b.mu.Unlock()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, topic := range [...]EventTopic{e.Topic, TopicAll} {
+		for _, sub := range b.Subscribers[topic] {
+			if !sub.wants(e) {
+				continue
+			}
+			select {
+			case sub.Channel <- e:
+				sub.Delivered.Add(1)
+				b.delivered.Add(1)
+			default:
+				sub.Dropped.Add(1)
+				b.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// wants — проходит ли событие таргетинг и фильтр подписчика.
+func (s *Subscriber) wants(e WorldEvent) bool {
+	if !s.Observer && len(e.AffectedAgents) > 0 && !containsID(e.AffectedAgents, s.ID) {
+		return false
+	}
+	return s.Filter == nil || s.Filter(e)
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// persist пишет событие в events и возвращает его Seq. События тика
+// сохраняются через SaveTick, в одной транзакции с продвижением current_tick.
+func (b *EventBus) persist(e WorldEvent) (int64, error) {
+	rec := storage.EventRecord{
+		ID:        e.ID,
+		Topic:     string(e.Topic),
+		Type:      e.Type,
+		Source:    e.Source,
+		Status:    e.Status,
+		CreatedAt: time.Now().UTC(),
+	}
+	if len(e.AffectedAgents) > 0 {
+		affected, _ := json.Marshal(e.AffectedAgents)
+		rec.AffectedAgents = sql.NullString{String: string(affected), Valid: true}
+	}
+	if e.Payload != nil {
+		payload, err := json.Marshal(e.Payload)
+		if err != nil {
+			return 0, fmt.Errorf("persist %s/%s: %w", e.Topic, e.Type, err)
+		}
+		rec.Payload = sql.NullString{String: string(payload), Valid: true}
+	}
+	if e.Tick > 0 {
+		rec.Tick = sql.NullInt64{Int64: e.Tick, Valid: true}
+		err := b.repo.SaveTick(e.Tick, time.Time{}, &rec)
+		return rec.Seq, err
+	}
+	err := b.repo.SaveEvent(&rec)
+	return rec.Seq, err
+}
+
+// BusStats — счётчики шины.
+type BusStats struct {
+	Published     int64
+	Delivered     int64
+	Dropped       int64
+	QueueDropped  int64
+	PersistErrors int64
+	QueueDepth    int
+	Subscribers   []SubscriberStats
+}
+
+// SubscriberStats — счётчики одного подписчика.
+type SubscriberStats struct {
+	Topic     EventTopic
+	ID        string
+	Observer  bool
+	Delivered int64
+	Dropped   int64
+}
+
+// Stats возвращает снимок счётчиков шины.
+func (b *EventBus) Stats() BusStats {
+	st := BusStats{
+		Published:     b.published.Load(),
+		Delivered:     b.delivered.Load(),
+		Dropped:       b.dropped.Load(),
+		QueueDropped:  b.queueDropped.Load(),
+		PersistErrors: b.persistErrors.Load(),
+		QueueDepth:    len(b.Queue),
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for topic, subs := range b.Subscribers {
+		for _, sub := range subs {
+			st.Subscribers = append(st.Subscribers, SubscriberStats{
+				Topic:     topic,
+				ID:        sub.ID,
+				Observer:  sub.Observer,
+				Delivered: sub.Delivered.Load(),
+				Dropped:   sub.Dropped.Load(),
+			})
+		}
+	}
+	return st
 }
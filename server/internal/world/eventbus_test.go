@@ -0,0 +1,161 @@
+package world
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"milk/server/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestRepo — хранилище над пустой базой со всеми миграциями.
+func newTestRepo(t *testing.T) *storage.Repository {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "society.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	repo := storage.NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return repo
+}
+
+// recvEvent ждёт событие не дольше d. ok=false — события не было.
+func recvEvent(ch <-chan WorldEvent, d time.Duration) (WorldEvent, bool) {
+	select {
+	case e := <-ch:
+		return e, true
+	case <-time.After(d):
+		return WorldEvent{}, false
+	}
+}
+
+func TestEventBusTargeting(t *testing.T) {
+	bus := NewEventBus(nil, 16)
+	alice := bus.Subscribe(TopicInteraction, "alice", nil)
+	bob := bus.Subscribe(TopicInteraction, "bob", nil)
+	dashboard := bus.Observe(TopicAll, "dashboard", nil)
+	onlyMood := bus.Observe(TopicAll, "mood", func(e WorldEvent) bool { return e.Topic == TopicMoodChange })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.Start(ctx)
+
+	bus.Publish(WorldEvent{Topic: TopicInteraction, Type: "conversation", AffectedAgents: []string{"alice"}})
+	if _, ok := recvEvent(alice, time.Second); !ok {
+		t.Error("targeted agent did not get the event")
+	}
+	if _, ok := recvEvent(dashboard, time.Second); !ok {
+		t.Error("observer did not get the event")
+	}
+	if e, ok := recvEvent(bob, 20*time.Millisecond); ok {
+		t.Errorf("untargeted agent got %+v", e)
+	}
+	if e, ok := recvEvent(onlyMood, 20*time.Millisecond); ok {
+		t.Errorf("filtered observer got %+v", e)
+	}
+
+	// Событие без AffectedAgents получают все подписчики топика.
+	bus.Publish(WorldEvent{Topic: TopicInteraction, Type: "conversation"})
+	for name, ch := range map[string]<-chan WorldEvent{"alice": alice, "bob": bob} {
+		if _, ok := recvEvent(ch, time.Second); !ok {
+			t.Errorf("%s did not get a broadcast", name)
+		}
+	}
+
+	// Повторная подписка закрывает прежний канал.
+	bus.Subscribe(TopicInteraction, "alice", nil)
+	if _, open := <-alice; open {
+		t.Error("old channel still open after resubscribe")
+	}
+}
+
+func TestEventBusPriority(t *testing.T) {
+	bus := NewEventBus(nil, 16)
+	ch := bus.Observe(TopicAll, "dashboard", nil)
+	for i, p := range []int{0, 2, 0, 1} {
+		bus.Publish(WorldEvent{Topic: TopicSystem, Type: string(rune('a' + i)), Priority: p})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.Start(ctx)
+
+	var got string
+	for range 4 {
+		e, ok := recvEvent(ch, time.Second)
+		if !ok {
+			t.Fatal("event not delivered")
+		}
+		got += e.Type
+	}
+	// Накопившиеся события — по приоритету, равные — в порядке публикации.
+	if got != "bdac" {
+		t.Errorf("delivery order = %q, want bdac", got)
+	}
+}
+
+func TestEventBusDropCounters(t *testing.T) {
+	bus := NewEventBus(nil, 1)
+	bus.Observe(TopicAll, "slow", nil)
+
+	if err := bus.Publish(WorldEvent{Topic: TopicSystem}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(WorldEvent{Topic: TopicSystem}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("publish to a full queue = %v, want ErrQueueFull", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.Start(ctx)
+
+	// Подписчик не читает: после subscriberQueue событий остальные отбрасываются.
+	for range subscriberQueue + 2 {
+		for len(bus.Queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if err := bus.Publish(WorldEvent{Topic: TopicSystem}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for bus.Stats().Delivered+bus.Stats().Dropped < subscriberQueue+3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	st := bus.Stats()
+	if st.Published != subscriberQueue+4 || st.QueueDropped != 1 || st.Delivered != subscriberQueue || st.Dropped != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(st.Subscribers) != 1 || st.Subscribers[0].Dropped != 3 {
+		t.Errorf("subscriber stats = %+v", st.Subscribers)
+	}
+}
+
+func TestEventBusPersists(t *testing.T) {
+	repo := newTestRepo(t)
+	bus := NewEventBus(repo, 16)
+
+	bus.Publish(WorldEvent{Topic: TopicSystem, Type: "pause", Source: "api"})
+	bus.Publish(WorldEvent{Topic: TopicInteraction, Type: "conversation", Source: "a",
+		AffectedAgents: []string{"a", "b"}, Payload: map[string]any{"content": "Привет"}, Tick: 7})
+
+	// Событие сохраняется сразу при публикации, до рассылки.
+	events, err := repo.GetEvents(storage.EventFilter{})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("events = %d, %v; want 2", len(events), err)
+	}
+	if tick, _, _ := repo.LoadClock(); tick != 7 {
+		t.Errorf("clock = %d, want 7 from the tick event", tick)
+	}
+}
@@ -0,0 +1,38 @@
+// Package world — публикация состояния LLM-слоя.
+//
+// Circuit breaker и цепочка fallback'ов в pkg/llm ничего не знают о БД
+// и SSE. LLMHealthReporter превращает их HealthEvent в system-события
+// шины, чтобы дашборд видел деградацию и восстановление модели.
+
+package world
+
+import (
+	"fmt"
+	"log"
+
+	"milk/server/pkg/llm"
+)
+
+// LLMHealthReporter возвращает llm.HealthFunc, который публикует событие
+// топика "system" (сохраняется в events и уходит SSE-клиентам).
+func LLMHealthReporter(bus *EventBus) llm.HealthFunc {
+	return func(e llm.HealthEvent) {
+		log.Printf("llm health: %s provider=%s %s", e.Type, e.Provider, e.Detail)
+
+		message := fmt.Sprintf("%s: %s", e.Type, e.Provider)
+		if e.Detail != "" {
+			message += " — " + e.Detail
+		}
+		bus.Publish(WorldEvent{
+			Topic:  TopicSystem,
+			Type:   e.Type,
+			Source: "system",
+			Payload: map[string]any{
+				"provider": e.Provider,
+				"detail":   e.Detail,
+				"message":  message,
+			},
+			Priority: 1,
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package world — фоновые задачи симуляции.
+//
+// Рефлексия, забывание и статистика мира идут на часах симуляции
+// (TimeManager.ScheduleEvery): на паузе они стоят, при ускорении
+// выполняются чаще — так же, как и диалоги.
+
+package world
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
+
+// Периоды фоновых задач в тиках.
+const (
+	statsEvery      = 5
+	reflectionEvery = 10
+	forgettingEvery = 20
+)
+
+// Параметры забывания: за один проход importance несвежих воспоминаний
+// падает на forgetRate, записи ниже forgetThreshold удаляются.
+const (
+	forgetRate      = 0.02
+	forgetThreshold = 0.1
+	forgetGrace     = 150 // тиков (около часа при скорости 1): не трогать воспоминания, созданные или вспомненные за этот срок
+)
+
+// reflectionLines — сколько последних реплик показывать агенту при рефлексии.
+const reflectionLines = 8
+
+// scheduleJobs регистрирует фоновые задачи в часах.
+func (o *Orchestrator) scheduleJobs(ctx context.Context) {
+	o.clock.ScheduleEvery(statsEvery, "world_stats", func(info TickInfo) {
+		o.recordWorldStats(info)
+	})
+	o.clock.ScheduleEvery(reflectionEvery, "reflection", func(info TickInfo) {
+		o.reflect(ctx, info)
+	})
+	o.clock.ScheduleEvery(forgettingEvery, "memory_forgetting", func(info TickInfo) {
+		o.forget(info)
+	})
+}
+
+// recordWorldStats сохраняет снимок мира системным событием "world_stats".
+func (o *Orchestrator) recordWorldStats(info TickInfo) {
+	agents, err := o.repo.CountActiveAgents()
+	if err != nil {
+		log.Printf("world_stats: %v", err)
+		return
+	}
+	events, err := o.repo.CountTotalEvents()
+	if err != nil {
+		log.Printf("world_stats: %v", err)
+		return
+	}
+	from := info.Tick - statsEvery + 1
+	usage, err := o.repo.GetUsageTotals(storage.UsageFilter{FromTick: &from, ToTick: &info.Tick})
+	if err != nil {
+		log.Printf("world_stats: %v", err)
+		return
+	}
+
+	o.bus.Publish(WorldEvent{
+		Topic:  TopicSystem,
+		Type:   "world_stats",
+		Source: "system",
+		Payload: map[string]any{
+			"activeAgents":     agents,
+			"totalEvents":      events,
+			"simTime":          info.SimTime,
+			"llmCalls":         usage.Calls,
+			"llmErrors":        usage.Errors,
+			"promptTokens":     usage.PromptTokens,
+			"completionTokens": usage.CompletionTokens,
+		},
+		Tick: info.Tick,
+	})
+}
+
+// reflect даёт случайному активному агенту осмыслить недавние разговоры
+// и сохраняет вывод семантическим воспоминанием.
+func (o *Orchestrator) reflect(ctx context.Context, info TickInfo) {
+	agents, err := o.repo.GetRandomActiveAgents(1)
+	if err != nil || len(agents) == 0 {
+		if err != nil {
+			log.Printf("reflection tick %d: %v", info.Tick, err)
+		}
+		return
+	}
+	a := agents[0]
+
+	recent, speakers, err := o.recentLines(a.ID)
+	if err != nil {
+		log.Printf("reflection tick %d: %v", info.Tick, err)
+		return
+	}
+	if len(recent) == 0 {
+		return // Не о чем размышлять
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.tickTimeout)
+	defer cancel()
+	ctx = llm.WithCallInfo(ctx, llm.CallInfo{Purpose: llm.PurposeReflection, AgentID: a.ID, Tick: info.Tick})
+
+	p := parsePersonality(a.Personality)
+	insight, err := agent.NewBrain(&p).Reflect(ctx, o.llm, a.Name, parseMood(a.MoodState), parseGoals(a.Goals), recent)
+	if err != nil {
+		o.logThinkError(info.Tick, a.Name, err)
+		return
+	}
+
+	// Вывод касается тех, с кем агент говорил: по related_agents
+	// он всплывёт в промпте при встрече с ними (см. partnerContext)
+	meta, _ := json.Marshal(map[string]any{"source": "reflection", "tick": info.Tick})
+	related, _ := json.Marshal(speakers)
+	if err := o.repo.SaveMemory(storage.MemoryRecord{
+		AgentID:       a.ID,
+		Type:          "semantic",
+		Content:       insight,
+		Importance:    0.6,
+		TouchedTick:   sql.NullInt64{Int64: info.Tick, Valid: true},
+		RelatedAgents: sql.NullString{String: string(related), Valid: len(speakers) > 0},
+		Metadata:      sql.NullString{String: string(meta), Valid: true},
+	}); err != nil {
+		log.Printf("reflection tick %d: %v", info.Tick, err)
+		return
+	}
+	o.bus.Publish(WorldEvent{
+		Topic:          TopicMemory,
+		Type:           "reflection",
+		Source:         a.ID,
+		AffectedAgents: []string{a.ID},
+		Payload:        map[string]any{"agentId": a.ID, "content": insight},
+		Tick:           info.Tick,
+	})
+	log.Printf("reflection tick %d: %s reflected", info.Tick, a.Name)
+}
+
+// recentLines возвращает последние реплики разговоров с участием агента
+// в виде "Имя: текст", от старых к новым, и id остальных говоривших.
+func (o *Orchestrator) recentLines(agentID string) ([]string, []string, error) {
+	events, err := o.repo.GetEvents(storage.EventFilter{
+		Topic:   string(TopicInteraction),
+		Type:    "conversation",
+		AgentID: agentID,
+		Limit:   reflectionLines,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := map[string]string{}
+	var speakers []string
+	lines := make([]string, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		var p struct {
+			SpeakerID string `json:"speakerId"`
+			Content   string `json:"content"`
+		}
+		if !events[i].Payload.Valid || json.Unmarshal([]byte(events[i].Payload.String), &p) != nil || p.Content == "" {
+			continue
+		}
+		name, ok := names[p.SpeakerID]
+		if !ok {
+			name = p.SpeakerID
+			if rec, err := o.repo.GetAgentByID(p.SpeakerID); err == nil && rec != nil {
+				name = rec.Name
+			}
+			names[p.SpeakerID] = name
+			if p.SpeakerID != agentID && p.SpeakerID != "" {
+				speakers = append(speakers, p.SpeakerID)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, p.Content))
+	}
+	return lines, speakers, nil
+}
+
+// forget ослабляет несвежие воспоминания и удаляет забытые.
+func (o *Orchestrator) forget(info TickInfo) {
+	decayed, forgotten, err := o.repo.DecayMemories(forgetRate, forgetThreshold, info.Tick-forgetGrace)
+	if err != nil {
+		log.Printf("memory_forgetting tick %d: %v", info.Tick, err)
+		return
+	}
+	if forgotten == 0 {
+		return
+	}
+	log.Printf("memory_forgetting tick %d: %d decayed, %d forgotten", info.Tick, decayed, forgotten)
+	o.bus.Publish(WorldEvent{
+		Topic:   TopicMemory,
+		Type:    "forgetting",
+		Source:  "system",
+		Payload: map[string]any{"decayed": decayed, "forgotten": forgotten},
+		Tick:    info.Tick,
+	})
+}
@@ -0,0 +1,43 @@
+package world
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"milk/server/internal/storage"
+)
+
+func TestRecentLinesOnlyConversation(t *testing.T) {
+	repo, o := newTestWorld(t, scriptedDialogue("Hi"))
+	save := func(eventType, payload string) {
+		t.Helper()
+		err := repo.SaveEvent(&storage.EventRecord{
+			Topic: string(TopicInteraction), Type: eventType, Source: "a",
+			AffectedAgents: sql.NullString{String: `["a","b"]`, Valid: true},
+			Payload:        sql.NullString{String: payload, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range reflectionLines {
+		save("conversation", fmt.Sprintf(`{"speakerId":"b","content":"line %d"}`, i))
+	}
+	// Более свежие служебные события разговора не вытесняют реплики.
+	for range reflectionLines {
+		save("conversation_started", `{"conversationId":"c1"}`)
+		save("conversation_joined", `{"agentId":"b","content":"Bob joins"}`)
+	}
+
+	lines, speakers, err := o.recentLines("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != reflectionLines || lines[0] != "Bob: line 0" || lines[len(lines)-1] != fmt.Sprintf("Bob: line %d", reflectionLines-1) {
+		t.Errorf("lines = %q", lines)
+	}
+	if len(speakers) != 1 || speakers[0] != "b" {
+		t.Errorf("speakers = %v, want [b]", speakers)
+	}
+}
@@ -0,0 +1,374 @@
+// Package world — выбор собеседников.
+//
+// PartnerPolicy решает, кто с кем разговаривает на тике. AffinityPolicy
+// взвешивает пары по экстраверсии и общительности инициатора, отношениям
+// (или соперничеству для конфликтных агентов), давности последней встречи
+// и целям; обе политики сводят только агентов из одной зоны (см. space.go).
+// Причины выбора сохраняются в Pairing: оркестратор логирует их
+// и показывает в событиях разговора.
+
+package world
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+)
+
+// Pairing — выбранная пара собеседников.
+type Pairing struct {
+	Initiator storage.AgentRecord
+	Partner   storage.AgentRecord
+
+	// Policy — имя политики, выбравшей пару.
+	Policy string
+
+	// Score — вес пары у политики (для случайного выбора — 1).
+	Score float64
+
+	// Reasons — человекочитаемые причины выбора.
+	Reasons []string
+
+	// Topic — тема разговора, если выбор определила цель инициатора.
+	Topic string
+}
+
+// Summary — причины одной строкой для логов и ленты.
+func (p Pairing) Summary() string {
+	return strings.Join(p.Reasons, "; ")
+}
+
+// MatchContext — данные для выбора пар.
+type MatchContext struct {
+	// Agents — доступные агенты.
+	Agents []storage.AgentRecord
+
+	// Relationships — направленные связи между агентами.
+	Relationships []storage.RelationshipRecord
+
+	// Now — текущее время (wall-clock, как relationships.last_interaction).
+	Now time.Time
+
+	// Rand — источник случайности.
+	Rand *rand.Rand
+}
+
+// relationship возвращает отношение a к b или nil.
+func (mc MatchContext) relationship(a, b string) *storage.RelationshipRecord {
+	for i, rel := range mc.Relationships {
+		if rel.Agent1ID == a && rel.Agent2ID == b {
+			return &mc.Relationships[i]
+		}
+	}
+	return nil
+}
+
+// PartnerPolicy — подключаемая политика выбора собеседников.
+type PartnerPolicy interface {
+	// Name — имя для логов и событий ("random", "affinity").
+	Name() string
+
+	// Pairs выбирает до max непересекающихся пар.
+	Pairs(mc MatchContext, max int) []Pairing
+}
+
+// NewPartnerPolicy возвращает политику по имени: "random" или "affinity"
+// (по умолчанию).
+func NewPartnerPolicy(name string) (PartnerPolicy, error) {
+	switch name {
+	case "", "affinity":
+		return AffinityPolicy{}, nil
+	case "random":
+		return RandomPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown partner policy %q (want affinity or random)", name)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// RandomPolicy — равновероятные пары
+// -----------------------------------------------------------------------------
+
+// RandomPolicy выбирает пары равновероятно — прежнее поведение.
+type RandomPolicy struct{}
+
+// Name реализует PartnerPolicy.
+func (RandomPolicy) Name() string { return "random" }
+
+// Pairs реализует PartnerPolicy.
+func (p RandomPolicy) Pairs(mc MatchContext, max int) []Pairing {
+	agents := append([]storage.AgentRecord(nil), mc.Agents...)
+	mc.Rand.Shuffle(len(agents), func(i, j int) { agents[i], agents[j] = agents[j], agents[i] })
+
+	// Пары только внутри зоны: первый свободный агент зоны ждёт второго
+	var pairs []Pairing
+	waiting := make(map[string]storage.AgentRecord)
+	for _, a := range agents {
+		if len(pairs) >= max {
+			break
+		}
+		zone := location(a)
+		first, ok := waiting[zone]
+		if !ok {
+			waiting[zone] = a
+			continue
+		}
+		delete(waiting, zone)
+		pairs = append(pairs, Pairing{
+			Initiator: first,
+			Partner:   a,
+			Policy:    p.Name(),
+			Score:     1,
+			Reasons:   []string{"random pairing"},
+		})
+	}
+	return pairs
+}
+
+// -----------------------------------------------------------------------------
+// AffinityPolicy — пары по характеру, настроению, отношениям и целям
+// -----------------------------------------------------------------------------
+
+// AffinityPolicy выбирает инициатора пропорционально желанию общаться,
+// а собеседника — пропорционально весу пары. Выбор случайный, но
+// взвешенный: предпочтения сдвигают вероятность, а не фиксируют пары.
+type AffinityPolicy struct{}
+
+// Name реализует PartnerPolicy.
+func (AffinityPolicy) Name() string { return "affinity" }
+
+// Ключевые слова целей (EN/RU), влияющие на выбор собеседника.
+var (
+	socialGoalWords   = []string{"friend", "meet", "talk", "social", "друг", "знаком", "общ", "поговор"}
+	conflictGoalWords = []string{"compete", "win", "rival", "argue", "beat", "соревн", "побед", "спор", "соперн"}
+)
+
+// Pairs реализует PartnerPolicy.
+func (p AffinityPolicy) Pairs(mc MatchContext, max int) []Pairing {
+	free := make([]matchAgent, 0, len(mc.Agents))
+	for _, a := range mc.Agents {
+		free = append(free, newMatchAgent(a))
+	}
+
+	var pairs []Pairing
+	for len(free) >= 2 && len(pairs) < max {
+		// Инициатор: чем общительнее сейчас, тем вероятнее
+		weights := make([]float64, len(free))
+		for i, a := range free {
+			weights[i] = a.drive()
+		}
+		ii := weightedIndex(mc.Rand, weights)
+		initiator := free[ii]
+		others := append(append([]matchAgent(nil), free[:ii]...), free[ii+1:]...)
+
+		// Поговорить можно только с теми, кто рядом; если рядом никого,
+		// инициатор в этот тик остаётся один
+		var rest, far []matchAgent
+		for _, cand := range others {
+			if nearby(initiator.rec, cand.rec) {
+				rest = append(rest, cand)
+			} else {
+				far = append(far, cand)
+			}
+		}
+		if len(rest) == 0 {
+			free = others
+			continue
+		}
+
+		// Собеседник: вес пары с причинами
+		scores := make([]float64, len(rest))
+		reasons := make([][]string, len(rest))
+		topics := make([]string, len(rest))
+		for i, cand := range rest {
+			scores[i], reasons[i], topics[i] = p.score(mc, initiator, cand)
+		}
+		pi := weightedIndex(mc.Rand, scores)
+		partner := rest[pi]
+
+		pairs = append(pairs, Pairing{
+			Initiator: initiator.rec,
+			Partner:   partner.rec,
+			Policy:    p.Name(),
+			Score:     scores[pi],
+			Reasons:   append([]string{initiator.driveReason()}, reasons[pi]...),
+			Topic:     topics[pi],
+		})
+
+		free = append(append(far, rest[:pi]...), rest[pi+1:]...)
+	}
+	return pairs
+}
+
+// score считает вес пары initiator → cand, причины и тему — первую
+// цель инициатора, повлиявшую на выбор.
+func (p AffinityPolicy) score(mc MatchContext, initiator, cand matchAgent) (float64, []string, string) {
+	score := 1.0
+	var reasons []string
+	var topic string
+
+	rel := mc.relationship(initiator.rec.ID, cand.rec.ID)
+	switch {
+	case rel == nil:
+		// Незнакомцы: любопытных тянет к новым лицам
+		score *= 1 + initiator.personality.Openness*0.5
+		reasons = append(reasons, fmt.Sprintf("%s is a stranger", cand.rec.Name))
+	case rel.Strength < 0 && initiator.conflictSeeking():
+		score *= 1 + -rel.Strength*1.5
+		reasons = append(reasons, fmt.Sprintf("seeks out rival %s (strength %.2f)", cand.rec.Name, rel.Strength))
+	case rel.Strength < 0:
+		score *= 1 + rel.Strength*0.7 // сила < 0 → вес меньше 1
+		reasons = append(reasons, fmt.Sprintf("avoids rival %s (strength %.2f)", cand.rec.Name, rel.Strength))
+	default:
+		score *= 1 + rel.Strength
+		reasons = append(reasons, fmt.Sprintf("%s with %s (strength %.2f)", rel.Type, cand.rec.Name, rel.Strength))
+	}
+
+	// Давность: недавние собеседники отходят на второй план
+	if rel != nil && rel.LastInteraction.Valid {
+		since := mc.Now.Sub(rel.LastInteraction.Time)
+		factor := 0.3 + since.Minutes()/30
+		if factor > 1.5 {
+			factor = 1.5
+		}
+		score *= factor
+		reasons = append(reasons, fmt.Sprintf("last talked %s ago", since.Round(time.Minute)))
+	} else if rel != nil {
+		score *= 1.3
+	}
+
+	// Цели инициатора
+	for _, g := range initiator.goals {
+		if g.IsCompleted {
+			continue
+		}
+		desc := strings.ToLower(g.Description)
+		matched := true
+		switch {
+		case strings.Contains(desc, strings.ToLower(cand.rec.Name)):
+			score *= 2
+			reasons = append(reasons, fmt.Sprintf("goal mentions %s: %q", cand.rec.Name, g.Description))
+		case rel == nil && containsAny(desc, socialGoalWords):
+			score *= 1 + 0.5*goalWeight(g)
+			reasons = append(reasons, fmt.Sprintf("goal: %q", g.Description))
+		case rel != nil && rel.Strength < 0 && containsAny(desc, conflictGoalWords):
+			score *= 1 + 0.5*goalWeight(g)
+			reasons = append(reasons, fmt.Sprintf("goal: %q", g.Description))
+		default:
+			matched = false
+		}
+		if matched && topic == "" {
+			topic = g.Description
+		}
+	}
+
+	// Собеседник тоже должен быть настроен на разговор
+	score *= 0.5 + cand.drive()/2
+
+	if score < 0.01 {
+		score = 0.01
+	}
+	return score, reasons, topic
+}
+
+// matchAgent — агент с разобранными личностью, настроением и целями.
+type matchAgent struct {
+	rec         storage.AgentRecord
+	personality agent.Personality
+	mood        agent.Mood
+	influence   agent.MoodInfluence
+	goals       []agent.Goal
+}
+
+func newMatchAgent(rec storage.AgentRecord) matchAgent {
+	return matchAgent{
+		rec:         rec,
+		personality: parsePersonality(rec.Personality),
+		mood:        parseMood(rec.MoodState),
+		influence:   agent.GetMoodInfluence(parsePAD(rec.MoodState)),
+		goals:       parseGoals(rec.Goals),
+	}
+}
+
+// drive — желание общаться: экстраверсия плюс общительность настроения.
+func (a matchAgent) drive() float64 {
+	d := 0.2 + a.personality.Extraversion + 0.5*a.influence.SociabilityModifier
+	if d < 0.05 {
+		d = 0.05
+	}
+	return d
+}
+
+func (a matchAgent) driveReason() string {
+	return fmt.Sprintf("%s starts (extraversion %.2f, sociability %+.2f)",
+		a.rec.Name, a.personality.Extraversion, a.influence.SociabilityModifier)
+}
+
+// conflictSeeking — ищет ли агент конфликта: неуступчивый характер
+// или напористое настроение.
+func (a matchAgent) conflictSeeking() bool {
+	return a.personality.Agreeableness < 0.4 || a.influence.AssertivenessModifier > 0.5
+}
+
+// parsePAD читает PAD из mood_state ({"label":..., "pad":{...}}).
+func parsePAD(raw sql.NullString) agent.PADState {
+	var m struct {
+		PAD agent.PADState `json:"pad"`
+	}
+	if raw.Valid && raw.String != "" {
+		json.Unmarshal([]byte(raw.String), &m)
+	}
+	return m.PAD
+}
+
+// parseMood читает метку настроения из mood_state. Без метки — нейтральное.
+func parseMood(raw sql.NullString) agent.Mood {
+	var m struct {
+		Label agent.Mood `json:"label"`
+	}
+	if raw.Valid && raw.String != "" {
+		json.Unmarshal([]byte(raw.String), &m)
+	}
+	if m.Label == "" {
+		return agent.MoodNeutral
+	}
+	return m.Label
+}
+
+func goalWeight(g agent.Goal) float64 {
+	if g.Priority <= 0 {
+		return 0.5
+	}
+	return g.Priority
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// weightedIndex выбирает индекс пропорционально весам.
+func weightedIndex(r *rand.Rand, weights []float64) int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return len(weights) - 1
+}
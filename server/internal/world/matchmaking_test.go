@@ -0,0 +1,130 @@
+package world
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"milk/server/internal/storage"
+)
+
+// testAgent — агент с заданными экстраверсией и доброжелательностью.
+func testAgent(id string, extraversion, agreeableness float64, goals ...string) storage.AgentRecord {
+	rec := storage.AgentRecord{
+		ID:          id,
+		Name:        id,
+		Personality: fmt.Sprintf(`{"Openness":0.5,"Extraversion":%g,"Agreeableness":%g}`, extraversion, agreeableness),
+	}
+	if len(goals) > 0 {
+		raw := "["
+		for i, g := range goals {
+			if i > 0 {
+				raw += ","
+			}
+			raw += fmt.Sprintf(`{"Description":%q,"Priority":1}`, g)
+		}
+		rec.Goals = sql.NullString{String: raw + "]", Valid: true}
+	}
+	return rec
+}
+
+func TestAffinityPolicyPairs(t *testing.T) {
+	mc := MatchContext{
+		Agents: []storage.AgentRecord{
+			testAgent("a", 0.5, 0.5), testAgent("b", 0.5, 0.5), testAgent("c", 0.5, 0.5),
+			testAgent("d", 0.5, 0.5), testAgent("e", 0.5, 0.5),
+		},
+		Now:  time.Now(),
+		Rand: rand.New(rand.NewSource(1)),
+	}
+	for max := 1; max <= 3; max++ {
+		pairs := AffinityPolicy{}.Pairs(mc, max)
+		if want := min(max, 2); len(pairs) != want {
+			t.Fatalf("max %d: %d pairs, want %d", max, len(pairs), want)
+		}
+		seen := map[string]bool{}
+		for _, p := range pairs {
+			for _, id := range []string{p.Initiator.ID, p.Partner.ID} {
+				if seen[id] {
+					t.Errorf("agent %s in two pairs: %+v", id, pairs)
+				}
+				seen[id] = true
+			}
+			if p.Policy != "affinity" || len(p.Reasons) == 0 {
+				t.Errorf("pairing = %+v", p)
+			}
+		}
+	}
+}
+
+func TestAffinityPolicyScore(t *testing.T) {
+	now := time.Now()
+	rel := func(a, b string, strength float64, last time.Duration) storage.RelationshipRecord {
+		r := storage.RelationshipRecord{Agent1ID: a, Agent2ID: b, Type: "friend", Strength: strength}
+		if last > 0 {
+			r.LastInteraction = sql.NullTime{Time: now.Add(-last), Valid: true}
+		}
+		return r
+	}
+	mc := MatchContext{
+		Relationships: []storage.RelationshipRecord{
+			rel("me", "friend", 0.8, time.Hour),
+			rel("me", "recent", 0.8, time.Minute),
+			rel("me", "rival", -0.8, time.Hour),
+		},
+		Now: now,
+	}
+	score := func(initiator, cand storage.AgentRecord) float64 {
+		s, _, _ := AffinityPolicy{}.score(mc, newMatchAgent(initiator), newMatchAgent(cand))
+		return s
+	}
+
+	kind := testAgent("me", 0.5, 0.9)
+	grumpy := testAgent("me", 0.5, 0.1)
+	other := func(id string) storage.AgentRecord { return testAgent(id, 0.5, 0.5) }
+
+	tests := []struct {
+		name      string
+		high, low float64
+	}{
+		{"friend over stranger", score(kind, other("friend")), score(kind, other("stranger"))},
+		{"long ago over recent", score(kind, other("friend")), score(kind, other("recent"))},
+		{"agreeable avoids rival", score(kind, other("stranger")), score(kind, other("rival"))},
+		{"conflict-seeking seeks rival", score(grumpy, other("rival")), score(grumpy, other("stranger"))},
+		{"goal names the partner", score(testAgent("me", 0.5, 0.9, "Поговорить с stranger"), other("stranger")), score(kind, other("stranger"))},
+		{"talkative partner", score(kind, testAgent("stranger", 0.9, 0.5)), score(kind, testAgent("stranger", 0.1, 0.5))},
+	}
+	for _, tt := range tests {
+		if tt.high <= tt.low {
+			t.Errorf("%s: %.3f <= %.3f", tt.name, tt.high, tt.low)
+		}
+	}
+}
+
+func TestAffinityPolicyPrefersExtraverts(t *testing.T) {
+	mc := MatchContext{
+		Agents: []storage.AgentRecord{testAgent("loud", 0.95, 0.5), testAgent("quiet", 0.05, 0.5), testAgent("x", 0.5, 0.5)},
+		Now:    time.Now(),
+		Rand:   rand.New(rand.NewSource(7)),
+	}
+	starts := map[string]int{}
+	for range 1000 {
+		starts[AffinityPolicy{}.Pairs(mc, 1)[0].Initiator.ID]++
+	}
+	if starts["loud"] <= 2*starts["quiet"] {
+		t.Errorf("initiators = %v, want the extravert to start far more often", starts)
+	}
+}
+
+func TestNewPartnerPolicy(t *testing.T) {
+	for name, want := range map[string]string{"": "affinity", "affinity": "affinity", "random": "random"} {
+		if p, err := NewPartnerPolicy(name); err != nil || p.Name() != want {
+			t.Errorf("NewPartnerPolicy(%q) = %v, %v; want %s", name, p, err, want)
+		}
+	}
+	if _, err := NewPartnerPolicy("nearest"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
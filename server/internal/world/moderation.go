@@ -0,0 +1,68 @@
+// Package world — журнал решений модерации.
+//
+// moderation.Moderator ничего не знает о БД и SSE. ModerationRecorder
+// публикует событием топика "moderation" каждое решение, кроме allow:
+// дашборд видит заблокированные инъекции, задержанные и отредактированные
+// реплики. Пропущенные реплики — это почти весь трафик, их только считаем.
+// Флаги и задержанные реплики сохраняются со статусом "pending" —
+// они ждут ревью через POST /moderation/decisions/{id}/review.
+
+package world
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"milk/server/internal/moderation"
+)
+
+// allowLogEvery — раз во сколько пропущенных реплик писать счётчик в лог.
+const allowLogEvery = 1000
+
+// ModerationRecorder возвращает получатель решений модерации,
+// публикующий их в шину. Решения allow не публикуются и не сохраняются,
+// а только считаются.
+func ModerationRecorder(bus *EventBus) func(moderation.Decision) {
+	var allowed atomic.Int64
+	return func(d moderation.Decision) {
+		if d.Action == moderation.ActionAllow {
+			if n := allowed.Add(1); n%allowLogEvery == 0 {
+				log.Printf("moderation: %d items allowed so far", n)
+			}
+			return
+		}
+
+		payload := map[string]any{
+			"kind":       d.Kind,
+			"agentId":    d.AgentID,
+			"action":     d.Action,
+			"category":   d.Category,
+			"reason":     d.Reason,
+			"classifier": d.Classifier,
+			"text":       d.Item.Text,
+			"redacted":   d.Redacted,
+			"errors":     d.Errors,
+		}
+
+		status := "completed"
+		if d.Action == moderation.ActionFlag || d.Action == moderation.ActionBlock {
+			status = "pending"
+		}
+		log.Printf("moderation: %s %s agent=%s (%s: %s)", d.Action, d.Kind, d.AgentID, d.Category, d.Reason)
+		payload["message"] = fmt.Sprintf("moderation: %s %s (%s)", d.Action, d.Kind, d.Category)
+
+		e := WorldEvent{
+			Topic:   TopicModeration,
+			Type:    "moderation_" + string(d.Action),
+			Source:  "moderation",
+			Payload: payload,
+			Tick:    d.Tick,
+			Status:  status,
+		}
+		if d.AgentID != "" {
+			e.AffectedAgents = []string{d.AgentID}
+		}
+		bus.Publish(e)
+	}
+}
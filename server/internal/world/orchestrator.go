@@ -1,7 +1,11 @@
 // Package world provides the simulation orchestrator.
 //
 // Orchestrator — центральный координатор симуляции.
-// Каждые 22 секунд берёт двух случайных агентов и запускает диалог.
+// На каждый тик TimeManager (22 секунды при скорости 1.0) выбирает
+// непересекающиеся пары агентов политикой PartnerPolicy и ведёт их диалоги
+// параллельно в пуле воркеров; к диалогу могут присоединиться другие
+// агенты (см. conversation.go). Тики не перекрываются: если предыдущий
+// ещё идёт, новый пропускается.
 
 package world
 
@@ -11,65 +15,162 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math/rand"
+	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"milk/server/internal/agent"
 	"milk/server/internal/api"
+	"milk/server/internal/moderation"
 	"milk/server/internal/storage"
 	"milk/server/pkg/llm"
 )
 
 // Orchestrator — тикер диалогов между агентами.
 type Orchestrator struct {
-	repo         *storage.Repository
-	llm          agent.LLMClient
-	hub          *api.Hub
-	tickInterval time.Duration
-	turns        int // реплик за тик
-	currentTick  int64
-	mu           sync.Mutex
-	cancel       context.CancelFunc
-}
-
-// NewOrchestrator создаёт Orchestrator.
-func NewOrchestrator(repo *storage.Repository, llmClient *llm.Client, hub *api.Hub) *Orchestrator {
+	repo        *storage.Repository
+	llm         agent.LLMClient
+	hub         *api.Hub
+	bus         *EventBus
+	clock       *TimeManager
+	tickTimeout time.Duration // после него запросы тика считаются устаревшими
+	length      ConversationLength
+	moderator   *moderation.Moderator
+	policy      PartnerPolicy
+	turnPolicy  TurnPolicy
+	groupSize   int // максимум участников разговора
+	workers     int // размер пула разговоров
+	pairs       int // пар за тик
+	locks       *agentLocks
+	seed        *int64       // зерно случайных решений; nil = от времени
+	tickBusy    atomic.Bool  // идёт ли обработка тика
+	skipped     atomic.Int64 // тиков пропущено из-за занятости
+	currentTick int64
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+}
+
+// NewOrchestrator создаёт Orchestrator. События тиков публикуются в bus;
+// из hub берутся сообщения человека.
+func NewOrchestrator(repo *storage.Repository, llmClient agent.LLMClient, hub *api.Hub, bus *EventBus) *Orchestrator {
 	return &Orchestrator{
-		repo:         repo,
-		llm:          llmClient,
-		hub:          hub,
-		tickInterval: 22 * time.Second,
-		turns:        4,
+		repo:        repo,
+		llm:         llmClient,
+		hub:         hub,
+		bus:         bus,
+		clock:       NewTimeManager(22 * time.Second),
+		tickTimeout: 5 * time.Minute,
+		length:      DefaultConversationLength,
+		policy:      AffinityPolicy{},
+		turnPolicy:  WeightedTurns{},
+		groupSize:   4,
+		workers:     1,
+		pairs:       1,
+		locks:       newAgentLocks(),
+	}
+}
+
+// UsePartnerPolicy меняет политику выбора собеседников.
+func (o *Orchestrator) UsePartnerPolicy(p PartnerPolicy) {
+	o.policy = p
+}
+
+// UseConcurrency задаёт размер пула разговоров (обычно — лимит
+// одновременных запросов к LLM) и число пар за тик. pairs <= 0 — столько
+// пар, сколько воркеров.
+func (o *Orchestrator) UseConcurrency(workers, pairs int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if pairs <= 0 {
+		pairs = workers
+	}
+	o.workers = workers
+	o.pairs = pairs
+}
+
+// UseGroupSize задаёт максимум участников разговора. 2 — только пары.
+func (o *Orchestrator) UseGroupSize(n int) {
+	if n < 2 {
+		n = 2
+	}
+	o.groupSize = n
+}
+
+// UseConversationLength задаёт границы длины разговора в репликах.
+func (o *Orchestrator) UseConversationLength(l ConversationLength) {
+	if l.Min < 1 {
+		l.Min = 1
 	}
+	if l.Max < l.Min {
+		l.Max = l.Min
+	}
+	o.length = l
+}
+
+// UseSeed делает случайные решения симуляции (перемещения, выбор пар,
+// очерёдность реплик) воспроизводимыми: генераторы выводятся из seed,
+// номера тика и инициатора разговора. Без UseSeed — из текущего времени.
+func (o *Orchestrator) UseSeed(seed int64) {
+	o.seed = &seed
+}
+
+// newRand возвращает генератор для решений тика tick в области scope.
+func (o *Orchestrator) newRand(tick int64, scope string) *rand.Rand {
+	if o.seed == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%d/%s", *o.seed, tick, scope)
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// UseModerator подключает модерацию реплик агентов. nil = без проверки.
+func (o *Orchestrator) UseModerator(m *moderation.Moderator) {
+	o.moderator = m
 }
 
-// Start запускает фоновый тикер. Блокирует до отмены ctx.
+// Start запускает часы симуляции и обрабатывает их тики. Блокирует до отмены ctx.
+// Тик, время симуляции, скорость и пауза восстанавливаются из world_state —
+// после рестарта шкала времени продолжается; SIM_START_PAUSED=true
+// ставит на паузу и мир, сохранённый работающим.
 func (o *Orchestrator) Start(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	o.cancel = cancel
 
-	log.Println("orchestrator: started, tick interval", o.tickInterval)
-
-	ticker := time.NewTicker(o.tickInterval)
-	defer ticker.Stop()
+	o.restoreClock()
+	status := o.clock.Status()
+	log.Printf("orchestrator: started at tick %d, tick duration %s, speed %.1f, paused %t",
+		status.Tick, o.clock.TickDuration, status.Speed, status.IsPaused)
 
-	o.mu.Lock()
-	o.currentTick++
-	tick := o.currentTick
-	o.mu.Unlock()
-
-	go o.runTick(ctx, tick)
+	o.scheduleJobs(ctx)
+	go o.clock.Run(ctx)
 
 	for {
 		select {
-		case <-ticker.C:
+		case info := <-o.clock.TickChannel():
 			o.mu.Lock()
-			o.currentTick++
-			tick := o.currentTick
+			o.currentTick = info.Tick
 			o.mu.Unlock()
 
-			go o.runTick(ctx, tick)
+			if !o.tickBusy.CompareAndSwap(false, true) {
+				n := o.skipped.Add(1)
+				log.Printf("orchestrator tick %d: skipped, previous tick still running (%d skipped)", info.Tick, n)
+				// У пропущенного тика нет своих событий: фиксируется сразу
+				if err := o.repo.SaveTick(info.Tick, info.SimTime); err != nil {
+					log.Printf("orchestrator tick %d: %v", info.Tick, err)
+				}
+				continue
+			}
+			go func() {
+				defer o.tickBusy.Store(false)
+				o.runTick(ctx, info)
+			}()
 
 		case <-ctx.Done():
 			log.Println("orchestrator: stopped")
@@ -78,133 +179,314 @@ func (o *Orchestrator) Start(ctx context.Context) {
 	}
 }
 
-// Stop останавливает тикер.
-func (o *Orchestrator) Stop() {
-	if o.cancel != nil {
-		o.cancel()
+// Pause ставит симуляцию на паузу.
+func (o *Orchestrator) Pause() error {
+	o.clock.Pause()
+	o.publishSystem("pause", "simulation paused")
+	return o.repo.SetWorldState(storage.WorldStateIsPaused, "true")
+}
+
+// Resume снимает симуляцию с паузы.
+func (o *Orchestrator) Resume() error {
+	o.clock.Resume()
+	o.publishSystem("resume", "simulation resumed")
+	return o.repo.SetWorldState(storage.WorldStateIsPaused, "false")
+}
+
+// publishSystem публикует системное событие с повышенным приоритетом.
+func (o *Orchestrator) publishSystem(eventType, message string) {
+	o.bus.Publish(WorldEvent{
+		Topic:    TopicSystem,
+		Type:     eventType,
+		Source:   "system",
+		Payload:  map[string]any{"message": message},
+		Tick:     o.clock.Status().Tick,
+		Priority: 1,
+	})
+}
+
+// ErrTickRunning — Step вызван, пока предыдущий тик ещё идёт: новый тик
+// был бы пропущен.
+var ErrTickRunning = errors.New("previous tick is still running: step again when it ends")
+
+// Step выполняет один тик на паузе.
+func (o *Orchestrator) Step() error {
+	if o.tickBusy.Load() {
+		return ErrTickRunning
 	}
+	return o.clock.Step()
 }
 
-func (o *Orchestrator) runTick(ctx context.Context, tick int64) {
-	agents, err := o.repo.GetRandomActiveAgents(2)
-	if err != nil {
-		log.Printf("orchestrator tick %d: getAgents error: %v", tick, err)
-		return
+// SetSpeed меняет множитель скорости (0.1–10).
+func (o *Orchestrator) SetSpeed(speed float64) error {
+	if err := o.clock.SetSpeed(speed); err != nil {
+		return err
 	}
-	if len(agents) < 2 {
-		log.Printf("orchestrator tick %d: not enough agents (%d)", tick, len(agents))
-		return
+	return o.repo.SetWorldState(storage.WorldStateSimulationSpeed, strconv.FormatFloat(speed, 'f', -1, 64))
+}
+
+// ScheduledTasks возвращает текущий тик и снимок задач часов для GET /world/tasks.
+func (o *Orchestrator) ScheduledTasks() (int64, []api.ScheduledTaskDTO) {
+	tasks := o.clock.Tasks()
+	out := make([]api.ScheduledTaskDTO, 0, len(tasks))
+	for _, t := range tasks {
+		dto := api.ScheduledTaskDTO{
+			Label:          t.Label,
+			Kind:           t.Kind,
+			Interval:       t.Interval,
+			LastRun:        t.LastRun,
+			NextRun:        t.NextRun,
+			Running:        t.Running,
+			Runs:           t.Stats.Runs,
+			Failures:       t.Stats.Failures,
+			Skipped:        t.Stats.Skipped,
+			LastError:      t.Stats.LastError,
+			LastDurationMs: millis(t.Stats.LastDuration),
+			MaxDurationMs:  millis(t.Stats.MaxDuration),
+		}
+		if t.Stats.Runs > 0 {
+			dto.AvgDurationMs = millis(t.Stats.TotalDuration) / float64(t.Stats.Runs)
+		}
+		out = append(out, dto)
 	}
+	return o.clock.Status().Tick, out
+}
 
-	log.Printf("orchestrator tick %d: %s <-> %s", tick, agents[0].Name, agents[1].Name)
-	o.runConversation(ctx, agents[0], agents[1], tick)
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
 }
 
-func (o *Orchestrator) runConversation(
-	ctx context.Context,
-	a1, a2 storage.AgentRecord,
-	tick int64,
-) {
-	p1 := parsePersonality(a1.Personality)
-	p2 := parsePersonality(a2.Personality)
+// restoreClock применяет к часам сохранённые тик, время симуляции,
+// скорость и паузу и записывает в world_state фактическое состояние паузы.
+func (o *Orchestrator) restoreClock() {
+	tick, simTime, err := o.repo.LoadClock()
+	if err != nil {
+		log.Printf("orchestrator: load clock: %v", err)
+	}
+	o.clock.Restore(tick, simTime)
+	o.mu.Lock()
+	o.currentTick = tick
+	o.mu.Unlock()
 
-	brain1 := agent.NewBrain(&p1)
-	brain2 := agent.NewBrain(&p2)
+	state, err := o.repo.GetWorldState()
+	if err != nil {
+		log.Printf("orchestrator: load world state: %v", err)
+	}
+	if v, err := strconv.ParseFloat(state[storage.WorldStateSimulationSpeed], 64); err == nil {
+		if err := o.clock.SetSpeed(v); err != nil {
+			log.Printf("orchestrator: saved speed %v ignored: %v", v, err)
+		}
+	}
 
-	mood1 := agent.MoodNeutral
-	mood2 := agent.MoodNeutral
+	paused := state[storage.WorldStateIsPaused] == "true" || os.Getenv("SIM_START_PAUSED") == "true"
+	if paused {
+		o.clock.Pause()
+	}
+	o.saveWorldState(storage.WorldStateIsPaused, strconv.FormatBool(paused))
+}
 
-	goals1 := parseGoals(a1.Goals)
-	goals2 := parseGoals(a2.Goals)
+// saveWorldState пишет значение в world_state, логируя ошибку.
+func (o *Orchestrator) saveWorldState(key, value string) {
+	if err := o.repo.SetWorldState(key, value); err != nil {
+		log.Printf("orchestrator: %v", err)
+	}
+}
 
-	var history1, history2 []llm.Message
+// Stop останавливает тикер.
+func (o *Orchestrator) Stop() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+}
 
-	// Начальное сообщение: агент 1 видит агента 2
-	opener := fmt.Sprintf(
-		"You notice %s nearby. Start a conversation naturally based on your personality and current mood.",
-		a2.Name,
-	)
-	history1 = append(history1, llm.Message{Role: "user", Content: opener})
+// runTick выбирает пары и ведёт их разговоры в пуле воркеров.
+// Возвращается, когда все разговоры тика завершились. События тика
+// сохраняются и рассылаются сразу при публикации (реплики идут в ленту
+// вживую); в конце фиксируются только current_tick и время симуляции.
+func (o *Orchestrator) runTick(ctx context.Context, info TickInfo) {
+	tick := info.Tick
+	defer func() {
+		if err := o.repo.SaveTick(tick, info.SimTime); err != nil {
+			log.Printf("orchestrator tick %d: %v", tick, err)
+		}
+	}()
+
+	// Контекст тика: когда тик завершается или устаревает, его запросы,
+	// ещё стоящие в очереди планировщика LLM, снимаются.
+	ctx, cancel := context.WithTimeout(ctx, o.tickTimeout)
+	defer cancel()
+
+	pool := newWorkerPool(o.workers)
+	for _, pair := range o.choosePairs(tick) {
+		if !o.locks.TryLock(pair.Initiator.ID, pair.Partner.ID) {
+			log.Printf("orchestrator tick %d: %s or %s is already talking, pair dropped",
+				tick, pair.Initiator.Name, pair.Partner.Name)
+			continue
+		}
+		c := o.startConversation(pair, tick)
+		pool.Go(func() { o.runConversation(ctx, c, tick) })
+	}
+	pool.Wait()
+}
 
-	for i := 0; i < o.turns; i++ {
-		select {
-		case <-ctx.Done():
-			log.Println("orchestrator: conversation canceled")
-			return
-		default:
+// choosePairs выбирает до o.pairs непересекающихся пар политикой o.policy
+// среди активных агентов, не занятых разговором.
+func (o *Orchestrator) choosePairs(tick int64) []Pairing {
+	agents, err := o.repo.GetActiveAgents()
+	if err != nil {
+		log.Printf("orchestrator tick %d: getAgents error: %v", tick, err)
+		return nil
+	}
+	free := agents[:0]
+	for _, a := range agents {
+		if !o.locks.IsBusy(a.ID) {
+			free = append(free, a)
 		}
+	}
+	if len(free) < 2 {
+		log.Printf("orchestrator tick %d: not enough free agents (%d)", tick, len(free))
+		return nil
+	}
+	rnd := o.newRand(tick, "pairs")
+	free = o.moveAgents(free, rnd, tick)
 
-		if i%2 == 0 {
-			// Ход агента 1
-			o.injectHumanMessages(&history1, a1.ID)
+	rels, err := o.repo.ListRelationships()
+	if err != nil {
+		log.Printf("orchestrator tick %d: relationships: %v", tick, err)
+	}
 
-			reply, err := brain1.Think(ctx, o.llm, a1.Name, mood1, goals1, history1)
-			if err != nil {
-				if errors.Is(err, context.DeadlineExceeded) {
-					log.Printf("orchestrator: agent1 timed out (Ollama is too slow)")
-				} else {
-					log.Printf("orchestrator: agent1 error: %v", err)
-				}
-				return // Выходим из диалога при любой ошибке LLM
-			}
-			o.saveAndBroadcast(a1, a2, reply, tick)
-
-			history1 = append(history1, llm.Message{Role: "assistant", Content: reply})
-			history2 = append(history2, llm.Message{
-				Role:    "user",
-				Content: fmt.Sprintf("%s says: %s", a1.Name, reply),
-			})
-		} else {
-			// Ход агента 2
-			o.injectHumanMessages(&history2, a2.ID)
-
-			if len(history2) == 0 {
-				history2 = append(history2, llm.Message{
-					Role:    "user",
-					Content: fmt.Sprintf("%s is talking to you. Respond naturally.", a1.Name),
-				})
-			}
+	return o.policy.Pairs(MatchContext{
+		Agents:        free,
+		Relationships: rels,
+		Now:           time.Now(),
+		Rand:          rnd,
+	}, o.pairs)
+}
 
-			reply, err := brain2.Think(ctx, o.llm, a2.Name, mood2, goals2, history2)
-			if err != nil {
-				if errors.Is(err, context.DeadlineExceeded) {
-					log.Printf("orchestrator: agent1 timed out (Ollama is too slow)")
-				} else {
-					log.Printf("orchestrator: agent1 error: %v", err)
-				}
-				return // Выходим из диалога при любой ошибке LLM
-			}
-			o.saveAndBroadcast(a2, a1, reply, tick)
+// pairingPayload — причины выбора пары для payload событий разговора.
+func pairingPayload(p Pairing) map[string]any {
+	return map[string]any{
+		"policy":  p.Policy,
+		"score":   p.Score,
+		"reasons": p.Reasons,
+	}
+}
 
-			history2 = append(history2, llm.Message{Role: "assistant", Content: reply})
-			history1 = append(history1, llm.Message{
-				Role:    "user",
-				Content: fmt.Sprintf("%s says: %s", a2.Name, reply),
-			})
-		}
+// logThinkError логирует ошибку LLM с именем говорящего.
+// Разомкнутый circuit breaker — штатная ситуация: тик просто пропускается.
+func (o *Orchestrator) logThinkError(tick int64, speaker string, err error) {
+	switch {
+	case errors.Is(err, llm.ErrCircuitOpen):
+		log.Printf("orchestrator tick %d: LLM unavailable (circuit open), skipping conversation", tick)
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("orchestrator tick %d: %s timed out (Ollama is too slow)", tick, speaker)
+	default:
+		log.Printf("orchestrator tick %d: %s error: %v", tick, speaker, err)
 	}
 }
 
-func (o *Orchestrator) injectHumanMessages(history *[]llm.Message, agentID string) {
+// injectHumanMessages добавляет в историю сообщения человека.
+// Возвращает true, если были инъекции — тогда ход отвечает человеку.
+func (o *Orchestrator) injectHumanMessages(history *[]llm.Message, agentID string) bool {
 	injections := o.hub.DrainInjections(agentID)
 	for _, inj := range injections {
+		// %q экранирует переводы строк: поддельная разметка ролей
+		// остаётся внутри цитаты человека.
 		*history = append(*history, llm.Message{
 			Role:    "user",
-			Content: fmt.Sprintf("[Human says to you]: %s", inj),
+			Content: fmt.Sprintf("[Human says to you]: %q", inj),
 		})
 	}
+	return len(injections) > 0
+}
+
+// turnContext помечает вызов LLM агентом, тиком и назначением:
+// планировщик обслуживает ответ человеку раньше обычной реплики,
+// учёт токенов относит вызов к агенту.
+func turnContext(ctx context.Context, human bool, agentID string, tick int64) context.Context {
+	purpose := llm.PurposeDialogue
+	if human {
+		purpose = llm.PurposeHumanReply
+	}
+	return llm.WithCallInfo(ctx, llm.CallInfo{Purpose: purpose, AgentID: agentID, Tick: tick})
 }
 
-func (o *Orchestrator) saveAndBroadcast(speaker, target storage.AgentRecord, reply string, tick int64) {
-	_ = o.repo.SaveConversationEvent(speaker.ID, target.ID, reply, tick)
-	o.hub.Broadcast(api.SSEEvent{
-		Type:    "conversation",
-		Speaker: speaker.Name,
-		Target:  target.Name,
-		Content: reply,
+// moderateReply проверяет реплику агента. Возвращает текст для публикации
+// (возможно, отредактированный) и false, если реплика задержана.
+func (o *Orchestrator) moderateReply(ctx context.Context, speaker storage.AgentRecord, reply string, tick int64) (string, bool) {
+	if o.moderator == nil {
+		return reply, true
+	}
+	d := o.moderator.Check(ctx, moderation.Item{
+		Kind:    moderation.KindOutput,
 		AgentID: speaker.ID,
 		Tick:    tick,
+		Text:    reply,
 	})
+	text := d.Text()
+	if text == "" {
+		log.Printf("orchestrator tick %d: %s reply held by moderation (%s)", tick, speaker.Name, d.Reason)
+		return "", false
+	}
+	return text, true
+}
+
+// publishReply публикует реплику. target — к кому обратились; nil —
+// собеседник в паре или все в группе.
+func (o *Orchestrator) publishReply(c *Conversation, speaker, target *Participant, reply string, tick int64) {
+	targetID, targetName := "", "everyone"
+	if target == nil && len(c.Participants) == 2 {
+		target = c.other(speaker)
+	}
+	if target != nil {
+		targetID, targetName = target.ID(), target.Name()
+	}
+
+	err := o.bus.Publish(WorldEvent{
+		Topic:          TopicInteraction,
+		Type:           "conversation",
+		Source:         speaker.ID(),
+		AffectedAgents: c.IDs(),
+		Payload: map[string]any{
+			"conversationId": c.ID,
+			"turn":           c.Turn,
+			"speakerId":      speaker.ID(),
+			"targetId":       targetID,
+			"speaker":        speaker.Name(),
+			"target":         targetName,
+			"content":        reply,
+			"participants":   c.IDs(),
+			"pairing":        pairingPayload(c.Pairing),
+		},
+		Tick: tick,
+	})
+	if err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
+}
+
+// publishMembership публикует приход или уход участника разговора.
+func (o *Orchestrator) publishMembership(c *Conversation, eventType string, who matchAgent, tick int64, message string) {
+	affected := c.IDs()
+	if !c.has(who.rec.ID) {
+		affected = append(affected, who.rec.ID)
+	}
+	err := o.bus.Publish(WorldEvent{
+		Topic:          TopicInteraction,
+		Type:           eventType,
+		Source:         who.rec.ID,
+		AffectedAgents: affected,
+		Payload: map[string]any{
+			"conversationId": c.ID,
+			"agentId":        who.rec.ID,
+			"participants":   c.IDs(),
+			"message":        message,
+		},
+		Tick: tick,
+	})
+	if err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+	}
 }
 
 func parsePersonality(raw string) agent.Personality {
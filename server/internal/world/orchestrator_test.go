@@ -0,0 +1,283 @@
+package world
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/api"
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
+
+// fixedPairs — политика для тестов: одна пара из двух первых агентов по id.
+type fixedPairs struct{}
+
+func (fixedPairs) Name() string { return "fixed" }
+
+func (fixedPairs) Pairs(mc MatchContext, max int) []Pairing {
+	agents := append([]storage.AgentRecord(nil), mc.Agents...)
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return []Pairing{{Initiator: agents[0], Partner: agents[1], Policy: "fixed", Score: 1}}
+}
+
+// scriptedDialogue отвечает репликами lines по очереди, а на просьбу
+// о пересказе — разметкой SUMMARY/FEELING/SHIFT/... .
+func scriptedDialogue(lines ...string) *llm.ScriptedResponder {
+	var mu sync.Mutex
+	next := 0
+	return &llm.ScriptedResponder{Respond: func(req llm.CompletionRequest) string {
+		if last := req.Messages[len(req.Messages)-1].Content; strings.Contains(last, "SUMMARY:") {
+			return "SUMMARY: We talked about the harvest.\nFEELING: calm\nSHIFT: 0.2\nWARMTH: 0.8\nCONFLICT: 0\nHELPFUL: 0.6"
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		line := lines[next%len(lines)]
+		next++
+		return line
+	}}
+}
+
+// newTestWorld — мигрированная база с двумя агентами и оркестратор
+// поверх client.
+func newTestWorld(t *testing.T, client agent.LLMClient) (*storage.Repository, *Orchestrator) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "society.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo := storage.NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	// Без карты никто не ходит: пара всегда в одном месте.
+	if _, err := db.Exec(`DELETE FROM zone_links; DELETE FROM zones`); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, a := range []struct {
+		id, name string
+		p        agent.Personality
+	}{
+		{"a", "Alice", agent.Personality{Openness: 0.6, Extraversion: 0.7, Agreeableness: 0.8}},
+		{"b", "Bob", agent.Personality{Openness: 0.4, Extraversion: 0.3, Agreeableness: 0.6}},
+	} {
+		p, _ := json.Marshal(a.p)
+		rec := storage.AgentRecord{ID: a.id, Name: a.name, Personality: string(p), State: "idle", IsActive: true, CreatedAt: created}
+		if err := repo.CreateAgent(rec); err != nil {
+			t.Fatalf("CreateAgent: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	bus := NewEventBus(repo, 64)
+	bus.Start(ctx)
+
+	o := NewOrchestrator(repo, client, api.NewHub(), bus)
+	o.UsePartnerPolicy(fixedPairs{})
+	o.UseGroupSize(2)
+	o.UseConversationLength(ConversationLength{Min: 2, Max: 6})
+	return repo, o
+}
+
+// runConversationTick проводит один тик и возвращает его разговор и реплики.
+func runConversationTick(t *testing.T, repo *storage.Repository, o *Orchestrator) (storage.ConversationRecord, []storage.ConversationTurnRecord) {
+	t.Helper()
+	o.runTick(context.Background(), TickInfo{Tick: 1, SimTime: time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)})
+
+	convs, err := repo.ListConversations(storage.ConversationFilter{})
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("ListConversations = %d conversations, %v; want 1", len(convs), err)
+	}
+	turns, err := repo.GetConversationTurns(convs[0].ID)
+	if err != nil {
+		t.Fatalf("GetConversationTurns: %v", err)
+	}
+	return convs[0], turns
+}
+
+// TestConversationReplay записывает разговор на кассету и воспроизводит
+// его на чистой базе: те же запросы к LLM, те же реплики, то же завершение.
+func TestConversationReplay(t *testing.T) {
+	lines := []string{
+		"Hi Bob! Are you coming to the harvest festival?",
+		"Of course, Alice. I'm baking bread for it.",
+		"Lovely. I have to go now, see you there!",
+	}
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := llm.NewRecordReplayClient(cassette, llm.CassetteRecord, scriptedDialogue(lines...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, o := newTestWorld(t, recorder)
+	recorded, recordedTurns := runConversationTick(t, repo, o)
+
+	if got := recorded.EndReason.String; got != string(EndFarewell) {
+		t.Errorf("end reason = %q, want %q", got, EndFarewell)
+	}
+	if len(recordedTurns) != len(lines) {
+		t.Fatalf("turns = %d, want %d", len(recordedTurns), len(lines))
+	}
+	for i, turn := range recordedTurns {
+		wantSpeaker := []string{"a", "b"}[i%2]
+		if turn.SpeakerID != wantSpeaker || turn.Content != lines[i] {
+			t.Errorf("turn %d = %s: %q, want %s: %q", i, turn.SpeakerID, turn.Content, wantSpeaker, lines[i])
+		}
+	}
+
+	if !recorded.Summary.Valid || !strings.Contains(recorded.Summary.String, "harvest") {
+		t.Errorf("summary = %q, want both perspectives", recorded.Summary.String)
+	}
+	rel, err := repo.GetRelationship("a", "b")
+	if err != nil || rel == nil || rel.Affection <= 0 {
+		t.Errorf("relationship a → b = %+v, %v; want warmer after a friendly talk", rel, err)
+	}
+
+	player, err := llm.NewRecordReplayClient(cassette, llm.CassetteReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, o = newTestWorld(t, player)
+	replayed, replayedTurns := runConversationTick(t, repo, o)
+
+	if unmatched := player.Unmatched(); len(unmatched) > 0 {
+		t.Fatalf("replay sent %d requests missing from the cassette", len(unmatched))
+	}
+	if replayed.EndReason != recorded.EndReason || replayed.Summary != recorded.Summary {
+		t.Errorf("replayed conversation = %s / %q, want %s / %q",
+			replayed.EndReason.String, replayed.Summary.String, recorded.EndReason.String, recorded.Summary.String)
+	}
+	if len(replayedTurns) != len(recordedTurns) {
+		t.Fatalf("replayed turns = %d, want %d", len(replayedTurns), len(recordedTurns))
+	}
+	for i := range recordedTurns {
+		if replayedTurns[i].SpeakerID != recordedTurns[i].SpeakerID || replayedTurns[i].Content != recordedTurns[i].Content {
+			t.Errorf("replayed turn %d = %q, want %q", i, replayedTurns[i].Content, recordedTurns[i].Content)
+		}
+	}
+}
+
+// TestTickEventsAreLive проверяет, что реплики уходят подписчикам
+// во время тика, а часы фиксируются в его конце.
+func TestTickEventsAreLive(t *testing.T) {
+	lines := scriptedDialogue("Hi Bob!", "Hello, Alice.", "Goodbye, see you!")
+	var events <-chan WorldEvent
+	asked, deliveredBeforeReply := 0, false
+	client := &llm.ScriptedResponder{Respond: func(req llm.CompletionRequest) string {
+		if !strings.Contains(req.Messages[len(req.Messages)-1].Content, "SUMMARY:") {
+			asked++
+		}
+		// Ко второй реплике первая уже разослана.
+		if asked == 2 {
+			timeout := time.After(2 * time.Second)
+			for !deliveredBeforeReply {
+				select {
+				case e := <-events:
+					deliveredBeforeReply = e.Type == "conversation"
+				case <-timeout:
+					return lines.Respond(req)
+				}
+			}
+		}
+		return lines.Respond(req)
+	}}
+	repo, o := newTestWorld(t, client)
+	events = o.bus.Subscribe(TopicInteraction, "a", nil)
+
+	simTime := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	o.runTick(context.Background(), TickInfo{Tick: 1, SimTime: simTime})
+
+	if !deliveredBeforeReply {
+		t.Error("first line was not delivered before the second was asked for")
+	}
+	if tick, got, err := repo.LoadClock(); err != nil || tick != 1 || !got.Equal(simTime) {
+		t.Errorf("clock = %d, %v, %v; want 1, %v", tick, got, err, simTime)
+	}
+}
+
+func TestOrchestratorSeed(t *testing.T) {
+	seeded := &Orchestrator{}
+	seeded.UseSeed(42)
+	draw := func(o *Orchestrator, tick int64, scope string) int64 { return o.newRand(tick, scope).Int63() }
+
+	if draw(seeded, 3, "pairs") != draw(seeded, 3, "pairs") {
+		t.Error("same seed, tick and scope gave different draws")
+	}
+	if draw(seeded, 3, "pairs") == draw(seeded, 4, "pairs") || draw(seeded, 3, "pairs") == draw(seeded, 3, "a") {
+		t.Error("different ticks or scopes share a generator")
+	}
+}
+
+// TestConversationUsesStoredMood проверяет, что в промпт реплики
+// попадает сохранённое настроение агента.
+func TestConversationUsesStoredMood(t *testing.T) {
+	lines := scriptedDialogue("Hi Bob!", "Hello, Alice.", "Goodbye, see you!")
+	var mu sync.Mutex
+	var prompts []string
+	client := &llm.ScriptedResponder{Respond: func(req llm.CompletionRequest) string {
+		mu.Lock()
+		prompts = append(prompts, req.SystemPrompt)
+		mu.Unlock()
+		return lines.Respond(req)
+	}}
+	repo, o := newTestWorld(t, client)
+	if _, err := repo.DB.Exec(`UPDATE agents SET mood_state = '{"label":"anxious","pad":{"pleasure":-0.2,"arousal":0.6}}' WHERE id = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+	runConversationTick(t, repo, o)
+
+	if len(prompts) == 0 || !strings.Contains(prompts[0], "настроение: anxious") {
+		t.Errorf("first prompt does not carry the stored mood: %q", prompts)
+	}
+}
+
+func TestLeaverStaysBusyUntilConversationEnds(t *testing.T) {
+	_, o := newTestWorld(t, scriptedDialogue("Hi"))
+	c := &Conversation{}
+	for _, id := range []string{"a", "b", "c"} {
+		p := testParticipant(id, 0.5, 0.5)
+		c.Participants = append(c.Participants, p)
+		c.Members = append(c.Members, p)
+	}
+	o.locks.TryLock(c.IDs()...)
+
+	o.leave(c, c.Participants[2], 1)
+	if !o.locks.IsBusy("c") {
+		t.Error("leaver freed before the conversation's summaries and relationships were written")
+	}
+	if ids := c.IDs(); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("participants after leave = %v", ids)
+	}
+}
+
+func TestSinceMet(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "только что"},
+		{3 * time.Hour, "недавно, сегодня"},
+		{3 * 24 * time.Hour, "несколько дней назад"},
+		{20 * 24 * time.Hour, "несколько недель назад"},
+		{365 * 24 * time.Hour, "давно"},
+	}
+	for _, tt := range tests {
+		if got := sinceMet(tt.d); got != tt.want {
+			t.Errorf("sinceMet(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package world — параллельные разговоры.
+//
+// На тике может идти несколько разговоров непересекающихся пар.
+// Их выполняет пул из workers горутин (по умолчанию — LLM_MAX_INFLIGHT:
+// больше одновременных разговоров всё равно ждали бы в очереди
+// планировщика LLM). agentLocks гарантирует, что агент не участвует
+// в двух разговорах сразу.
+
+package world
+
+import (
+	"sync"
+)
+
+// agentLocks — множество агентов, занятых разговором.
+type agentLocks struct {
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+func newAgentLocks() *agentLocks {
+	return &agentLocks{busy: make(map[string]bool)}
+}
+
+// TryLock занимает всех агентов ids или ни одного. false — кто-то уже занят.
+func (l *agentLocks) TryLock(ids ...string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		if l.busy[id] {
+			return false
+		}
+	}
+	for _, id := range ids {
+		l.busy[id] = true
+	}
+	return true
+}
+
+// Unlock освобождает агентов ids.
+func (l *agentLocks) Unlock(ids ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		delete(l.busy, id)
+	}
+}
+
+// IsBusy — занят ли агент разговором.
+func (l *agentLocks) IsBusy(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.busy[id]
+}
+
+// workerPool выполняет задачи не более чем в size горутинах.
+type workerPool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &workerPool{sem: make(chan struct{}, size)}
+}
+
+// Go запускает fn, когда освободится воркер. Не блокирует вызывающего.
+func (p *workerPool) Go(fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.sem <- struct{}{}
+		defer func() { <-p.sem }()
+		fn()
+	}()
+}
+
+// Wait ждёт завершения всех задач.
+func (p *workerPool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,183 @@
+// Package world — динамика отношений.
+//
+// Отношения направленные: после разговора каждый участник по своей же
+// оценке (теплота, польза, конфликт и сдвиг настроения из
+// Brain.Summarize) меняет своё доверие, симпатию и уважение к остальным.
+// Алиса может восхищаться Бобом, пока Боб на неё обижен. Тип связи
+// меняется по силе (среднему измерений) с гистерезисом — порог входа выше
+// порога выхода, поэтому связь не мигает между типами от разговора к
+// разговору:
+//
+//	neutral → friend  при strength ≥ 0.35, обратно при ≤ 0.20
+//	friend  → close   при strength ≥ 0.70, обратно при ≤ 0.55
+//	neutral → rival   при strength ≤ -0.35, обратно при ≥ -0.20
+//
+// Остальные типы (romantic, mentor, family... — их задаёт оператор) не
+// меняются. Каждый переход публикуется событием топика "relationship".
+//
+// Тип, измерения и заметка оператора попадают в промпт агента в начале
+// разговора (partnerContext), поэтому правка через POST /relationships
+// сказывается на следующей же встрече пары.
+
+package world
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+)
+
+// Типы связей, которыми управляет динамика.
+const (
+	RelNeutral = storage.RelationshipNeutral
+	RelFriend  = "friend"
+	RelClose   = "close"
+	RelRival   = "rival"
+)
+
+// relTransition — порог перехода между типами.
+type relTransition struct {
+	from, to  string
+	threshold float64
+	up        bool // true — переход при strength ≥ threshold, иначе при ≤
+}
+
+var relTransitions = []relTransition{
+	{RelNeutral, RelFriend, 0.35, true},
+	{RelFriend, RelClose, 0.70, true},
+	{RelClose, RelFriend, 0.55, false},
+	{RelFriend, RelNeutral, 0.20, false},
+	{RelNeutral, RelRival, -0.35, false},
+	{RelRival, RelNeutral, -0.20, true},
+}
+
+// nextRelType возвращает тип связи после изменения силы. Переходы
+// применяются цепочкой: резкое падение ведёт friend → neutral → rival.
+func nextRelType(current string, strength float64) string {
+	for changed := true; changed; {
+		changed = false
+		for _, t := range relTransitions {
+			if t.from != current {
+				continue
+			}
+			if (t.up && strength >= t.threshold) || (!t.up && strength <= t.threshold) {
+				current, changed = t.to, true
+				break
+			}
+		}
+	}
+	return current
+}
+
+// Вклад оценок разговора в измерения отношения.
+const (
+	relWarmthAffection   = 0.15 // теплота → симпатия
+	relShiftAffection    = 0.05 // настроение после разговора → симпатия
+	relHelpfulTrust      = 0.10 // польза → доверие
+	relConflictTrust     = 0.20 // конфликт подрывает доверие
+	relConflictAffection = 0.10 // ...и симпатию
+	relHelpfulRespect    = 0.12 // польза → уважение
+	relConflictRespect   = 0.05
+	relFamiliarity       = 0.02 // любой разговор немного сближает
+	relFamiliarityTrust  = 0.01
+)
+
+// relationshipDelta — изменение отношения участника к собеседнику по его
+// оценке разговора.
+func relationshipDelta(s agent.ConversationSummary) storage.RelationshipDelta {
+	d := storage.RelationshipDelta{
+		Trust:     relFamiliarityTrust,
+		Affection: relFamiliarity + relShiftAffection*s.Shift,
+	}
+	if s.Judged {
+		d.Affection += relWarmthAffection*s.Warmth - relConflictAffection*s.Conflict
+		d.Trust += relHelpfulTrust*s.Helpfulness - relConflictTrust*s.Conflict
+		d.Respect += relHelpfulRespect*s.Helpfulness - relConflictRespect*s.Conflict
+	}
+	return d
+}
+
+// updateRelationships меняет отношения каждого участника разговора
+// к остальным по его оценке из пересказов sums. Участник без пересказа
+// только привыкает к собеседникам.
+func (o *Orchestrator) updateRelationships(c *Conversation, sums map[string]agent.ConversationSummary, tick int64) {
+	if len(c.Transcript) < 2 {
+		return // Разговора не было
+	}
+	now := time.Now()
+	for _, a := range c.Members {
+		delta := storage.RelationshipDelta{Trust: relFamiliarityTrust, Affection: relFamiliarity}
+		if s, ok := sums[a.ID()]; ok {
+			delta = relationshipDelta(s)
+		}
+		for _, b := range c.Members {
+			if b != a {
+				o.adjustRelationship(c, a, b, delta, now, tick)
+			}
+		}
+	}
+}
+
+// adjustRelationship применяет delta к отношению a к b и публикует смену типа.
+func (o *Orchestrator) adjustRelationship(c *Conversation, a, b *Participant, delta storage.RelationshipDelta, now time.Time, tick int64) {
+	before, after, err := o.repo.ApplyRelationshipDelta(a.ID(), b.ID(), delta, now)
+	if err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+		return
+	}
+
+	next := nextRelType(after.Type, after.Strength)
+	if next == after.Type {
+		return
+	}
+	if err := o.repo.SetRelationshipType(after.ID, next); err != nil {
+		log.Printf("orchestrator tick %d: %v", tick, err)
+		return
+	}
+
+	prevStrength := 0.0
+	if before != nil {
+		prevStrength = before.Strength
+	}
+	message := relTransitionMessage(a.Name(), b.Name(), after.Type, next)
+	log.Printf("orchestrator tick %d: %s (strength %.2f → %.2f)", tick, message, prevStrength, after.Strength)
+	o.bus.Publish(WorldEvent{
+		Topic:          TopicRelationship,
+		Type:           "relationship_changed",
+		Source:         "system",
+		AffectedAgents: []string{a.ID(), b.ID()},
+		Payload: map[string]any{
+			"relationshipId": after.ID,
+			"conversationId": c.ID,
+			"agent1":         a.ID(), // кто относится
+			"agent2":         b.ID(), // к кому
+			"from":           after.Type,
+			"to":             next,
+			"strength":       after.Strength,
+			"trust":          after.Trust,
+			"affection":      after.Affection,
+			"respect":        after.Respect,
+			"message":        message,
+		},
+		Tick: tick,
+	})
+}
+
+// relTransitionMessage — текст ленты о смене отношения a к b.
+func relTransitionMessage(a, b, from, to string) string {
+	switch {
+	case to == RelFriend && from == RelNeutral:
+		return fmt.Sprintf("%s now considers %s a friend", a, b)
+	case to == RelClose:
+		return fmt.Sprintf("%s now feels close to %s", a, b)
+	case to == RelRival:
+		return fmt.Sprintf("%s now sees %s as a rival", a, b)
+	case from == RelRival:
+		return fmt.Sprintf("%s no longer holds a grudge against %s", a, b)
+	default:
+		return fmt.Sprintf("%s drifted apart from %s (%s → %s)", a, b, from, to)
+	}
+}
@@ -0,0 +1,31 @@
+package world
+
+import "testing"
+
+func TestNextRelType(t *testing.T) {
+	tests := []struct {
+		current  string
+		strength float64
+		want     string
+	}{
+		{RelNeutral, 0.30, RelNeutral},
+		{RelNeutral, 0.35, RelFriend},
+		{RelNeutral, 0.80, RelClose}, // neutral → friend → close
+		{RelFriend, 0.25, RelFriend}, // ниже порога входа, выше порога выхода
+		{RelFriend, 0.20, RelNeutral},
+		{RelClose, 0.60, RelClose},
+		{RelClose, 0.50, RelFriend},
+		{RelClose, 0.10, RelNeutral},
+		{RelFriend, -0.50, RelRival}, // friend → neutral → rival
+		{RelNeutral, -0.30, RelNeutral},
+		{RelRival, -0.25, RelRival},
+		{RelRival, -0.20, RelNeutral},
+		{RelRival, 0.50, RelFriend},
+		{"romantic", -0.90, "romantic"},
+	}
+	for _, tt := range tests {
+		if got := nextRelType(tt.current, tt.strength); got != tt.want {
+			t.Errorf("nextRelType(%s, %.2f) = %s, want %s", tt.current, tt.strength, got, tt.want)
+		}
+	}
+}
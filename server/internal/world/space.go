@@ -0,0 +1,231 @@
+// Package world — пространство: зоны, перемещения и близость.
+//
+// Город — граф зон со смежностью (storage.ListZones). Каждый тик, до
+// выбора пар, свободные агенты решают, не перейти ли в соседнюю зону
+// (действие agent.ActionExplore): цель вроде "Explore the eastern zone"
+// ведёт к упомянутой зоне кратчайшим путём, одиноких общительных агентов
+// тянет туда, где больше людей, любопытных — просто пройтись. Рядом
+// (и значит могут поговорить или присоединиться к разговору) только
+// агенты из одной зоны. Каждый переход публикуется событием топика
+// "movement".
+
+package world
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+)
+
+// worldMap — зоны города по id.
+type worldMap map[string]storage.ZoneRecord
+
+// loadMap читает карту из БД.
+func (o *Orchestrator) loadMap() (worldMap, error) {
+	zones, err := o.repo.ListZones()
+	if err != nil {
+		return nil, err
+	}
+	m := make(worldMap, len(zones))
+	for _, z := range zones {
+		m[z.ID] = z
+	}
+	return m, nil
+}
+
+// name — имя зоны или её id, если зона неизвестна.
+func (m worldMap) name(id string) string {
+	if z, ok := m[id]; ok {
+		return z.Name
+	}
+	return id
+}
+
+// nextStep — первая зона на кратчайшем пути from → to. Пусто — пути нет
+// или агент уже на месте.
+func (m worldMap) nextStep(from, to string) string {
+	if from == to {
+		return ""
+	}
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range m[cur].Neighbors {
+			if _, seen := prev[n]; seen {
+				continue
+			}
+			prev[n] = cur
+			if n == to {
+				for prev[n] != from {
+					n = prev[n]
+				}
+				return n
+			}
+			queue = append(queue, n)
+		}
+	}
+	return ""
+}
+
+// Общие слова мест: «town» в «Explore the town» — не про Town Square.
+var genericPlaceWords = map[string]bool{
+	"town": true, "city": true, "zone": true, "area": true, "place": true,
+	"город": true, "зона": true, "место": true,
+}
+
+// zoneIn — зона, упомянутая в тексте цели. Лучше всего совпадение с id,
+// затем с полным именем, затем с самым длинным словом имени (длиннее
+// трёх букв, не из genericPlaceWords). Зоны перебираются по id, так что
+// при равенстве выбор всегда один. Пусто — цель не про место.
+func (m worldMap) zoneIn(desc string) string {
+	text := words(desc)
+	set := make(map[string]bool, len(text))
+	for _, w := range text {
+		set[w] = true
+	}
+
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	best, bestScore := "", 0
+	for _, id := range ids {
+		score := 0
+		name := words(m[id].Name)
+		switch {
+		case set[id]:
+			score = 3000 + len(id)
+		case len(name) > 1 && hasPhrase(text, name):
+			score = 2000 + len(name)
+		default:
+			for _, w := range name {
+				if n := len([]rune(w)); n > 3 && !genericPlaceWords[w] && set[w] {
+					score = max(score, n)
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best
+}
+
+// location — зона агента.
+func location(rec storage.AgentRecord) string {
+	if rec.Location.Valid && rec.Location.String != "" {
+		return rec.Location.String
+	}
+	return storage.DefaultZone
+}
+
+// move — решение агента о переходе.
+type move struct {
+	to     string
+	reason string
+}
+
+// chooseMove решает, куда (и идти ли) агенту a из его зоны. crowd —
+// сколько свободных агентов в каждой зоне.
+func chooseMove(m worldMap, a matchAgent, crowd map[string]int, r *rand.Rand) (move, bool) {
+	from := location(a.rec)
+	neighbors := m[from].Neighbors
+	if len(neighbors) == 0 {
+		return move{}, false
+	}
+
+	// Цель, связанная с местом, ведёт кратчайшим путём
+	for _, g := range a.goals {
+		if g.IsCompleted {
+			continue
+		}
+		target := m.zoneIn(g.Description)
+		if step := m.nextStep(from, target); step != "" && r.Float64() < 0.4+0.5*goalWeight(g) {
+			return move{step, fmt.Sprintf("goal: %q", g.Description)}, true
+		}
+	}
+
+	alone := crowd[from] <= 1
+	chance := 0.05 + 0.2*a.personality.Openness
+	if alone {
+		chance += 0.3 * min(a.drive(), 1)
+	}
+	if r.Float64() >= chance {
+		return move{}, false
+	}
+
+	weights := make([]float64, len(neighbors))
+	for i, n := range neighbors {
+		weights[i] = 1 + 2*a.personality.Extraversion*float64(crowd[n])
+	}
+	to := neighbors[weightedIndex(r, weights)]
+	reason := fmt.Sprintf("curious (openness %.2f)", a.personality.Openness)
+	if alone {
+		reason = fmt.Sprintf("alone at %s, looking for company", m.name(from))
+	}
+	return move{to, reason}, true
+}
+
+// moveAgents даёт каждому свободному агенту шанс перейти в соседнюю
+// зону и возвращает агентов с обновлённым положением.
+func (o *Orchestrator) moveAgents(agents []storage.AgentRecord, r *rand.Rand, tick int64) []storage.AgentRecord {
+	m, err := o.loadMap()
+	if err != nil || len(m) == 0 {
+		if err != nil {
+			log.Printf("orchestrator tick %d: map: %v", tick, err)
+		}
+		return agents
+	}
+
+	crowd := make(map[string]int)
+	for _, a := range agents {
+		crowd[location(a)]++
+	}
+
+	for i, a := range agents {
+		mv, ok := chooseMove(m, newMatchAgent(a), crowd, r)
+		if !ok {
+			continue
+		}
+		from := location(a)
+		if err := o.repo.SetAgentLocation(a.ID, mv.to); err != nil {
+			log.Printf("orchestrator tick %d: %v", tick, err)
+			continue
+		}
+		crowd[from]--
+		crowd[mv.to]++
+		agents[i].Location.String, agents[i].Location.Valid = mv.to, true
+
+		message := fmt.Sprintf("%s walks from %s to %s", a.Name, m.name(from), m.name(mv.to))
+		log.Printf("orchestrator tick %d: %s (%s)", tick, message, mv.reason)
+		o.bus.Publish(WorldEvent{
+			Topic:          TopicMovement,
+			Type:           "agent_moved",
+			Source:         a.ID,
+			AffectedAgents: []string{a.ID},
+			Payload: map[string]any{
+				"agentId": a.ID,
+				"action":  string(agent.ActionExplore),
+				"from":    from,
+				"to":      mv.to,
+				"reason":  mv.reason,
+				"message": message,
+			},
+			Tick: tick,
+		})
+	}
+	return agents
+}
+
+// nearby — находятся ли агенты в одной зоне.
+func nearby(a, b storage.AgentRecord) bool {
+	return location(a) == location(b)
+}
@@ -0,0 +1,64 @@
+package world
+
+import (
+	"testing"
+
+	"milk/server/internal/storage"
+)
+
+// testMap — square — market — east, square — park; плюс два сада без связей.
+func testMap() worldMap {
+	m := worldMap{}
+	for _, z := range []storage.ZoneRecord{
+		{ID: "square", Name: "Town Square", Neighbors: []string{"market", "park"}},
+		{ID: "market", Name: "Old Market", Neighbors: []string{"square", "east"}},
+		{ID: "east", Name: "East District", Neighbors: []string{"market"}},
+		{ID: "park", Name: "Central Park", Neighbors: []string{"square"}},
+		{ID: "rose_garden", Name: "Rose Garden"},
+		{ID: "herb_garden", Name: "Herb Garden"},
+	} {
+		m[z.ID] = z
+	}
+	return m
+}
+
+func TestWorldMapNextStep(t *testing.T) {
+	m := testMap()
+	tests := []struct {
+		from, to, want string
+	}{
+		{"square", "park", "park"},
+		{"square", "east", "market"},
+		{"east", "park", "market"},
+		{"square", "square", ""},
+		{"square", "rose_garden", ""},
+		{"square", "nowhere", ""},
+	}
+	for _, tt := range tests {
+		if got := m.nextStep(tt.from, tt.to); got != tt.want {
+			t.Errorf("nextStep(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWorldMapZoneIn(t *testing.T) {
+	m := testMap()
+	tests := []struct {
+		desc, want string
+	}{
+		{"Buy bread at the market", "market"},
+		{"Walk in the central park", "park"},
+		{"Meet friends in the town square", "square"},
+		{"Sell goods in the Old Market", "market"},
+		{"Explore the town", ""},
+		{"Make a new friend", ""},
+		{"Relax in a garden", "herb_garden"}, // равные совпадения — меньший id
+	}
+	for _, tt := range tests {
+		for range 20 { // порядок обхода map не должен влиять на выбор
+			if got := m.zoneIn(tt.desc); got != tt.want {
+				t.Fatalf("zoneIn(%q) = %q, want %q", tt.desc, got, tt.want)
+			}
+		}
+	}
+}
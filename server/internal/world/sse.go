@@ -0,0 +1,115 @@
+// Package world — мосты между EventBus и API.
+//
+// APIPublisher даёт HTTP-обработчикам публиковать события в шину,
+// EventReplay — досылать SSE-клиентам события из БД после переподключения.
+// StreamToHub наблюдает за всеми топиками шины и пересылает каждое событие
+// SSE-клиентам; hub раздаёт его по фильтрам ?topics и ?agents.
+
+package world
+
+import (
+	"context"
+	"encoding/json"
+
+	"milk/server/internal/api"
+	"milk/server/internal/storage"
+)
+
+// StreamToHub пересылает события шины в SSE hub до отмены ctx.
+func StreamToHub(ctx context.Context, bus *EventBus, hub *api.Hub) {
+	events := bus.Observe(TopicAll, "sse", nil)
+	go func() {
+		defer bus.Unsubscribe(TopicAll, "sse")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				hub.Broadcast(sseEvent(e))
+			}
+		}
+	}()
+}
+
+// APIPublisher возвращает api.PublishFunc, публикующий события API в шину
+// с источником "api".
+func APIPublisher(bus *EventBus) api.PublishFunc {
+	return func(topic, eventType string, affectedAgents []string, payload map[string]any) {
+		bus.Publish(WorldEvent{
+			Topic:          EventTopic(topic),
+			Type:           eventType,
+			Source:         "api",
+			AffectedAgents: affectedAgents,
+			Payload:        payload,
+		})
+	}
+}
+
+// EventReplay возвращает api.ReplaySource, досылающий SSE-клиентам
+// события из таблицы events.
+func EventReplay(repo *storage.Repository) api.ReplaySource {
+	return func(after int64, limit int) ([]api.SSEEvent, error) {
+		records, err := repo.GetEventsAfter(after, limit)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]api.SSEEvent, 0, len(records))
+		for _, rec := range records {
+			out = append(out, sseEvent(worldEvent(rec)))
+		}
+		return out, nil
+	}
+}
+
+// worldEvent восстанавливает событие шины из строки events.
+func worldEvent(rec storage.EventRecord) WorldEvent {
+	e := WorldEvent{
+		ID:        rec.ID,
+		Seq:       rec.Seq,
+		Topic:     EventTopic(rec.Topic),
+		Type:      rec.Type,
+		Source:    rec.Source,
+		Timestamp: rec.CreatedAt,
+		Tick:      rec.Tick.Int64,
+		Status:    rec.Status,
+	}
+	if rec.AffectedAgents.Valid {
+		json.Unmarshal([]byte(rec.AffectedAgents.String), &e.AffectedAgents)
+	}
+	if rec.Payload.Valid {
+		json.Unmarshal([]byte(rec.Payload.String), &e.Payload)
+	}
+	return e
+}
+
+// sseEvent переводит событие шины в формат SSE: топик, тип, payload
+// целиком, плюс поля реплики для диалогов и текст ленты для остальных.
+func sseEvent(e WorldEvent) api.SSEEvent {
+	str := func(key string) string {
+		v, _ := e.Payload[key].(string)
+		return v
+	}
+
+	msg := api.SSEEvent{
+		Seq:            e.Seq,
+		ID:             e.ID,
+		Topic:          string(e.Topic),
+		Type:           e.Type,
+		Content:        str("message"),
+		AgentID:        str("agentId"),
+		AffectedAgents: e.AffectedAgents,
+		Payload:        e.Payload,
+		Tick:           e.Tick,
+		ConversationID: str("conversationId"),
+	}
+	if e.Topic == TopicInteraction && e.Type == "conversation" {
+		msg.Speaker = str("speaker")
+		msg.Target = str("target")
+		msg.Content = str("content")
+		msg.AgentID = e.Source
+	}
+	return msg
+}
@@ -0,0 +1,202 @@
+// Package world — память о разговорах.
+//
+// После разговора каждый участник пересказывает его от своего лица
+// (Brain.Summarize). Пересказ сохраняется эпизодическим воспоминанием
+// с related_agents = остальные участники; важность растёт с силой
+// эмоционального сдвига. При следующей встрече с кем-то из них агент
+// вспоминает последние такие пересказы и свои выводы о собеседнике — вместе
+// с отношением к нему они попадают в системный промпт (agent.PartnerContext).
+
+package world
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"milk/server/internal/agent"
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
+
+// Сколько воспоминаний о каждом собеседнике поднимать при встрече:
+// общих эпизодов и выводов о нём.
+const (
+	recallLimit = 3
+	beliefLimit = 2
+)
+
+// summarize сохраняет пересказ разговора в память каждого участника
+// и краткое содержание — в conversations.summary. Возвращает пересказы
+// по id участника (для оценки отношений).
+func (o *Orchestrator) summarize(ctx context.Context, c *Conversation, tick int64) map[string]agent.ConversationSummary {
+	sums := make(map[string]agent.ConversationSummary)
+	if len(c.Transcript) < 2 || ctx.Err() != nil {
+		return sums // Нечего вспоминать или тик уже отменён
+	}
+
+	transcript := make([]string, 0, len(c.Transcript))
+	for _, line := range c.Transcript {
+		transcript = append(transcript, fmt.Sprintf("%s: %s", line.Speaker, line.Content))
+	}
+
+	var perspectives []string
+	for _, p := range c.Members {
+		var names, ids []string
+		for _, q := range c.Members {
+			if q != p {
+				names = append(names, q.Name())
+				ids = append(ids, q.ID())
+			}
+		}
+
+		callCtx := llm.WithCallInfo(ctx, llm.CallInfo{Purpose: llm.PurposeSummarization, AgentID: p.ID(), Tick: tick})
+		sum, err := p.brain.Summarize(callCtx, o.llm, p.Name(), p.mood, p.goals, names, transcript)
+		if err != nil {
+			o.logThinkError(tick, p.Name(), err)
+			continue
+		}
+		sums[p.ID()] = sum
+		if sum.Text == "" {
+			continue
+		}
+
+		importance := math.Min(0.3+0.6*math.Abs(sum.Shift), 1)
+		related, _ := json.Marshal(ids)
+		meta, _ := json.Marshal(map[string]any{
+			"source":         "conversation",
+			"conversationId": c.ID,
+			"tick":           tick,
+			"shift":          sum.Shift,
+		})
+		rec := storage.MemoryRecord{
+			AgentID:       p.ID(),
+			Type:          "episodic",
+			Content:       sum.Text,
+			Importance:    importance,
+			TouchedTick:   sql.NullInt64{Int64: tick, Valid: true},
+			RelatedAgents: sql.NullString{String: string(related), Valid: true},
+			Metadata:      sql.NullString{String: string(meta), Valid: true},
+		}
+		if sum.Feeling != "" {
+			rec.EmotionalTag = sql.NullString{String: sum.Feeling, Valid: true}
+		}
+		if err := o.repo.SaveMemory(rec); err != nil {
+			log.Printf("orchestrator tick %d: %v", tick, err)
+			continue
+		}
+
+		perspectives = append(perspectives, fmt.Sprintf("%s: %s", p.Name(), sum.Text))
+		o.bus.Publish(WorldEvent{
+			Topic:          TopicMemory,
+			Type:           "conversation_summary",
+			Source:         p.ID(),
+			AffectedAgents: append([]string{p.ID()}, ids...),
+			Payload: map[string]any{
+				"conversationId": c.ID,
+				"agentId":        p.ID(),
+				"content":        sum.Text,
+				"feeling":        sum.Feeling,
+				"shift":          sum.Shift,
+				"importance":     importance,
+			},
+			Tick: tick,
+		})
+	}
+
+	if len(perspectives) > 0 {
+		if err := o.repo.SetConversationSummary(c.ID, strings.Join(perspectives, "\n")); err != nil {
+			log.Printf("orchestrator tick %d: %v", tick, err)
+		}
+	}
+	return sums
+}
+
+// remind собирает для p всё, что он знает об остальных участниках
+// разговора: это попадает в его системный промпт.
+func (o *Orchestrator) remind(c *Conversation, p *Participant, tick int64) {
+	p.partners = p.partners[:0]
+	for _, q := range c.Participants {
+		if q != p {
+			p.partners = append(p.partners, o.partnerContext(p, q, tick))
+		}
+	}
+}
+
+// partnerContext — что p знает о q: отношение к нему, как познакомились,
+// последние общие воспоминания и выводы о нём.
+func (o *Orchestrator) partnerContext(p, q *Participant, tick int64) agent.PartnerContext {
+	pc := agent.PartnerContext{ID: q.ID(), Name: q.Name()}
+
+	rel, err := o.repo.GetRelationship(p.ID(), q.ID())
+	if err != nil {
+		log.Printf("orchestrator: relationship for %s: %v", p.Name(), err)
+	}
+	if rel != nil {
+		pc.Type = rel.Type
+		pc.Strength, pc.Trust, pc.Affection, pc.Respect = rel.Strength, rel.Trust, rel.Affection, rel.Respect
+		pc.Note = storage.RelationshipNote(rel.Metadata)
+	}
+
+	first, err := o.repo.FirstConversationBetween(p.ID(), q.ID())
+	if err != nil {
+		log.Printf("orchestrator: first meeting for %s: %v", p.Name(), err)
+	}
+	if first != nil {
+		elapsed := time.Duration(tick-first.StartTick) * o.clock.TickDuration
+		pc.Met = "Вы познакомились " + sinceMet(elapsed)
+		switch {
+		case first.Topic.Valid:
+			pc.Met += fmt.Sprintf(", разговор шёл о «%s»", first.Topic.String)
+		case first.InitiatorID == p.ID():
+			pc.Met += ", ты сам заговорил первым"
+		case first.InitiatorID == q.ID():
+			pc.Met += fmt.Sprintf(", %s заговорил первым", q.Name())
+		}
+		pc.Met += "."
+	}
+
+	for _, m := range o.recall(p, "episodic", q, recallLimit, tick) {
+		line := m.Content
+		if m.EmotionalTag.Valid {
+			line += fmt.Sprintf(" (ты чувствовал: %s)", m.EmotionalTag.String)
+		}
+		pc.Memories = append(pc.Memories, line)
+	}
+	for _, m := range o.recall(p, "semantic", q, beliefLimit, tick) {
+		pc.Beliefs = append(pc.Beliefs, m.Content)
+	}
+	return pc
+}
+
+// sinceMet — давность знакомства словами по времени симуляции:
+// номер тика агенту ничего не говорит.
+func sinceMet(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "только что"
+	case d < 24*time.Hour:
+		return "недавно, сегодня"
+	case d < 7*24*time.Hour:
+		return "несколько дней назад"
+	case d < 60*24*time.Hour:
+		return "несколько недель назад"
+	default:
+		return "давно"
+	}
+}
+
+// recall поднимает воспоминания p типа memType, связанные с q,
+// и отмечает их вспомненными на тике tick.
+func (o *Orchestrator) recall(p *Participant, memType string, q *Participant, limit int, tick int64) []storage.MemoryRecord {
+	memories, err := o.repo.RecallMemoriesAbout(p.ID(), memType, []string{q.ID()}, limit, tick)
+	if err != nil {
+		log.Printf("orchestrator: recall for %s: %v", p.Name(), err)
+	}
+	return memories
+}
@@ -0,0 +1,191 @@
+// Package world — задачи на часах симуляции.
+//
+// ScheduleAt и ScheduleEvery привязывают колбэки к тикам, а не к
+// wall-clock: на паузе задачи не идут, при ускорении — идут чаще.
+// Задачи выполняются в отдельной горутине после отдачи тика оркестратору,
+// поэтому медленная рефлексия не задерживает часы. Паника задачи
+// перехватывается и попадает в её статистику, не роняя сервер.
+
+package world
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// doneOnceLimit — сколько выполненных разовых задач помнить для GET /world/tasks.
+const doneOnceLimit = 20
+
+// TaskStatus — снимок задачи для API.
+type TaskStatus struct {
+	Label string
+
+	// Kind — "recurring" или "once".
+	Kind string
+
+	// Interval — период в тиках (0 для разовых задач).
+	Interval int64
+
+	// LastRun — тик последнего запуска (0 = не запускалась).
+	LastRun int64
+
+	// NextRun — тик следующего запуска (0 = больше не запустится).
+	NextRun int64
+
+	// Running — выполняется прямо сейчас.
+	Running bool
+
+	Stats TaskStats
+}
+
+// ScheduleAt планирует fn на тик tick. Тик в прошлом — задача не выполнится.
+func (tm *TimeManager) ScheduleAt(tick int64, fn func(TickInfo)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.ScheduledOnce[tick] = append(tm.ScheduledOnce[tick], &OnceTask{
+		Tick:     tick,
+		Callback: fn,
+		Label:    fmt.Sprintf("once@%d", tick),
+	})
+}
+
+// ScheduleEvery планирует fn на каждый тик, кратный n.
+func (tm *TimeManager) ScheduleEvery(n int64, label string, fn func(TickInfo)) {
+	if n <= 0 {
+		n = 1
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.ScheduledRecurring = append(tm.ScheduledRecurring, &RecurringTask{
+		Interval: n,
+		Callback: fn,
+		Label:    label,
+	})
+}
+
+// Tasks возвращает снимок задач: повторяющиеся, ожидающие разовые
+// и последние выполненные разовые.
+func (tm *TimeManager) Tasks() []TaskStatus {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	out := make([]TaskStatus, 0, len(tm.ScheduledRecurring)+len(tm.ScheduledOnce)+len(tm.doneOnce))
+	for _, t := range tm.ScheduledRecurring {
+		out = append(out, TaskStatus{
+			Label:    t.Label,
+			Kind:     "recurring",
+			Interval: t.Interval,
+			LastRun:  t.LastRun,
+			NextRun:  (tm.CurrentTick/t.Interval + 1) * t.Interval,
+			Running:  t.running,
+			Stats:    t.Stats,
+		})
+	}
+
+	var pending []TaskStatus
+	for _, tasks := range tm.ScheduledOnce {
+		for _, t := range tasks {
+			pending = append(pending, TaskStatus{Label: t.Label, Kind: "once", NextRun: t.Tick})
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].NextRun < pending[j].NextRun })
+	out = append(out, pending...)
+
+	for _, t := range tm.doneOnce {
+		out = append(out, TaskStatus{
+			Label:   t.Label,
+			Kind:    "once",
+			LastRun: t.Tick,
+			Running: t.running,
+			Stats:   t.Stats,
+		})
+	}
+	return out
+}
+
+// dispatch запускает задачи, которым пора выполниться на тике info.
+func (tm *TimeManager) dispatch(info TickInfo) {
+	tm.mu.Lock()
+	var due []*RecurringTask
+	for _, t := range tm.ScheduledRecurring {
+		if info.Tick%t.Interval != 0 {
+			continue
+		}
+		if t.running {
+			t.Stats.Skipped++
+			log.Printf("tasks: %s still running, skipping tick %d", t.Label, info.Tick)
+			continue
+		}
+		t.running = true
+		t.LastRun = info.Tick
+		due = append(due, t)
+	}
+
+	// Разовые задачи на этот тик и на уже прошедшие: ScheduleAt мог быть
+	// вызван на тик, который часы успели миновать.
+	var once []*OnceTask
+	for tick, tasks := range tm.ScheduledOnce {
+		if tick > info.Tick {
+			continue
+		}
+		for _, t := range tasks {
+			t.running = true
+			once = append(once, t)
+		}
+		delete(tm.ScheduledOnce, tick)
+	}
+	tm.mu.Unlock()
+
+	if len(due) == 0 && len(once) == 0 {
+		return
+	}
+
+	go func() {
+		for _, t := range due {
+			tm.runTask(t.Label, info, t.Callback, &t.Stats, &t.running)
+		}
+		for _, t := range once {
+			tm.runTask(t.Label, info, t.Callback, &t.Stats, &t.running)
+			tm.mu.Lock()
+			tm.doneOnce = append(tm.doneOnce, t)
+			if len(tm.doneOnce) > doneOnceLimit {
+				tm.doneOnce = tm.doneOnce[len(tm.doneOnce)-doneOnceLimit:]
+			}
+			tm.mu.Unlock()
+		}
+	}()
+}
+
+// runTask выполняет колбэк с перехватом паники и обновляет статистику.
+// stats и running защищены tm.mu.
+func (tm *TimeManager) runTask(label string, info TickInfo, fn func(TickInfo), stats *TaskStats, running *bool) {
+	start := time.Now()
+	var failure string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				failure = fmt.Sprint(r)
+				log.Printf("tasks: %s panicked at tick %d: %v\n%s", label, info.Tick, r, debug.Stack())
+			}
+		}()
+		fn(info)
+	}()
+	d := time.Since(start)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	*running = false
+	stats.Runs++
+	stats.LastDuration = d
+	stats.TotalDuration += d
+	if d > stats.MaxDuration {
+		stats.MaxDuration = d
+	}
+	stats.LastError = failure
+	if failure != "" {
+		stats.Failures++
+	}
+}
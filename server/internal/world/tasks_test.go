@@ -0,0 +1,102 @@
+package world
+
+import (
+	"testing"
+	"time"
+)
+
+// waitTasks ждёт, пока ни одна задача не выполняется.
+func waitTasks(t *testing.T, tm *TimeManager) []TaskStatus {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		tasks := tm.Tasks()
+		running := false
+		for _, s := range tasks {
+			running = running || s.Running
+		}
+		if !running {
+			return tasks
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tasks still running: %+v", tasks)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduleAt(t *testing.T) {
+	tm := NewTimeManager(time.Second)
+	ran := make(chan int64, 4)
+	tm.ScheduleAt(3, func(info TickInfo) { ran <- info.Tick })
+	tm.ScheduleAt(1, func(info TickInfo) { ran <- info.Tick })
+
+	tm.dispatch(TickInfo{Tick: 2})
+	if got := <-ran; got != 2 {
+		t.Errorf("task for a missed tick ran at %d, want 2", got)
+	}
+
+	tm.dispatch(TickInfo{Tick: 3})
+	if got := <-ran; got != 3 {
+		t.Errorf("task ran at %d, want 3", got)
+	}
+	tm.dispatch(TickInfo{Tick: 4})
+	select {
+	case tick := <-ran:
+		t.Errorf("once task ran again at tick %d", tick)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Выполненная задача попадает в список после колбэка.
+	var tasks []TaskStatus
+	for deadline := time.Now().Add(time.Second); len(tasks) < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		tasks = waitTasks(t, tm)
+	}
+	if len(tasks) != 2 || tasks[0].Label != "once@1" || tasks[1].LastRun != 3 || tasks[1].Stats.Runs != 1 {
+		t.Errorf("done tasks = %+v", tasks)
+	}
+}
+
+func TestScheduleEveryIsolatesPanics(t *testing.T) {
+	tm := NewTimeManager(time.Second)
+	calls := 0
+	tm.ScheduleEvery(2, "boom", func(TickInfo) { panic("boom") })
+	tm.ScheduleEvery(2, "count", func(TickInfo) { calls++ })
+
+	for tick := int64(1); tick <= 4; tick++ {
+		tm.CurrentTick = tick
+		tm.dispatch(TickInfo{Tick: tick})
+		waitTasks(t, tm)
+	}
+
+	tasks := tm.Tasks()
+	boom, count := tasks[0], tasks[1]
+	if boom.Stats.Runs != 2 || boom.Stats.Failures != 2 || boom.Stats.LastError != "boom" || boom.LastRun != 4 {
+		t.Errorf("panicking task = %+v", boom)
+	}
+	// Паника одной задачи не мешает следующей в том же тике.
+	if calls != 2 || count.Stats.Failures != 0 || count.NextRun != 6 {
+		t.Errorf("calls = %d, task = %+v", calls, count)
+	}
+}
+
+func TestScheduleEverySkipsOverlappingRuns(t *testing.T) {
+	tm := NewTimeManager(time.Second)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	tm.ScheduleEvery(1, "slow", func(TickInfo) {
+		started <- struct{}{}
+		<-release
+	})
+
+	tm.dispatch(TickInfo{Tick: 1})
+	<-started
+	tm.dispatch(TickInfo{Tick: 2})
+	close(release)
+
+	tasks := waitTasks(t, tm)
+	if s := tasks[0].Stats; s.Runs != 1 || s.Skipped != 1 {
+		t.Errorf("stats = %+v, want 1 run and 1 skip", s)
+	}
+}
@@ -6,6 +6,8 @@
 package world
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
@@ -42,19 +44,31 @@ type TimeManager struct {
 	// simTime — текущее время симуляции (может отличаться от wall-clock).
 	SimTime time.Time
 
-	// ticker — системный тикер, генерирующий события с интервалом tickDuration/speed.
-	Ticker *time.Ticker
-
 	// scheduledOnce — колбэки, запланированные на конкретный тик.
-	// Ключ = номер тика, значение = список функций для вызова.
-	ScheduledOnce map[int64][]func()
+	// Ключ = номер тика, значение = список задач для вызова.
+	ScheduledOnce map[int64][]*OnceTask
 
 	// scheduledRecurring — повторяющиеся колбэки.
 	// Вызываются каждые N тиков (для рефлексии, статистики, случайных событий).
-	ScheduledRecurring []RecurringTask
+	ScheduledRecurring []*RecurringTask
+
+	// doneOnce — последние выполненные разовые задачи (для GET /world/tasks).
+	doneOnce []*OnceTask
 
 	// mu — мьютекс для потокобезопасного доступа.
 	mu sync.Mutex
+
+	// ticks — канал тиков для оркестратора (см. TickChannel).
+	ticks chan TickInfo
+
+	// wake — сигнал циклу Run об изменении паузы, скорости или шаге.
+	wake chan struct{}
+
+	// pendingSteps — запрошенные Step, ещё не выполненные.
+	pendingSteps int
+
+	// lastTick — wall-clock последнего тика, для пересчёта интервала.
+	lastTick time.Time
 }
 
 // TickInfo — информация о текущем тике, передаётся через TickChannel.
@@ -74,6 +88,8 @@ type TickInfo struct {
 }
 
 // RecurringTask — повторяющаяся задача, выполняемая каждые Interval тиков.
+// Запускается на тиках, кратных Interval, поэтому расписание не сбивается
+// после рестарта.
 type RecurringTask struct {
 	// Interval — через сколько тиков повторять (например, 100 = каждые 100 тиков).
 	Interval int64
@@ -82,8 +98,258 @@ type RecurringTask struct {
 	LastRun int64
 
 	// Callback — функция для вызова.
-	Callback func()
+	Callback func(TickInfo)
 
 	// Label — описание задачи для логирования ("memory_consolidation", "mood_decay").
 	Label string
+
+	// Stats — счётчики запусков и длительностей.
+	Stats TaskStats
+
+	running bool
+}
+
+// OnceTask — задача, запланированная на конкретный тик (ScheduleAt).
+type OnceTask struct {
+	// Tick — тик запуска.
+	Tick int64
+
+	// Callback — функция для вызова.
+	Callback func(TickInfo)
+
+	// Label — описание задачи ("once@<tick>", если не задано).
+	Label string
+
+	// Stats — итог запуска.
+	Stats TaskStats
+
+	running bool
+}
+
+// TaskStats — статистика выполнения задачи.
+type TaskStats struct {
+	// Runs — сколько раз задача выполнялась (включая упавшие).
+	Runs int64
+
+	// Failures — сколько запусков завершились паникой.
+	Failures int64
+
+	// Skipped — сколько запусков пропущено, потому что предыдущий ещё шёл.
+	Skipped int64
+
+	// LastDuration, TotalDuration, MaxDuration — wall-clock длительности.
+	LastDuration  time.Duration
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+
+	// LastError — текст последней паники. Пусто = последний запуск успешен.
+	LastError string
+}
+
+// Границы множителя скорости.
+const (
+	MinSpeed = 0.1
+	MaxSpeed = 10.0
+)
+
+var (
+	// ErrSpeedOutOfRange — множитель скорости вне диапазона MinSpeed–MaxSpeed.
+	ErrSpeedOutOfRange = errors.New("simulation speed must be between 0.1 and 10")
+
+	// ErrNotPaused — Step вызван на работающей симуляции.
+	ErrNotPaused = errors.New("simulation is running: pause it before stepping")
+)
+
+// TimeStatus — снимок состояния часов.
+type TimeStatus struct {
+	Tick     int64
+	SimTime  time.Time
+	Speed    float64
+	IsPaused bool
+}
+
+// NewTimeManager создаёт часы с базовой длительностью тика tickDuration
+// и скоростью 1.0. Тики не идут до вызова Run.
+func NewTimeManager(tickDuration time.Duration) *TimeManager {
+	return &TimeManager{
+		TickDuration:    tickDuration,
+		SpeedMultiplier: 1.0,
+		ScheduledOnce:   make(map[int64][]*OnceTask),
+		ticks:           make(chan TickInfo),
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// Restore продолжает сохранённую шкалу времени: следующий тик будет tick+1,
+// время симуляции — simTime+TickDuration. Нулевой simTime — начать с wall-clock.
+// Вызывается до Run.
+func (tm *TimeManager) Restore(tick int64, simTime time.Time) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.CurrentTick = tick
+	tm.SimTime = simTime
+}
+
+// TickChannel возвращает канал тиков. Читает оркестратор.
+func (tm *TimeManager) TickChannel() <-chan TickInfo {
+	return tm.ticks
+}
+
+// Run генерирует тики до отмены ctx. Первый тик — сразу после запуска,
+// если часы не на паузе.
+func (tm *TimeManager) Run(ctx context.Context) {
+	tm.mu.Lock()
+	tm.StartTime = time.Now()
+	if tm.SimTime.IsZero() {
+		tm.SimTime = tm.StartTime
+	}
+	paused := tm.IsPaused
+	tm.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	if paused {
+		timer.Stop()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-timer.C:
+			if !tm.emit(ctx) {
+				return
+			}
+			timer.Reset(tm.interval())
+
+		case <-tm.wake:
+			// Изменилось состояние: пауза, скорость или запрошен шаг.
+			timer.Stop()
+			tm.mu.Lock()
+			steps := tm.pendingSteps
+			tm.pendingSteps = 0
+			paused := tm.IsPaused
+			tm.mu.Unlock()
+
+			for ; steps > 0; steps-- {
+				if !tm.emit(ctx) {
+					return
+				}
+			}
+			if !paused {
+				timer.Reset(tm.untilNext())
+			}
+		}
+	}
+}
+
+// Pause останавливает генерацию тиков.
+func (tm *TimeManager) Pause() {
+	tm.mu.Lock()
+	tm.IsPaused = true
+	tm.mu.Unlock()
+	tm.notify()
+}
+
+// Resume возобновляет генерацию тиков.
+func (tm *TimeManager) Resume() {
+	tm.mu.Lock()
+	tm.IsPaused = false
+	tm.mu.Unlock()
+	tm.notify()
+}
+
+// Step выполняет один тик на паузе.
+func (tm *TimeManager) Step() error {
+	tm.mu.Lock()
+	if !tm.IsPaused {
+		tm.mu.Unlock()
+		return ErrNotPaused
+	}
+	tm.pendingSteps++
+	tm.mu.Unlock()
+	tm.notify()
+	return nil
+}
+
+// SetSpeed меняет множитель скорости. Интервал до следующего тика
+// пересчитывается сразу, а не после текущего.
+func (tm *TimeManager) SetSpeed(speed float64) error {
+	if speed < MinSpeed || speed > MaxSpeed {
+		return ErrSpeedOutOfRange
+	}
+	tm.mu.Lock()
+	tm.SpeedMultiplier = speed
+	tm.mu.Unlock()
+	tm.notify()
+	return nil
+}
+
+// Status возвращает снимок состояния часов.
+func (tm *TimeManager) Status() TimeStatus {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return TimeStatus{
+		Tick:     tm.CurrentTick,
+		SimTime:  tm.SimTime,
+		Speed:    tm.SpeedMultiplier,
+		IsPaused: tm.IsPaused,
+	}
+}
+
+// emit продвигает часы на один тик, отдаёт TickInfo и запускает задачи тика.
+// false — ctx отменён.
+func (tm *TimeManager) emit(ctx context.Context) bool {
+	tm.mu.Lock()
+	tm.CurrentTick++
+	tm.SimTime = tm.SimTime.Add(tm.TickDuration)
+	now := time.Now()
+	tm.lastTick = now
+	info := TickInfo{
+		Tick:     tm.CurrentTick,
+		SimTime:  tm.SimTime,
+		Delta:    tm.TickDuration,
+		WallTime: now,
+	}
+	tm.mu.Unlock()
+
+	select {
+	case tm.ticks <- info:
+		tm.dispatch(info)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// interval — реальный интервал между тиками: TickDuration / speed.
+func (tm *TimeManager) interval() time.Duration {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return time.Duration(float64(tm.TickDuration) / tm.SpeedMultiplier)
+}
+
+// untilNext — сколько ждать следующего тика с учётом уже прошедшего времени.
+// До первого тика — 0: первый тик идёт сразу.
+func (tm *TimeManager) untilNext() time.Duration {
+	tm.mu.Lock()
+	last := tm.lastTick
+	tm.mu.Unlock()
+	if last.IsZero() {
+		return 0
+	}
+	d := tm.interval() - time.Since(last)
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// notify будит цикл Run. Неблокирующий: одного сигнала достаточно.
+func (tm *TimeManager) notify() {
+	select {
+	case tm.wake <- struct{}{}:
+	default:
+	}
 }
@@ -0,0 +1,93 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// recvTick ждёт тик не дольше d. ok=false — тика не было.
+func recvTick(tm *TimeManager, d time.Duration) (TickInfo, bool) {
+	select {
+	case info := <-tm.TickChannel():
+		return info, true
+	case <-time.After(d):
+		return TickInfo{}, false
+	}
+}
+
+func TestTimeManagerStepWhilePaused(t *testing.T) {
+	tm := NewTimeManager(time.Minute)
+	tm.Pause()
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	tm.SimTime = start
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tm.Run(ctx)
+
+	if _, ok := recvTick(tm, 50*time.Millisecond); ok {
+		t.Fatal("tick emitted while paused")
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		if err := tm.Step(); err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+		info, ok := recvTick(tm, time.Second)
+		if !ok {
+			t.Fatalf("no tick after Step %d", want)
+		}
+		if info.Tick != want || !info.SimTime.Equal(start.Add(time.Duration(want)*time.Minute)) || info.Delta != time.Minute {
+			t.Errorf("step %d = %+v", want, info)
+		}
+	}
+	if _, ok := recvTick(tm, 50*time.Millisecond); ok {
+		t.Error("extra tick after steps")
+	}
+	if st := tm.Status(); st.Tick != 2 || !st.IsPaused {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+func TestTimeManagerPauseResume(t *testing.T) {
+	tm := NewTimeManager(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tm.Run(ctx)
+
+	if _, ok := recvTick(tm, time.Second); !ok {
+		t.Fatal("no first tick")
+	}
+	if err := tm.Step(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("Step while running = %v, want ErrNotPaused", err)
+	}
+
+	tm.Pause()
+	// Тик, выпущенный до паузы, может ещё ждать в канале.
+	recvTick(tm, 30*time.Millisecond)
+	if _, ok := recvTick(tm, 50*time.Millisecond); ok {
+		t.Fatal("ticks continue after Pause")
+	}
+
+	tm.Resume()
+	if _, ok := recvTick(tm, time.Second); !ok {
+		t.Error("no tick after Resume")
+	}
+}
+
+func TestTimeManagerSetSpeed(t *testing.T) {
+	tm := NewTimeManager(time.Second)
+	for _, speed := range []float64{0.05, 11} {
+		if err := tm.SetSpeed(speed); !errors.Is(err, ErrSpeedOutOfRange) {
+			t.Errorf("SetSpeed(%v) = %v, want ErrSpeedOutOfRange", speed, err)
+		}
+	}
+	if err := tm.SetSpeed(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := tm.interval(); got != 250*time.Millisecond {
+		t.Errorf("interval at 4x = %v, want 250ms", got)
+	}
+}
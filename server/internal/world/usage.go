@@ -0,0 +1,40 @@
+// Package world — сохранение учёта вызовов LLM.
+//
+// llm.UsageRecorder отдаёт CallRecord на каждый вызов модели;
+// LLMUsageSink пишет их в таблицу llm_calls для /agents/{id}/usage
+// и /world/usage.
+
+package world
+
+import (
+	"database/sql"
+	"log"
+
+	"milk/server/internal/storage"
+	"milk/server/pkg/llm"
+)
+
+// LLMUsageSink возвращает получатель llm.CallRecord, сохраняющий их в llm_calls.
+func LLMUsageSink(repo *storage.Repository) func(llm.CallRecord) {
+	return func(c llm.CallRecord) {
+		rec := storage.LLMCallRecord{
+			AgentID:          sql.NullString{String: c.AgentID, Valid: c.AgentID != ""},
+			Tick:             sql.NullInt64{Int64: c.Tick, Valid: c.Tick > 0},
+			Purpose:          string(c.Purpose),
+			Model:            c.Model,
+			Provider:         sql.NullString{String: c.Provider, Valid: c.Provider != ""},
+			RoutedModel:      sql.NullString{String: c.Route.Model, Valid: c.Route.Model != ""},
+			RouteRule:        sql.NullString{String: c.Route.Rule, Valid: c.Route.Rule != ""},
+			PromptTokens:     c.PromptTokens,
+			CompletionTokens: c.CompletionTokens,
+			LatencyMs:        c.Latency.Milliseconds(),
+			CreatedAt:        c.StartedAt.UTC(),
+		}
+		if c.Err != nil {
+			rec.Error = sql.NullString{String: c.Err.Error(), Valid: true}
+		}
+		if err := repo.SaveLLMCall(rec); err != nil {
+			log.Printf("llm usage: %v", err)
+		}
+	}
+}
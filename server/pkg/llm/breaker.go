@@ -0,0 +1,180 @@
+// Package llm — circuit breaker для LLM-провайдеров.
+//
+// Когда Ollama лежит, каждый тик всё равно ждёт таймаут и ретраи.
+// CircuitBreaker после серии ошибок «размыкает цепь» и сразу отвечает
+// ErrCircuitOpen — тик пропускается без ожидания. По истечении Cooldown
+// пропускается один пробный запрос (half-open): успех замыкает цепь.
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen — провайдер временно отключён circuit breaker'ом.
+var ErrCircuitOpen = errors.New("llm: circuit breaker open")
+
+// BreakerState — состояние circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Запросы проходят
+	BreakerOpen     BreakerState = "open"      // Запросы отклоняются сразу
+	BreakerHalfOpen BreakerState = "half_open" // Пропускается один пробный запрос
+)
+
+// HealthEvent — изменение состояния LLM-слоя, интересное дашборду.
+type HealthEvent struct {
+	// Type — "llm_degraded", "llm_recovered", "llm_fallback".
+	Type string
+
+	// Provider — имя провайдера, с которым связано событие.
+	Provider string
+
+	// Detail — человеко-читаемое пояснение (обычно текст ошибки).
+	Detail string
+}
+
+// HealthFunc — получатель HealthEvent (оркестратор публикует их как system-события).
+type HealthFunc func(HealthEvent)
+
+// CircuitBreaker — декоратор Completer, отсекающий вызовы к упавшему провайдеру.
+type CircuitBreaker struct {
+	// Next — оборачиваемый провайдер.
+	Next Completer
+
+	// Name — имя провайдера для событий и логов.
+	Name string
+
+	// FailureThreshold — сколько ошибок подряд размыкают цепь (default: 3).
+	FailureThreshold int
+
+	// Cooldown — сколько цепь остаётся разомкнутой до пробного запроса (default: 1m).
+	Cooldown time.Duration
+
+	// OnHealth — опциональный получатель событий degraded/recovered.
+	OnHealth HealthFunc
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker создаёт CircuitBreaker с настройками по умолчанию.
+func NewCircuitBreaker(name string, next Completer, onHealth HealthFunc) *CircuitBreaker {
+	return &CircuitBreaker{
+		Next:             next,
+		Name:             name,
+		FailureThreshold: 3,
+		Cooldown:         time.Minute,
+		OnHealth:         onHealth,
+		state:            BreakerClosed,
+	}
+}
+
+// State возвращает текущее состояние цепи.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == "" {
+		return BreakerClosed
+	}
+	return b.state
+}
+
+// Complete пропускает запрос к Next, если цепь не разомкнута.
+func (b *CircuitBreaker) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	if err := b.allow(); err != nil {
+		return CompletionResponse{}, err
+	}
+
+	resp, err := b.Next.Complete(ctx, req)
+	// Отмена вызывающей стороной ничего не говорит о здоровье провайдера.
+	if err != nil && errors.Is(err, context.Canceled) {
+		b.release()
+		return CompletionResponse{}, err
+	}
+	b.record(err)
+	return resp, err
+}
+
+// allow решает, пропускать ли запрос, и переводит open → half-open по таймеру.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown() {
+			return fmt.Errorf("llm %s: %w", b.Name, ErrCircuitOpen)
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("llm %s: %w", b.Name, ErrCircuitOpen)
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// release снимает флаг пробного запроса без изменения статистики.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record учитывает результат вызова и при смене состояния шлёт HealthEvent.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	prev := b.state
+	if prev == "" {
+		prev = BreakerClosed
+	}
+	b.probing = false
+
+	if err == nil {
+		b.failures = 0
+		b.state = BreakerClosed
+	} else {
+		b.failures++
+		threshold := b.FailureThreshold
+		if threshold <= 0 {
+			threshold = 3
+		}
+		if prev == BreakerHalfOpen || b.failures >= threshold {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	}
+	next := b.state
+	b.mu.Unlock()
+
+	if b.OnHealth == nil || prev == next {
+		return
+	}
+	switch {
+	case next == BreakerOpen && prev != BreakerHalfOpen:
+		b.OnHealth(HealthEvent{Type: "llm_degraded", Provider: b.Name, Detail: err.Error()})
+	case next == BreakerClosed:
+		b.OnHealth(HealthEvent{Type: "llm_recovered", Provider: b.Name})
+	}
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return time.Minute
+	}
+	return b.Cooldown
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	fail := &StatusError{Op: "Complete", Code: http.StatusServiceUnavailable}
+	next := &stubCompleter{}
+	var events []string
+	b := NewCircuitBreaker("test", next, func(e HealthEvent) { events = append(events, e.Type) })
+	b.Cooldown = 20 * time.Millisecond
+
+	call := func() error {
+		_, err := b.Complete(context.Background(), CompletionRequest{})
+		return err
+	}
+
+	// Ошибки ниже порога цепь не размыкают.
+	next.errs = []error{fail, fail, fail, fail}
+	for i := 0; i < 2; i++ {
+		call()
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("after 2 failures state = %s, want closed", got)
+	}
+
+	// Третья ошибка подряд размыкает цепь, дальше — отказ без вызова Next.
+	call()
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("after 3 failures state = %s, want open", got)
+	}
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker error = %v, want ErrCircuitOpen", err)
+	}
+	if next.calls != 3 {
+		t.Fatalf("calls while open = %d, want 3", next.calls)
+	}
+
+	// После cooldown пробный запрос снова падает — цепь размыкается опять.
+	time.Sleep(b.Cooldown)
+	call()
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("after failed probe state = %s, want open", got)
+	}
+
+	// Успешная проба замыкает цепь.
+	time.Sleep(b.Cooldown)
+	if err := call(); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("after successful probe state = %s, want closed", got)
+	}
+
+	want := []string{"llm_degraded", "llm_recovered"}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Errorf("health events = %v, want %v", events, want)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b := NewCircuitBreaker("test", &stubCompleter{}, nil)
+	b.state = BreakerOpen
+	b.openedAt = time.Now().Add(-2 * b.Cooldown)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("first probe rejected: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second concurrent probe error = %v, want ErrCircuitOpen", err)
+	}
+	b.release()
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after release rejected: %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancel(t *testing.T) {
+	next := &stubCompleter{errs: []error{context.Canceled, context.Canceled, context.Canceled}}
+	b := NewCircuitBreaker("test", next, nil)
+	for i := 0; i < 3; i++ {
+		b.Complete(context.Background(), CompletionRequest{})
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Errorf("state after cancellations = %s, want closed", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Package llm — метаданные вызова LLM.
+//
+// Декораторам (планировщик, учёт токенов) нужно знать, зачем сделан
+// вызов, но в тело запроса к провайдеру это не попадает. CallInfo
+// передаётся через context.Context и не влияет на ключ кассеты.
+
+package llm
+
+import "context"
+
+// Purpose — назначение вызова LLM (когнитивная задача).
+type Purpose string
+
+const (
+	PurposeHumanReply    Purpose = "human_reply"   // Ответ на инъекцию человека
+	PurposeDialogue      Purpose = "dialogue"      // Реплика в диалоге агентов
+	PurposeDecision      Purpose = "decision"      // Выбор действия
+	PurposeSummarization Purpose = "summarization" // Сводка разговора
+	PurposeReflection    Purpose = "reflection"    // Рефлексия агента
+	PurposeConsolidation Purpose = "consolidation" // Консолидация памяти
+	PurposeModeration    Purpose = "moderation"    // Проверка текста LLM-судьёй
+)
+
+// Purposes — все известные назначения, в порядке приоритета.
+var Purposes = []Purpose{
+	PurposeHumanReply, PurposeModeration, PurposeDialogue, PurposeDecision,
+	PurposeSummarization, PurposeReflection, PurposeConsolidation,
+}
+
+// Valid сообщает, известно ли назначение.
+func (p Purpose) Valid() bool {
+	for _, known := range Purposes {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Priority возвращает приоритет планировщика: меньше = раньше.
+// Человек ждёт ответа в UI, поэтому его реплики (и их модерация) идут
+// первыми, затем живой диалог, и только потом фоновые задачи.
+func (p Purpose) Priority() int {
+	switch p {
+	case PurposeHumanReply, PurposeModeration:
+		return 0
+	case PurposeDialogue, PurposeDecision:
+		return 1
+	case PurposeSummarization:
+		return 2
+	case PurposeReflection:
+		return 3
+	case PurposeConsolidation:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// CallInfo — метаданные вызова, передаваемые через контекст.
+type CallInfo struct {
+	// Purpose — назначение вызова. Пустое = PurposeDialogue.
+	Purpose Purpose
+
+	// AgentID — агент, от имени которого сделан вызов. Пустой = системный.
+	AgentID string
+
+	// Tick — тик симуляции, в котором сделан вызов.
+	Tick int64
+}
+
+type callInfoKey struct{}
+
+// WithCallInfo возвращает контекст с метаданными вызова.
+func WithCallInfo(ctx context.Context, info CallInfo) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, info)
+}
+
+// CallInfoFrom извлекает метаданные вызова из контекста.
+func CallInfoFrom(ctx context.Context) CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(CallInfo)
+	if info.Purpose == "" {
+		info.Purpose = PurposeDialogue
+	}
+	return info
+}
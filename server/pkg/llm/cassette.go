@@ -0,0 +1,298 @@
+// Package llm — запись и воспроизведение ответов LLM (cassette).
+//
+// RecordReplayClient — декоратор Completer для детерминированных тестов и
+// демо без Ollama. В режиме record он проксирует запросы в реальный
+// провайдер и дописывает пары запрос/ответ в JSON-файл («кассету»).
+// В режиме replay отвечает из кассеты офлайн: ключ — SHA-256 от
+// нормализованного запроса. Запросы без записи помечаются как unmatched
+// и либо возвращают ErrUnmatchedRequest, либо уходят в Fallback
+// (например, ScriptedResponder).
+
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnmatchedRequest — в кассете нет ответа на запрос (режим replay).
+var ErrUnmatchedRequest = errors.New("llm cassette: unmatched request")
+
+// CassetteMode — режим работы RecordReplayClient.
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record" // Проксировать в Next и записывать
+	CassetteReplay CassetteMode = "replay" // Отвечать только из кассеты
+)
+
+// Cassette — содержимое файла кассеты.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction — одна записанная пара запрос/ответ.
+type Interaction struct {
+	// Key — хеш нормализованного запроса (см. RequestKey).
+	Key string `json:"key"`
+
+	// Request — нормализованный запрос, сохраняется для читаемости кассеты.
+	Request NormalizedRequest `json:"request"`
+
+	// Response — ответ провайдера.
+	Response RecordedResponse `json:"response"`
+
+	// RecordedAt — когда ответ был записан.
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// NormalizedRequest — часть запроса, по которой ищется ответ.
+// Параметры сэмплирования в ключ не входят: подстройка температуры
+// или top_p не должна инвалидировать записанные кассеты.
+type NormalizedRequest struct {
+	SystemPrompt string    `json:"system"`
+	Messages     []Message `json:"messages"`
+}
+
+// RecordedResponse — сериализуемая часть CompletionResponse.
+type RecordedResponse struct {
+	Content          string `json:"content"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"promptTokens,omitempty"`
+	CompletionTokens int    `json:"completionTokens,omitempty"`
+}
+
+// NormalizeRequest приводит запрос к каноническому виду:
+// пробельные последовательности схлопываются, края обрезаются.
+func NormalizeRequest(req CompletionRequest) NormalizedRequest {
+	n := NormalizedRequest{
+		SystemPrompt: normalizeText(req.SystemPrompt),
+		Messages:     make([]Message, 0, len(req.Messages)),
+	}
+	for _, m := range req.Messages {
+		n.Messages = append(n.Messages, Message{
+			Role:    strings.ToLower(strings.TrimSpace(m.Role)),
+			Content: normalizeText(m.Content),
+		})
+	}
+	return n
+}
+
+// RequestKey возвращает ключ кассеты для запроса.
+func RequestKey(req CompletionRequest) string {
+	data, _ := json.Marshal(NormalizeRequest(req))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// RecordReplayClient — декоратор записи/воспроизведения.
+type RecordReplayClient struct {
+	// Next — реальный провайдер. Нужен только в режиме record.
+	Next Completer
+
+	// Mode — record или replay.
+	Mode CassetteMode
+
+	// Path — путь к JSON-файлу кассеты.
+	Path string
+
+	// Fallback — ответчик для unmatched-запросов в режиме replay.
+	// nil = вернуть ErrUnmatchedRequest.
+	Fallback Completer
+
+	mu        sync.Mutex
+	cassette  Cassette
+	byKey     map[string][]int // key → индексы в cassette.Interactions
+	cursor    map[string]int   // key → сколько раз ключ уже воспроизведён
+	unmatched []string
+}
+
+// NewRecordReplayClient загружает кассету из path (если файл есть)
+// и возвращает декоратор в заданном режиме.
+func NewRecordReplayClient(path string, mode CassetteMode, next Completer) (*RecordReplayClient, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("NewRecordReplayClient: unknown mode %q", mode)
+	}
+	if mode == CassetteRecord && next == nil {
+		return nil, errors.New("NewRecordReplayClient: record mode requires a provider")
+	}
+
+	c := &RecordReplayClient{
+		Next:     next,
+		Mode:     mode,
+		Path:     path,
+		cassette: Cassette{Version: 1},
+		byKey:    make(map[string][]int),
+		cursor:   make(map[string]int),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &c.cassette); err != nil {
+			return nil, fmt.Errorf("NewRecordReplayClient decode %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && mode == CassetteRecord:
+		// Новая кассета создастся при первой записи.
+	default:
+		return nil, fmt.Errorf("NewRecordReplayClient read %s: %w", path, err)
+	}
+
+	for i, it := range c.cassette.Interactions {
+		c.byKey[it.Key] = append(c.byKey[it.Key], i)
+	}
+	return c, nil
+}
+
+// Complete в режиме record проксирует и записывает, в режиме replay —
+// отвечает из кассеты. Одинаковые запросы воспроизводятся в порядке
+// записи; когда записи кончаются, повторяется последняя.
+func (c *RecordReplayClient) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	key := RequestKey(req)
+	if c.Mode == CassetteRecord {
+		return c.record(ctx, key, req)
+	}
+
+	if resp, ok := c.replay(key); ok {
+		return resp, nil
+	}
+
+	c.mu.Lock()
+	c.unmatched = append(c.unmatched, key)
+	c.mu.Unlock()
+	log.Printf("llm cassette: unmatched request %s", key[:12])
+
+	if c.Fallback == nil {
+		return CompletionResponse{}, fmt.Errorf("%w %s", ErrUnmatchedRequest, key[:12])
+	}
+	return c.Fallback.Complete(ctx, req)
+}
+
+// Unmatched возвращает ключи запросов, для которых в кассете не нашлось ответа.
+func (c *RecordReplayClient) Unmatched() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.unmatched...)
+}
+
+func (c *RecordReplayClient) replay(key string) (CompletionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx := c.byKey[key]
+	if len(idx) == 0 {
+		return CompletionResponse{}, false
+	}
+	n := c.cursor[key]
+	if n >= len(idx) {
+		n = len(idx) - 1
+	}
+	c.cursor[key]++
+
+	r := c.cassette.Interactions[idx[n]].Response
+	return CompletionResponse{
+		Content:          r.Content,
+		Model:            r.Model,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+	}, true
+}
+
+func (c *RecordReplayClient) record(ctx context.Context, key string, req CompletionRequest) (CompletionResponse, error) {
+	resp, err := c.Next.Complete(ctx, req)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cassette.Interactions = append(c.cassette.Interactions, Interaction{
+		Key:     key,
+		Request: NormalizeRequest(req),
+		Response: RecordedResponse{
+			Content:          resp.Content,
+			Model:            resp.Model,
+			PromptTokens:     resp.PromptTokens,
+			CompletionTokens: resp.CompletionTokens,
+		},
+		RecordedAt: time.Now().UTC(),
+	})
+	c.byKey[key] = append(c.byKey[key], len(c.cassette.Interactions)-1)
+
+	if err := c.save(); err != nil {
+		log.Printf("llm cassette: %v", err)
+	}
+	return resp, nil
+}
+
+// save атомарно перезаписывает файл кассеты (tmp + rename).
+// Вызывается под c.mu.
+func (c *RecordReplayClient) save() error {
+	data, err := json.MarshalIndent(c.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.Path); err != nil {
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	return nil
+}
+
+// ScriptedResponder — детерминированный офлайн-провайдер.
+// Если задан Respond, ответ вычисляется им; иначе Lines выдаются по кругу.
+type ScriptedResponder struct {
+	// Lines — заготовленные реплики, выдаются по порядку.
+	Lines []string
+
+	// Respond — опциональная функция ответа, перекрывает Lines.
+	Respond func(req CompletionRequest) string
+
+	mu   sync.Mutex
+	next int
+}
+
+// Complete возвращает следующую заготовленную реплику.
+func (s *ScriptedResponder) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	var content string
+	if s.Respond != nil {
+		content = s.Respond(req)
+	} else {
+		if len(s.Lines) == 0 {
+			return CompletionResponse{}, errors.New("ScriptedResponder: no lines")
+		}
+		s.mu.Lock()
+		content = s.Lines[s.next%len(s.Lines)]
+		s.next++
+		s.mu.Unlock()
+	}
+	return CompletionResponse{Content: content, Model: "scripted"}, nil
+}
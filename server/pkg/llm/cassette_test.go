@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func request(system string, msgs ...string) CompletionRequest {
+	req := CompletionRequest{SystemPrompt: system}
+	for _, m := range msgs {
+		req.Messages = append(req.Messages, Message{Role: "user", Content: m})
+	}
+	return req
+}
+
+func TestRequestKeyNormalization(t *testing.T) {
+	temp := 0.1
+	a := request("Ты —  Алиса.", "Привет,\n Боб")
+	b := request(" Ты — Алиса. ", "Привет, Боб")
+	b.Temperature = &temp
+	if RequestKey(a) != RequestKey(b) {
+		t.Error("keys differ for requests that differ only in whitespace and sampling")
+	}
+	if RequestKey(a) == RequestKey(request("Ты — Алиса.", "Пока, Боб")) {
+		t.Error("keys match for different messages")
+	}
+}
+
+func TestRecordReplayClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+	hello := request("Ты — Алиса.", "Привет")
+
+	rec, err := NewRecordReplayClient(path, CassetteRecord, &ScriptedResponder{Lines: []string{"первый", "второй"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"первый", "второй"} {
+		if resp, err := rec.Complete(ctx, hello); err != nil || resp.Content != want {
+			t.Fatalf("record = %q, %v; want %q", resp.Content, err, want)
+		}
+	}
+
+	play, err := NewRecordReplayClient(path, CassetteReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Одинаковые запросы воспроизводятся по порядку, затем повторяется последний.
+	for _, want := range []string{"первый", "второй", "второй"} {
+		if resp, err := play.Complete(ctx, hello); err != nil || resp.Content != want {
+			t.Fatalf("replay = %q, %v; want %q", resp.Content, err, want)
+		}
+	}
+
+	if _, err := play.Complete(ctx, request("Ты — Боб.", "Привет")); !errors.Is(err, ErrUnmatchedRequest) {
+		t.Fatalf("unmatched error = %v, want ErrUnmatchedRequest", err)
+	}
+	if n := len(play.Unmatched()); n != 1 {
+		t.Errorf("unmatched = %d, want 1", n)
+	}
+
+	play.Fallback = &ScriptedResponder{Lines: []string{"заготовка"}}
+	if resp, err := play.Complete(ctx, request("Ты — Боб.", "Привет")); err != nil || resp.Content != "заготовка" {
+		t.Errorf("fallback = %q, %v; want заготовка", resp.Content, err)
+	}
+}
+
+func TestRecordReplayClientModes(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewRecordReplayClient(filepath.Join(dir, "missing.json"), CassetteReplay, nil); err == nil {
+		t.Error("replay of a missing cassette succeeded")
+	}
+	if _, err := NewRecordReplayClient(filepath.Join(dir, "c.json"), CassetteRecord, nil); err == nil {
+		t.Error("record without a provider succeeded")
+	}
+	if _, err := NewRecordReplayClient(filepath.Join(dir, "c.json"), "rewind", nil); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
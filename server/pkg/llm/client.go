@@ -12,9 +12,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Completer — общий интерфейс провайдеров LLM (Ollama, OpenAI-совместимые).
+// Совпадает по сигнатуре с agent.LLMClient, поэтому любой провайдер
+// можно передать в Brain и оркестратор.
+type Completer interface {
+	Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error)
+}
+
 // Client — HTTP-клиент для Ollama API.
 type Client struct {
 	// BaseURL — endpoint Ollama сервера (default: http://localhost:11434).
@@ -29,6 +38,10 @@ type Client struct {
 	Config ClientConfig
 }
 
+// DefaultMaxTokens — лимит токенов ответа по умолчанию для всех
+// провайдеров. Переопределяется LLM_MAX_TOKENS.
+const DefaultMaxTokens = 1024
+
 // ClientConfig — конфигурация LLM-клиента.
 type ClientConfig struct {
 	// DefaultTemperature — температура по умолчанию (0.0–2.0).
@@ -39,10 +52,117 @@ type ClientConfig struct {
 
 	// Timeout — таймаут HTTP-запроса.
 	Timeout time.Duration
+
+	// TopP — nucleus sampling (0 = дефолт модели).
+	TopP float64
+
+	// TopK — ограничение выборки K самыми вероятными токенами (0 = дефолт модели).
+	TopK int
+
+	// RepeatPenalty — штраф за повторы (1.0 = без штрафа, 0 = дефолт модели).
+	RepeatPenalty float64
+
+	// Stop — стоп-последовательности, обрывающие генерацию.
+	Stop []string
+
+	// Seed — зерно генерации. Вместе с фиксированной температурой даёт
+	// воспроизводимые ответы. nil = случайное.
+	Seed *int
+
+	// NumCtx — размер контекстного окна в токенах (0 = дефолт модели).
+	NumCtx int
+}
+
+// LoadEnv переопределяет параметры генерации из env-переменных:
+// LLM_MAX_TOKENS, LLM_TEMPERATURE, LLM_TOP_P, LLM_TOP_K, LLM_REPEAT_PENALTY,
+// LLM_STOP (через "|"), LLM_SEED, LLM_NUM_CTX. Пустые переменные игнорируются.
+// LLM_SEED задаёт и зерно случайных решений симуляции (Orchestrator.UseSeed).
+func (cfg *ClientConfig) LoadEnv() {
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_TOKENS")); err == nil && v > 0 {
+		cfg.MaxTokens = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_TEMPERATURE"), 64); err == nil && v >= 0 {
+		cfg.DefaultTemperature = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_TOP_P"), 64); err == nil && v > 0 {
+		cfg.TopP = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_TOP_K")); err == nil && v > 0 {
+		cfg.TopK = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_REPEAT_PENALTY"), 64); err == nil && v > 0 {
+		cfg.RepeatPenalty = v
+	}
+	if v := os.Getenv("LLM_STOP"); v != "" {
+		cfg.Stop = strings.Split(v, "|")
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_SEED")); err == nil {
+		cfg.Seed = &v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_NUM_CTX")); err == nil && v > 0 {
+		cfg.NumCtx = v
+	}
+}
+
+// GenerationOptions — итоговые параметры одного запроса:
+// дефолты клиента, перекрытые полями CompletionRequest.
+type GenerationOptions struct {
+	Temperature   float64
+	MaxTokens     int
+	TopP          float64
+	TopK          int
+	RepeatPenalty float64
+	Stop          []string
+	Seed          *int
+	NumCtx        int
+}
+
+// Resolve сливает дефолты cfg с переопределениями из req.
+func (cfg ClientConfig) Resolve(req CompletionRequest) GenerationOptions {
+	opts := GenerationOptions{
+		Temperature:   cfg.DefaultTemperature,
+		MaxTokens:     cfg.MaxTokens,
+		TopP:          cfg.TopP,
+		TopK:          cfg.TopK,
+		RepeatPenalty: cfg.RepeatPenalty,
+		Stop:          cfg.Stop,
+		Seed:          cfg.Seed,
+		NumCtx:        cfg.NumCtx,
+	}
+	if req.Temperature != nil {
+		opts.Temperature = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		opts.MaxTokens = *req.MaxTokens
+	} else if req.MaxTokensScale > 0 && opts.MaxTokens > 0 {
+		opts.MaxTokens = max(1, int(float64(opts.MaxTokens)*req.MaxTokensScale))
+	}
+	if req.TopP != nil {
+		opts.TopP = *req.TopP
+	}
+	if req.TopK != nil {
+		opts.TopK = *req.TopK
+	}
+	if req.RepeatPenalty != nil {
+		opts.RepeatPenalty = *req.RepeatPenalty
+	}
+	if len(req.Stop) > 0 {
+		opts.Stop = req.Stop
+	}
+	if req.Seed != nil {
+		opts.Seed = req.Seed
+	}
+	if req.NumCtx != nil {
+		opts.NumCtx = *req.NumCtx
+	}
+	return opts
 }
 
 // CompletionRequest — запрос на генерацию текста.
 type CompletionRequest struct {
+	// Model — переопределение модели (выставляет Router). Пустая = модель клиента.
+	Model string
+
 	// SystemPrompt — системный промпт (личность агента).
 	SystemPrompt string
 
@@ -54,6 +174,21 @@ type CompletionRequest struct {
 
 	// MaxTokens — переопределение лимита токенов. nil = дефолт.
 	MaxTokens *int
+
+	// MaxTokensScale — множитель к лимиту токенов клиента, если MaxTokens
+	// не задан. 0 = без изменений.
+	MaxTokensScale float64
+
+	// TopP, TopK, RepeatPenalty, Seed, NumCtx — переопределения
+	// параметров сэмплирования. nil = дефолт клиента.
+	TopP          *float64
+	TopK          *int
+	RepeatPenalty *float64
+	Seed          *int
+	NumCtx        *int
+
+	// Stop — стоп-последовательности. Пустой = дефолт клиента.
+	Stop []string
 }
 
 // Message — одно сообщение в контексте разговора (формат Ollama).
@@ -70,13 +205,29 @@ type CompletionResponse struct {
 	// Model — модель, которая ответила.
 	Model string
 
+	// Provider — провайдер цепочки FallbackClient, который ответил
+	// (FallbackEntry.Name). Пусто без FallbackClient.
+	Provider string
+
 	// Duration — время запроса.
 	Duration time.Duration
+
+	// PromptTokens — токены промпта по данным провайдера (0 = неизвестно).
+	PromptTokens int
+
+	// CompletionTokens — сгенерированные токены по данным провайдера.
+	CompletionTokens int
+
+	// PromptEvalDuration, EvalDuration — время обработки промпта и генерации
+	// по данным провайдера (Ollama). 0 = провайдер не сообщает.
+	PromptEvalDuration time.Duration
+	EvalDuration       time.Duration
 }
 
 // NewClient создаёт Ollama-клиент из env-переменных.
 // OLLAMA_URL — адрес сервера (default: http://localhost:11434)
 // OLLAMA_MODEL — модель (default: gemma3)
+// Параметры генерации по умолчанию — см. ClientConfig.LoadEnv.
 func NewClient() *Client {
 	baseURL := os.Getenv("OLLAMA_URL")
 	if baseURL == "" {
@@ -86,17 +237,19 @@ func NewClient() *Client {
 	if model == "" {
 		model = "gemma3:4b"
 	}
+	cfg := ClientConfig{
+		DefaultTemperature: 0.7,
+		MaxTokens:          DefaultMaxTokens,
+		Timeout:            5 * time.Minute,
+	}
+	cfg.LoadEnv()
 	return &Client{
 		BaseURL: baseURL,
 		Model:   model,
 		HTTPClient: &http.Client{
-			Timeout: 5 * time.Minute,
-		},
-		Config: ClientConfig{
-			DefaultTemperature: 0.7,
-			MaxTokens:          5500,
-			Timeout:            5 * time.Minute,
+			Timeout: cfg.Timeout,
 		},
+		Config: cfg,
 	}
 }
 
@@ -109,12 +262,56 @@ type ollamaChatRequest struct {
 }
 
 // ollamaChatResponse — ответ от Ollama /api/chat (stream: false).
+// Длительности Ollama отдаёт в наносекундах.
 type ollamaChatResponse struct {
 	Message struct {
 		Content string `json:"content"`
 	} `json:"message"`
 	Model string `json:"model"`
 	Done  bool   `json:"done"`
+
+	TotalDuration      int64 `json:"total_duration"`
+	LoadDuration       int64 `json:"load_duration"`
+	PromptEvalCount    int   `json:"prompt_eval_count"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration"`
+	EvalCount          int   `json:"eval_count"`
+	EvalDuration       int64 `json:"eval_duration"`
+}
+
+// ollamaOptions переводит GenerationOptions в поле options Ollama.
+// Нулевые значения не отправляются — Ollama возьмёт дефолты модели.
+func ollamaOptions(o GenerationOptions) map[string]any {
+	opts := map[string]any{"temperature": o.Temperature}
+	if o.MaxTokens > 0 {
+		opts["num_predict"] = o.MaxTokens
+	}
+	if o.TopP > 0 {
+		opts["top_p"] = o.TopP
+	}
+	if o.TopK > 0 {
+		opts["top_k"] = o.TopK
+	}
+	if o.RepeatPenalty > 0 {
+		opts["repeat_penalty"] = o.RepeatPenalty
+	}
+	if len(o.Stop) > 0 {
+		opts["stop"] = o.Stop
+	}
+	if o.Seed != nil {
+		opts["seed"] = *o.Seed
+	}
+	if o.NumCtx > 0 {
+		opts["num_ctx"] = o.NumCtx
+	}
+	return opts
+}
+
+// modelFor возвращает модель запроса: маршрут из req.Model или модель клиента.
+func (c *Client) modelFor(req CompletionRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return c.Model
 }
 
 // Complete отправляет запрос в Ollama и возвращает ответ.
@@ -126,16 +323,11 @@ func (c *Client) Complete(ctx context.Context, req CompletionRequest) (Completio
 		messages = append([]Message{{Role: "system", Content: req.SystemPrompt}}, messages...)
 	}
 
-	temp := c.Config.DefaultTemperature
-	if req.Temperature != nil {
-		temp = *req.Temperature
-	}
-
 	body := ollamaChatRequest{
-		Model:    c.Model,
+		Model:    c.modelFor(req),
 		Messages: messages,
 		Stream:   false,
-		Options:  map[string]any{"temperature": temp},
+		Options:  ollamaOptions(c.Config.Resolve(req)),
 	}
 
 	data, err := json.Marshal(body)
@@ -159,7 +351,7 @@ func (c *Client) Complete(ctx context.Context, req CompletionRequest) (Completio
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return CompletionResponse{}, fmt.Errorf("Complete status %d", resp.StatusCode)
+		return CompletionResponse{}, &StatusError{Op: "Complete", Code: resp.StatusCode}
 	}
 
 	var ollResp ollamaChatResponse
@@ -168,8 +360,12 @@ func (c *Client) Complete(ctx context.Context, req CompletionRequest) (Completio
 	}
 
 	return CompletionResponse{
-		Content:  ollResp.Message.Content,
-		Model:    ollResp.Model,
-		Duration: time.Since(start),
+		Content:            ollResp.Message.Content,
+		Model:              ollResp.Model,
+		Duration:           time.Since(start),
+		PromptTokens:       ollResp.PromptEvalCount,
+		CompletionTokens:   ollResp.EvalCount,
+		PromptEvalDuration: time.Duration(ollResp.PromptEvalDuration),
+		EvalDuration:       time.Duration(ollResp.EvalDuration),
 	}, nil
 }
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientConfigResolve(t *testing.T) {
+	seed := 7
+	cfg := ClientConfig{DefaultTemperature: 0.7, MaxTokens: 256, TopK: 40, Stop: []string{"\n\n"}, Seed: &seed}
+
+	got := cfg.Resolve(CompletionRequest{})
+	want := GenerationOptions{Temperature: 0.7, MaxTokens: 256, TopK: 40, Stop: []string{"\n\n"}, Seed: &seed}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults = %+v, want %+v", got, want)
+	}
+
+	temp, maxTokens, topP, reqSeed := 0.2, 64, 0.9, 1
+	got = cfg.Resolve(CompletionRequest{Temperature: &temp, MaxTokens: &maxTokens, TopP: &topP, Seed: &reqSeed, Stop: []string{"END"}})
+	want = GenerationOptions{Temperature: 0.2, MaxTokens: 64, TopP: 0.9, TopK: 40, Stop: []string{"END"}, Seed: &reqSeed}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overrides = %+v, want %+v", got, want)
+	}
+}
+
+func TestClientConfigLoadEnv(t *testing.T) {
+	t.Setenv("LLM_MAX_TOKENS", "300")
+	t.Setenv("LLM_TEMPERATURE", "0.4")
+	t.Setenv("LLM_STOP", "END|STOP")
+	t.Setenv("LLM_SEED", "42")
+	t.Setenv("LLM_TOP_K", "not a number")
+
+	cfg := ClientConfig{DefaultTemperature: 0.7, MaxTokens: 100, TopK: 20}
+	cfg.LoadEnv()
+	if cfg.MaxTokens != 300 || cfg.DefaultTemperature != 0.4 || cfg.TopK != 20 {
+		t.Errorf("config = %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Stop, []string{"END", "STOP"}) || cfg.Seed == nil || *cfg.Seed != 42 {
+		t.Errorf("stop = %v, seed = %v", cfg.Stop, cfg.Seed)
+	}
+}
+
+func TestClientSendsOllamaOptions(t *testing.T) {
+	var got ollamaChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"model":"gemma3:4b","message":{"role":"assistant","content":"Hi"},"done":true}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, Model: "gemma3:4b", HTTPClient: srv.Client(), Config: ClientConfig{DefaultTemperature: 0.7, MaxTokens: 128}}
+	topK := 30
+	resp, err := c.Complete(context.Background(), CompletionRequest{TopK: &topK})
+	if err != nil || resp.Content != "Hi" {
+		t.Fatalf("Complete = %q, %v", resp.Content, err)
+	}
+	want := map[string]any{"temperature": 0.7, "num_predict": 128.0, "top_k": 30.0}
+	if !reflect.DeepEqual(got.Options, want) {
+		t.Errorf("options = %v, want %v", got.Options, want)
+	}
+}
+
+func TestProvidersShareMaxTokens(t *testing.T) {
+	if c, o := NewClient(), NewOpenAIClient(); c.Config.MaxTokens != DefaultMaxTokens || o.Config.MaxTokens != DefaultMaxTokens {
+		t.Errorf("defaults = %d (ollama), %d (openai); want %d", c.Config.MaxTokens, o.Config.MaxTokens, DefaultMaxTokens)
+	}
+	t.Setenv("LLM_MAX_TOKENS", "256")
+	if c, o := NewClient(), NewOpenAIClient(); c.Config.MaxTokens != 256 || o.Config.MaxTokens != 256 {
+		t.Errorf("LLM_MAX_TOKENS = %d (ollama), %d (openai); want 256", c.Config.MaxTokens, o.Config.MaxTokens)
+	}
+}
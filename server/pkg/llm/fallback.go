@@ -0,0 +1,93 @@
+// Package llm — цепочка резервных провайдеров и моделей.
+//
+// FallbackClient перебирает провайдеров по порядку: если основной не
+// ответил (ошибка или разомкнутый circuit breaker), запрос уходит
+// следующему — другой модели в том же Ollama или другому серверу.
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// FallbackEntry — один провайдер в цепочке.
+type FallbackEntry struct {
+	// Name — имя для логов и событий ("ollama/gemma3:4b").
+	Name string
+
+	// Client — провайдер (обычно уже обёрнутый в RetryClient и CircuitBreaker).
+	Client Completer
+}
+
+// FallbackClient — декоратор, пробующий провайдеров по порядку.
+type FallbackClient struct {
+	// Entries — упорядоченный список: первый — основной, остальные — резерв.
+	Entries []FallbackEntry
+
+	// OnHealth — опциональный получатель событий "llm_fallback".
+	OnHealth HealthFunc
+}
+
+// NewFallbackClient создаёт цепочку из entries.
+func NewFallbackClient(onHealth HealthFunc, entries ...FallbackEntry) *FallbackClient {
+	return &FallbackClient{Entries: entries, OnHealth: onHealth}
+}
+
+// Complete возвращает ответ первого провайдера, ответившего без ошибки.
+// Если все цепи разомкнуты, ошибка оборачивает ErrCircuitOpen —
+// оркестратор по ней пропускает тик без шума в логах.
+// Маршрут req.Model относится к основному провайдеру: резервы отвечают
+// своими моделями из LLM_FALLBACKS. Ответивший провайдер — в resp.Provider.
+func (f *FallbackClient) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	if len(f.Entries) == 0 {
+		return CompletionResponse{}, errors.New("llm fallback: no providers configured")
+	}
+
+	var (
+		errs    []error
+		allOpen = true
+	)
+	routed := req.Model
+	for i, e := range f.Entries {
+		if i == 1 {
+			req.Model = ""
+		}
+		resp, err := e.Client.Complete(ctx, req)
+		if err == nil {
+			if i > 0 && routed != "" {
+				log.Printf("llm fallback: served by %s instead of routed model %s", e.Name, routed)
+			} else if i > 0 {
+				log.Printf("llm fallback: served by %s", e.Name)
+			}
+			resp.Provider = e.Name
+			return resp, nil
+		}
+		if !errors.Is(err, ErrCircuitOpen) {
+			errs = append(errs, err)
+			allOpen = false
+		}
+		if ctx.Err() != nil {
+			return CompletionResponse{}, err
+		}
+
+		if i+1 < len(f.Entries) {
+			next := f.Entries[i+1].Name
+			log.Printf("llm fallback: %s failed (%v), trying %s", e.Name, err, next)
+			if f.OnHealth != nil && !errors.Is(err, ErrCircuitOpen) {
+				f.OnHealth(HealthEvent{
+					Type:     "llm_fallback",
+					Provider: next,
+					Detail:   fmt.Sprintf("%s failed: %v", e.Name, err),
+				})
+			}
+		}
+	}
+
+	if allOpen {
+		return CompletionResponse{}, fmt.Errorf("llm fallback: all providers unavailable: %w", ErrCircuitOpen)
+	}
+	return CompletionResponse{}, fmt.Errorf("llm fallback: all providers failed: %w", errors.Join(errs...))
+}
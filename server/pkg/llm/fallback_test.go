@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFallbackClient(t *testing.T) {
+	fail := &StatusError{Op: "Complete", Code: http.StatusServiceUnavailable}
+	open := fmt.Errorf("llm primary: %w", ErrCircuitOpen)
+
+	tests := []struct {
+		name        string
+		errs        [][]error // ошибки каждого провайдера
+		wantCalls   []int
+		wantErr     error
+		wantHealths int
+	}{
+		{"primary answers", [][]error{nil, nil}, []int{1, 0}, nil, 0},
+		{"fallback answers", [][]error{{fail}, nil}, []int{1, 1}, nil, 1},
+		{"all failed", [][]error{{fail}, {fail}}, []int{1, 1}, fail, 1},
+		{"all open", [][]error{{open}, {open}}, []int{1, 1}, ErrCircuitOpen, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []FallbackEntry
+			var stubs []*stubCompleter
+			for i, errs := range tt.errs {
+				s := &stubCompleter{errs: errs}
+				stubs = append(stubs, s)
+				entries = append(entries, FallbackEntry{Name: []string{"primary", "backup"}[i], Client: s})
+			}
+			healths := 0
+			f := NewFallbackClient(func(HealthEvent) { healths++ }, entries...)
+
+			_, err := f.Complete(context.Background(), CompletionRequest{})
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Complete error = %v, want %v", err, tt.wantErr)
+			}
+			for i, s := range stubs {
+				if s.calls != tt.wantCalls[i] {
+					t.Errorf("provider %d calls = %d, want %d", i, s.calls, tt.wantCalls[i])
+				}
+			}
+			if healths != tt.wantHealths {
+				t.Errorf("llm_fallback events = %d, want %d", healths, tt.wantHealths)
+			}
+		})
+	}
+}
+
+func TestFallbackClientReportsProvider(t *testing.T) {
+	primary := &modelRecorder{}
+	f := NewFallbackClient(nil,
+		FallbackEntry{Name: "ollama/gemma3:4b", Client: primary},
+		FallbackEntry{Name: "openai/qwen", Client: &modelRecorder{}},
+	)
+	resp, err := f.Complete(context.Background(), CompletionRequest{Model: "gemma3:12b"})
+	if err != nil || resp.Provider != "ollama/gemma3:4b" || primary.model != "gemma3:12b" {
+		t.Errorf("primary: provider %q, model %q, %v", resp.Provider, primary.model, err)
+	}
+
+	// Резерв отвечает своей моделью, а не моделью маршрута.
+	backup := &modelRecorder{}
+	f.Entries[0].Client = &stubCompleter{errs: []error{&StatusError{Code: http.StatusBadGateway}}}
+	f.Entries[1].Client = backup
+	resp, err = f.Complete(context.Background(), CompletionRequest{Model: "gemma3:12b"})
+	if err != nil || resp.Provider != "openai/qwen" || backup.model != "" {
+		t.Errorf("fallback: provider %q, model %q, %v", resp.Provider, backup.model, err)
+	}
+}
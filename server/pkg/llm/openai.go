@@ -0,0 +1,179 @@
+// Package llm — клиент для OpenAI-совместимых серверов.
+//
+// llama.cpp server, vLLM и другие реализации отдают /v1/chat/completions
+// вместо Ollama /api/chat. OpenAIClient реализует тот же Complete(),
+// поэтому подменяет Ollama без изменений в Brain и оркестраторе.
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAIClient — HTTP-клиент для OpenAI Chat Completions API.
+type OpenAIClient struct {
+	// BaseURL — адрес сервера без /v1 (default: http://localhost:8000).
+	BaseURL string
+	// Model — имя модели, как её знает сервер.
+	Model string
+	// APIKey — Bearer-токен. Пустой = заголовок Authorization не отправляется.
+	APIKey string
+
+	// HTTPClient — HTTP-клиент с таймаутами.
+	HTTPClient *http.Client
+
+	// Config — параметры по умолчанию для запросов.
+	Config ClientConfig
+}
+
+// NewOpenAIClient создаёт клиент OpenAI-совместимого API из env-переменных.
+// OPENAI_BASE_URL — адрес сервера (default: http://localhost:8000)
+// OPENAI_MODEL — модель (default: gemma3:4b)
+// OPENAI_API_KEY — токен (опционально)
+// Остальные параметры генерации — см. ClientConfig.LoadEnv.
+func NewOpenAIClient() *OpenAIClient {
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8000"
+	}
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gemma3:4b"
+	}
+	cfg := ClientConfig{
+		DefaultTemperature: 0.7,
+		MaxTokens:          DefaultMaxTokens,
+		Timeout:            5 * time.Minute,
+	}
+	cfg.LoadEnv()
+	return &OpenAIClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Model:   model,
+		APIKey:  os.Getenv("OPENAI_API_KEY"),
+		HTTPClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		Config: cfg,
+	}
+}
+
+// openAIChatRequest — тело запроса к /v1/chat/completions.
+// top_k и repeat_penalty — расширения llama.cpp server, отправляются
+// только если заданы явно.
+type openAIChatRequest struct {
+	Model         string    `json:"model"`
+	Messages      []Message `json:"messages"`
+	Temperature   float64   `json:"temperature"`
+	MaxTokens     int       `json:"max_tokens,omitempty"`
+	TopP          float64   `json:"top_p,omitempty"`
+	TopK          int       `json:"top_k,omitempty"`
+	RepeatPenalty float64   `json:"repeat_penalty,omitempty"`
+	Stop          []string  `json:"stop,omitempty"`
+	Seed          *int      `json:"seed,omitempty"`
+	Stream        bool      `json:"stream"`
+}
+
+// openAIUsage — счётчики токенов из ответа.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// openAIChatResponse — ответ /v1/chat/completions (stream: false).
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// Complete отправляет запрос в OpenAI-совместимый сервер и возвращает ответ.
+func (c *OpenAIClient) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	start := time.Now()
+
+	messages := req.Messages
+	if req.SystemPrompt != "" {
+		messages = append([]Message{{Role: "system", Content: req.SystemPrompt}}, messages...)
+	}
+
+	// num_ctx задаётся при запуске сервера и в OpenAI API не передаётся.
+	opts := c.Config.Resolve(req)
+	model := c.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+	body := openAIChatRequest{
+		Model:         model,
+		Messages:      messages,
+		Temperature:   opts.Temperature,
+		MaxTokens:     opts.MaxTokens,
+		TopP:          opts.TopP,
+		TopK:          opts.TopK,
+		RepeatPenalty: opts.RepeatPenalty,
+		Stop:          opts.Stop,
+		Seed:          opts.Seed,
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return CompletionResponse{}, fmt.Errorf("OpenAI Complete marshal: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/v1/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return CompletionResponse{}, fmt.Errorf("OpenAI Complete create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return CompletionResponse{}, fmt.Errorf("OpenAI Complete http: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CompletionResponse{}, &StatusError{Op: "OpenAI Complete", Code: resp.StatusCode}
+	}
+
+	out, err := c.readBody(resp)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if out.Model == "" {
+		out.Model = model
+	}
+	out.Duration = time.Since(start)
+	return out, nil
+}
+
+// readBody разбирает ответ сервера.
+func (c *OpenAIClient) readBody(resp *http.Response) (CompletionResponse, error) {
+	var oaResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&oaResp); err != nil {
+		return CompletionResponse{}, fmt.Errorf("OpenAI Complete decode: %w", err)
+	}
+	if len(oaResp.Choices) == 0 {
+		return CompletionResponse{}, fmt.Errorf("OpenAI Complete: empty choices")
+	}
+
+	out := CompletionResponse{
+		Content: oaResp.Choices[0].Message.Content,
+		Model:   oaResp.Model,
+	}
+	if oaResp.Usage != nil {
+		out.PromptTokens = oaResp.Usage.PromptTokens
+		out.CompletionTokens = oaResp.Usage.CompletionTokens
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIClientComplete(t *testing.T) {
+	var got openAIChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q, want Bearer secret", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"model":"qwen","choices":[{"message":{"role":"assistant","content":"Привет!"}}],
+			"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	defer srv.Close()
+
+	c := NewOpenAIClient()
+	c.BaseURL, c.Model, c.APIKey = srv.URL, "qwen", "secret"
+
+	temp, maxTokens := 0.2, 64
+	resp, err := c.Complete(context.Background(), CompletionRequest{
+		SystemPrompt: "Ты — Алиса.",
+		Messages:     []Message{{Role: "user", Content: "Привет"}},
+		Temperature:  &temp,
+		MaxTokens:    &maxTokens,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Content != "Привет!" || resp.Model != "qwen" || resp.PromptTokens != 12 || resp.CompletionTokens != 3 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if got.Model != "qwen" || got.Temperature != 0.2 || got.MaxTokens != 64 || got.Stream {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Content != "Привет" {
+		t.Errorf("messages = %+v, want system prompt first", got.Messages)
+	}
+}
+
+func TestOpenAIClientStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewOpenAIClient()
+	c.BaseURL = srv.URL
+	_, err := c.Complete(context.Background(), CompletionRequest{})
+	if !IsTransient(err) {
+		t.Fatalf("Complete with 503 error = %v, want a transient StatusError", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package llm — повторные попытки для временных ошибок LLM.
+//
+// Ollama на слабом CPU регулярно отвечает 503, рвёт соединение или не
+// успевает в таймаут. RetryClient повторяет такие вызовы с экспоненциальной
+// задержкой и джиттером, чтобы один сбой не обрывал весь диалог.
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// StatusError — ответ провайдера с HTTP-статусом, отличным от 200.
+type StatusError struct {
+	// Op — операция, в которой получен статус ("Complete", "OpenAI Complete").
+	Op string
+	// Code — HTTP-статус ответа.
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s status %d", e.Op, e.Code)
+}
+
+// IsTransient сообщает, имеет ли смысл повторить запрос:
+// отказ в соединении, разрыв, сетевой таймаут, 429 и 5xx.
+// Отмена контекста вызывающей стороной временной ошибкой не считается.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// RetryClient — декоратор Completer с повторами и jittered backoff.
+type RetryClient struct {
+	// Next — оборачиваемый провайдер.
+	Next Completer
+
+	// Name — имя провайдера для логов.
+	Name string
+
+	// MaxAttempts — максимум попыток, включая первую (default: 3).
+	MaxAttempts int
+
+	// BaseDelay — задержка перед второй попыткой (default: 500ms).
+	// Каждая следующая удваивается, реальная задержка выбирается
+	// случайно в [0, delay] (full jitter).
+	BaseDelay time.Duration
+
+	// MaxDelay — потолок задержки (default: 10s).
+	MaxDelay time.Duration
+}
+
+// NewRetryClient создаёт RetryClient с настройками по умолчанию.
+func NewRetryClient(name string, next Completer) *RetryClient {
+	return &RetryClient{
+		Next:        next,
+		Name:        name,
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// Complete вызывает Next, повторяя временные ошибки.
+func (r *RetryClient) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	attempts := r.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := r.backoff(attempt)
+			log.Printf("llm %s: retry %d/%d in %v after: %v", r.Name, attempt+1, attempts, delay, lastErr)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return CompletionResponse{}, fmt.Errorf("llm %s retry: %w", r.Name, ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		resp, err := r.Next.Complete(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		// Контекст тика закончился — повторять бессмысленно.
+		if ctx.Err() != nil || !IsTransient(err) {
+			return CompletionResponse{}, err
+		}
+	}
+	return CompletionResponse{}, fmt.Errorf("llm %s: %d attempts failed: %w", r.Name, attempts, lastErr)
+}
+
+// backoff возвращает задержку перед попыткой attempt (1-based после первой).
+func (r *RetryClient) backoff(attempt int) time.Duration {
+	base := r.BaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	maxDelay := r.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// stubCompleter отдаёт ошибки из errs по очереди, затем — успешный ответ.
+type stubCompleter struct {
+	errs  []error
+	calls int
+}
+
+func (s *stubCompleter) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	s.calls++
+	if s.calls <= len(s.errs) && s.errs[s.calls-1] != nil {
+		return CompletionResponse{}, s.errs[s.calls-1]
+	}
+	return CompletionResponse{Content: "ok"}, nil
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"429", &StatusError{Op: "Complete", Code: http.StatusTooManyRequests}, true},
+		{"503", &StatusError{Op: "Complete", Code: http.StatusServiceUnavailable}, true},
+		{"400", &StatusError{Op: "Complete", Code: http.StatusBadRequest}, false},
+		{"wrapped 502", fmt.Errorf("call: %w", &StatusError{Code: http.StatusBadGateway}), true},
+		{"conn refused", syscall.ECONNREFUSED, true},
+		{"conn reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"eof", io.ErrUnexpectedEOF, true},
+		{"other", errors.New("bad json"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryClient(t *testing.T) {
+	unavailable := &StatusError{Op: "Complete", Code: http.StatusServiceUnavailable}
+	badRequest := &StatusError{Op: "Complete", Code: http.StatusBadRequest}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"first try", nil, 1, nil},
+		{"recovers after transient", []error{unavailable, unavailable}, 3, nil},
+		{"gives up after max attempts", []error{unavailable, unavailable, unavailable}, 3, unavailable},
+		{"permanent error is not retried", []error{badRequest}, 1, badRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &stubCompleter{errs: tt.errs}
+			r := NewRetryClient("test", next)
+			r.BaseDelay = time.Millisecond
+			r.MaxDelay = time.Millisecond
+
+			resp, err := r.Complete(context.Background(), CompletionRequest{})
+			if next.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if err != nil || resp.Content != "ok" {
+					t.Fatalf("Complete = %q, %v; want ok", resp.Content, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Complete error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryClientStopsOnCancel(t *testing.T) {
+	next := &stubCompleter{errs: []error{&StatusError{Code: http.StatusServiceUnavailable}}}
+	r := NewRetryClient("test", next)
+	r.BaseDelay = time.Hour
+	r.MaxDelay = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Complete(ctx, CompletionRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Complete error = %v, want deadline exceeded", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("calls = %d, want 1", next.calls)
+	}
+}
+
+func TestRetryClientBackoff(t *testing.T) {
+	r := &RetryClient{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt, limit := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  300 * time.Millisecond,
+		10: 300 * time.Millisecond,
+	} {
+		for range 50 {
+			if d := r.backoff(attempt); d <= 0 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want (0, %v]", attempt, d, limit)
+			}
+		}
+	}
+}
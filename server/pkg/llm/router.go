@@ -0,0 +1,289 @@
+// Package llm — маршрутизация вызовов по моделям.
+//
+// Router выбирает модель по назначению вызова (dialogue, decision,
+// reflection, consolidation, summarization) и, опционально, по агенту:
+// мелкая модель ведёт болтовню, крупная — рефлексию, а отдельный агент
+// может жить на своей модели. Правила читаются из JSON-файла
+// (LLM_ROUTING_FILE) и перекрываются через API. Выбранный маршрут
+// кладётся в контекст, UsageRecorder пишет его в llm_calls.
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RoutingConfig — правила выбора модели.
+//
+//	{
+//	  "default": "gemma3:4b",
+//	  "purposes": {"dialogue": "gemma3:1b", "reflection": "gemma3:12b"},
+//	  "agents": {"<agent-id>": {"default": "qwen2.5:3b"}}
+//	}
+type RoutingConfig struct {
+	// Default — модель для всех вызовов без более точного правила.
+	// Пустая = модель провайдера из env.
+	Default string `json:"default,omitempty"`
+
+	// Purposes — модель по назначению вызова.
+	Purposes map[Purpose]string `json:"purposes,omitempty"`
+
+	// Agents — правила отдельных агентов, важнее правил по назначению.
+	Agents map[string]AgentRouting `json:"agents,omitempty"`
+}
+
+// AgentRouting — правила одного агента.
+type AgentRouting struct {
+	// Default — модель агента для всех назначений.
+	Default string `json:"default,omitempty"`
+
+	// Purposes — модель агента по назначению.
+	Purposes map[Purpose]string `json:"purposes,omitempty"`
+}
+
+// Route — решение маршрутизатора для одного вызова.
+type Route struct {
+	// Model — выбранная модель. Пустая = модель провайдера по умолчанию.
+	Model string
+
+	// Rule — сработавшее правило: "agent:<id>:<purpose>", "agent:<id>",
+	// "purpose:<purpose>", "default" или "" (правил нет).
+	Rule string
+
+	// Override — правило задано через API, а не файлом.
+	Override bool
+}
+
+// LoadRoutingConfig читает правила из JSON-файла.
+func LoadRoutingConfig(path string) (RoutingConfig, error) {
+	var cfg RoutingConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("LoadRoutingConfig: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("LoadRoutingConfig decode %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Уровни точности правил: от самого точного к самому общему.
+const (
+	levelAgentPurpose = iota
+	levelAgent
+	levelPurpose
+	levelDefault
+	levelCount
+)
+
+// Resolve выбирает модель по правилам. Порядок: агент+назначение,
+// агент, назначение, default.
+func (c RoutingConfig) Resolve(info CallInfo) Route {
+	for level := 0; level < levelCount; level++ {
+		if route, ok := c.ruleAt(level, info); ok {
+			return route
+		}
+	}
+	return Route{}
+}
+
+// ruleAt возвращает правило заданного уровня, если оно есть.
+func (c RoutingConfig) ruleAt(level int, info CallInfo) (Route, bool) {
+	switch level {
+	case levelAgentPurpose:
+		if m := c.Agents[info.AgentID].Purposes[info.Purpose]; info.AgentID != "" && m != "" {
+			return Route{Model: m, Rule: "agent:" + info.AgentID + ":" + string(info.Purpose)}, true
+		}
+	case levelAgent:
+		if m := c.Agents[info.AgentID].Default; info.AgentID != "" && m != "" {
+			return Route{Model: m, Rule: "agent:" + info.AgentID}, true
+		}
+	case levelPurpose:
+		if m := c.Purposes[info.Purpose]; m != "" {
+			return Route{Model: m, Rule: "purpose:" + string(info.Purpose)}, true
+		}
+	case levelDefault:
+		if c.Default != "" {
+			return Route{Model: c.Default, Rule: "default"}, true
+		}
+	}
+	return Route{}, false
+}
+
+// Router — декоратор Completer, выставляющий req.Model по правилам.
+type Router struct {
+	// Next — оборачиваемый провайдер.
+	Next Completer
+
+	mu        sync.RWMutex
+	base      RoutingConfig // из файла
+	overrides RoutingConfig // из API
+
+	// updateMu сериализует изменения из API: сохранение и применение
+	// переопределений не перемежаются.
+	updateMu sync.Mutex
+}
+
+// NewRouter создаёт Router с правилами из файла.
+func NewRouter(next Completer, base RoutingConfig) *Router {
+	return &Router{Next: next, base: base}
+}
+
+// Complete выбирает модель и передаёт запрос дальше. Явный req.Model
+// не перекрывается.
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	route := Route{Model: req.Model, Rule: "request"}
+	if req.Model == "" {
+		route = r.Resolve(CallInfoFrom(ctx))
+		req.Model = route.Model
+	}
+	return r.Next.Complete(withRoute(ctx, route), req)
+}
+
+// Resolve возвращает маршрут для вызова. Более точное правило важнее,
+// на одном уровне правило API важнее правила файла.
+func (r *Router) Resolve(info CallInfo) Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for level := 0; level < levelCount; level++ {
+		if route, ok := r.overrides.ruleAt(level, info); ok {
+			route.Override = true
+			return route
+		}
+		if route, ok := r.base.ruleAt(level, info); ok {
+			return route
+		}
+	}
+	return Route{}
+}
+
+// Base возвращает правила из файла.
+func (r *Router) Base() RoutingConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.base.clone()
+}
+
+// Overrides возвращает правила, заданные через API.
+func (r *Router) Overrides() RoutingConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.overrides.clone()
+}
+
+// SetOverrides заменяет правила API целиком.
+func (r *Router) SetOverrides(cfg RoutingConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.overrides = cfg.clone()
+}
+
+// SaveFunc сохраняет переопределения до того, как Router их применит.
+type SaveFunc func(RoutingConfig) error
+
+// ReplaceOverrides заменяет переопределения API. Если save вернул ошибку,
+// прежние правила остаются в силе. save == nil — без сохранения.
+func (r *Router) ReplaceOverrides(cfg RoutingConfig, save SaveFunc) error {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	if save != nil {
+		if err := save(cfg.clone()); err != nil {
+			return err
+		}
+	}
+	r.SetOverrides(cfg)
+	return nil
+}
+
+// SetOverride задаёт или (model == "") снимает одно правило API и
+// возвращает новые переопределения. agentID и purpose могут быть пустыми:
+// оба пустые — правило default. Если save вернул ошибку, прежние правила
+// остаются в силе. save == nil — без сохранения.
+func (r *Router) SetOverride(agentID string, purpose Purpose, model string, save SaveFunc) (RoutingConfig, error) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	o := r.Overrides()
+	switch {
+	case agentID != "":
+		if o.Agents == nil {
+			o.Agents = make(map[string]AgentRouting)
+		}
+		a := o.Agents[agentID]
+		if purpose != "" {
+			a.Purposes = setRule(a.Purposes, purpose, model)
+		} else {
+			a.Default = model
+		}
+		if a.Default == "" && len(a.Purposes) == 0 {
+			delete(o.Agents, agentID)
+		} else {
+			o.Agents[agentID] = a
+		}
+	case purpose != "":
+		o.Purposes = setRule(o.Purposes, purpose, model)
+	default:
+		o.Default = model
+	}
+	if save != nil {
+		if err := save(o.clone()); err != nil {
+			return RoutingConfig{}, err
+		}
+	}
+	r.SetOverrides(o)
+	return o, nil
+}
+
+func setRule(m map[Purpose]string, p Purpose, model string) map[Purpose]string {
+	if model == "" {
+		delete(m, p)
+		return m
+	}
+	if m == nil {
+		m = make(map[Purpose]string)
+	}
+	m[p] = model
+	return m
+}
+
+func (c RoutingConfig) clone() RoutingConfig {
+	out := RoutingConfig{Default: c.Default}
+	if len(c.Purposes) > 0 {
+		out.Purposes = make(map[Purpose]string, len(c.Purposes))
+		for p, m := range c.Purposes {
+			out.Purposes[p] = m
+		}
+	}
+	if len(c.Agents) > 0 {
+		out.Agents = make(map[string]AgentRouting, len(c.Agents))
+		for id, a := range c.Agents {
+			ac := AgentRouting{Default: a.Default}
+			if len(a.Purposes) > 0 {
+				ac.Purposes = make(map[Purpose]string, len(a.Purposes))
+				for p, m := range a.Purposes {
+					ac.Purposes[p] = m
+				}
+			}
+			out.Agents[id] = ac
+		}
+	}
+	return out
+}
+
+type routeKey struct{}
+
+// withRoute кладёт решение маршрутизатора в контекст вызова.
+func withRoute(ctx context.Context, r Route) context.Context {
+	return context.WithValue(ctx, routeKey{}, r)
+}
+
+// RouteFrom возвращает маршрут вызова. Пустой Route = Router не подключён.
+func RouteFrom(ctx context.Context) Route {
+	r, _ := ctx.Value(routeKey{}).(Route)
+	return r
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRoutingConfigResolve(t *testing.T) {
+	cfg := RoutingConfig{
+		Default:  "gemma3:4b",
+		Purposes: map[Purpose]string{PurposeDialogue: "gemma3:1b", PurposeReflection: "gemma3:12b"},
+		Agents: map[string]AgentRouting{
+			"alice": {Default: "qwen2.5:3b", Purposes: map[Purpose]string{PurposeReflection: "qwen2.5:7b"}},
+		},
+	}
+	tests := []struct {
+		info CallInfo
+		want Route
+	}{
+		{CallInfo{AgentID: "alice", Purpose: PurposeReflection}, Route{Model: "qwen2.5:7b", Rule: "agent:alice:reflection"}},
+		{CallInfo{AgentID: "alice", Purpose: PurposeDialogue}, Route{Model: "qwen2.5:3b", Rule: "agent:alice"}},
+		{CallInfo{AgentID: "bob", Purpose: PurposeDialogue}, Route{Model: "gemma3:1b", Rule: "purpose:dialogue"}},
+		{CallInfo{Purpose: PurposeConsolidation}, Route{Model: "gemma3:4b", Rule: "default"}},
+	}
+	for _, tt := range tests {
+		if got := cfg.Resolve(tt.info); got != tt.want {
+			t.Errorf("Resolve(%+v) = %+v, want %+v", tt.info, got, tt.want)
+		}
+	}
+	if got := (RoutingConfig{}).Resolve(CallInfo{Purpose: PurposeDialogue}); got != (Route{}) {
+		t.Errorf("empty config Resolve = %+v, want no route", got)
+	}
+}
+
+func TestRouterOverrides(t *testing.T) {
+	r := NewRouter(nil, RoutingConfig{
+		Default:  "gemma3:4b",
+		Purposes: map[Purpose]string{PurposeDialogue: "gemma3:1b"},
+	})
+	dialogue := CallInfo{AgentID: "alice", Purpose: PurposeDialogue}
+
+	// Правило API на том же уровне важнее правила файла.
+	r.SetOverride("", PurposeDialogue, "llama3.2:3b", nil)
+	if got := r.Resolve(dialogue); got != (Route{Model: "llama3.2:3b", Rule: "purpose:dialogue", Override: true}) {
+		t.Errorf("purpose override = %+v", got)
+	}
+
+	// Более точное правило файла важнее общего правила API.
+	r.SetOverride("", "", "mistral:7b", nil)
+	if got := r.Resolve(CallInfo{Purpose: PurposeDialogue}); got.Model != "llama3.2:3b" {
+		t.Errorf("dialogue = %+v, want the purpose override", got)
+	}
+	if got := r.Resolve(CallInfo{Purpose: PurposeReflection}); got != (Route{Model: "mistral:7b", Rule: "default", Override: true}) {
+		t.Errorf("reflection = %+v, want the default override", got)
+	}
+
+	cfg, _ := r.SetOverride("alice", PurposeDialogue, "qwen2.5:3b", nil)
+	if cfg.Agents["alice"].Purposes[PurposeDialogue] != "qwen2.5:3b" {
+		t.Errorf("overrides = %+v", cfg)
+	}
+	if got := r.Resolve(dialogue); got.Rule != "agent:alice:dialogue" {
+		t.Errorf("agent override = %+v", got)
+	}
+
+	// Пустая модель снимает правило, пустой агент удаляется целиком.
+	cfg, _ = r.SetOverride("alice", PurposeDialogue, "", nil)
+	if _, ok := cfg.Agents["alice"]; ok {
+		t.Errorf("agent without rules kept: %+v", cfg.Agents)
+	}
+	r.SetOverride("", PurposeDialogue, "", nil)
+	r.SetOverride("", "", "", nil)
+	if got := r.Resolve(dialogue); got != (Route{Model: "gemma3:1b", Rule: "purpose:dialogue"}) {
+		t.Errorf("after removing overrides = %+v, want the file rule", got)
+	}
+
+	// Правило применяется только после сохранения.
+	var saved RoutingConfig
+	if _, err := r.SetOverride("", PurposeDialogue, "phi3", func(cfg RoutingConfig) error {
+		saved = cfg
+		return nil
+	}); err != nil || saved.Purposes[PurposeDialogue] != "phi3" {
+		t.Errorf("saved = %+v, %v", saved, err)
+	}
+	failed := errors.New("disk full")
+	if _, err := r.SetOverride("", PurposeDialogue, "mistral:7b", func(RoutingConfig) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("save error = %v", err)
+	}
+	if got := r.Resolve(dialogue); got.Model != "phi3" {
+		t.Errorf("after failed save = %+v, want the saved rule", got)
+	}
+}
+
+// modelRecorder запоминает модель и маршрут последнего запроса.
+type modelRecorder struct {
+	model string
+	route Route
+}
+
+func (m *modelRecorder) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	m.model, m.route = req.Model, RouteFrom(ctx)
+	return CompletionResponse{Content: "ok", Model: req.Model}, nil
+}
+
+func TestRouterComplete(t *testing.T) {
+	next := &modelRecorder{}
+	r := NewRouter(next, RoutingConfig{Purposes: map[Purpose]string{PurposeReflection: "gemma3:12b"}})
+
+	ctx := WithCallInfo(context.Background(), CallInfo{Purpose: PurposeReflection})
+	r.Complete(ctx, CompletionRequest{})
+	if next.model != "gemma3:12b" || next.route.Rule != "purpose:reflection" {
+		t.Errorf("routed model = %q, route = %+v", next.model, next.route)
+	}
+
+	r.Complete(ctx, CompletionRequest{Model: "phi3"})
+	if next.model != "phi3" || next.route.Rule != "request" {
+		t.Errorf("explicit model = %q, route = %+v; want phi3 kept", next.model, next.route)
+	}
+}
@@ -0,0 +1,230 @@
+// Package llm — глобальный планировщик вызовов LLM.
+//
+// Тики стартуют каждые 22 секунды отдельными горутинами. Если Ollama
+// отвечает медленно, разговоры накладываются и одновременно бьют в
+// одну CPU-модель. Scheduler стоит перед провайдером, ограничивает число
+// одновременных запросов (MaxInFlight) и выдаёт слоты по приоритету
+// Purpose: ответы человеку → диалог → сводки → рефлексия → консолидация.
+// Запрос, чей контекст (контекст тика) завершился в очереди, снимается
+// с очереди, не дойдя до модели.
+
+package llm
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Scheduler — декоратор Completer с лимитом in-flight и приоритетной очередью.
+type Scheduler struct {
+	// Next — оборачиваемый провайдер.
+	Next Completer
+
+	// MaxInFlight — сколько запросов одновременно уходит в провайдер.
+	MaxInFlight int
+
+	mu       sync.Mutex
+	inFlight int
+	queue    waitQueue
+	seq      uint64
+	stats    map[Purpose]*purposeStats
+}
+
+// SchedulerStats — снимок состояния планировщика для API.
+type SchedulerStats struct {
+	MaxInFlight int
+	InFlight    int
+	QueueDepth  int
+	ByPurpose   map[Purpose]PurposeStats
+}
+
+// PurposeStats — статистика очереди по одному Purpose.
+type PurposeStats struct {
+	// Queued — сколько запросов сейчас ждут слота.
+	Queued int
+	// Granted — сколько запросов получили слот.
+	Granted int64
+	// Canceled — сколько запросов снято с очереди по отмене контекста.
+	Canceled int64
+	// AvgWait, MaxWait — время ожидания слота.
+	AvgWait time.Duration
+	MaxWait time.Duration
+}
+
+type purposeStats struct {
+	queued    int
+	granted   int64
+	canceled  int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// NewScheduler создаёт Scheduler. maxInFlight <= 0 трактуется как 1.
+func NewScheduler(next Completer, maxInFlight int) *Scheduler {
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	return &Scheduler{
+		Next:        next,
+		MaxInFlight: maxInFlight,
+		stats:       make(map[Purpose]*purposeStats),
+	}
+}
+
+// Complete ждёт слот в порядке приоритета и вызывает Next.
+func (s *Scheduler) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	purpose := CallInfoFrom(ctx).Purpose
+	if err := s.acquire(ctx, purpose); err != nil {
+		return CompletionResponse{}, err
+	}
+	defer s.release()
+	return s.Next.Complete(ctx, req)
+}
+
+// Stats возвращает снимок очереди и времени ожидания.
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := SchedulerStats{
+		MaxInFlight: s.MaxInFlight,
+		InFlight:    s.inFlight,
+		QueueDepth:  s.queue.Len(),
+		ByPurpose:   make(map[Purpose]PurposeStats, len(s.stats)),
+	}
+	for p, st := range s.stats {
+		ps := PurposeStats{
+			Queued:   st.queued,
+			Granted:  st.granted,
+			Canceled: st.canceled,
+			MaxWait:  st.maxWait,
+		}
+		if st.granted > 0 {
+			ps.AvgWait = st.totalWait / time.Duration(st.granted)
+		}
+		out.ByPurpose[p] = ps
+	}
+	return out
+}
+
+// waiter — запрос, ожидающий слота.
+type waiter struct {
+	priority int
+	seq      uint64
+	purpose  Purpose
+	enqueued time.Time
+	ready    chan struct{}
+	index    int // позиция в heap, -1 = уже не в очереди
+}
+
+// acquire занимает слот сразу или встаёт в очередь.
+func (s *Scheduler) acquire(ctx context.Context, purpose Purpose) error {
+	s.mu.Lock()
+	st := s.statsFor(purpose)
+	if s.inFlight < s.MaxInFlight && s.queue.Len() == 0 {
+		s.inFlight++
+		st.granted++
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.seq++
+	w := &waiter{
+		priority: purpose.Priority(),
+		seq:      s.seq,
+		purpose:  purpose,
+		enqueued: time.Now(),
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&s.queue, w)
+	st.queued++
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.index >= 0 {
+			// Ещё в очереди — снимаем, слот не занимали.
+			heap.Remove(&s.queue, w.index)
+			st.queued--
+			st.canceled++
+			s.mu.Unlock()
+			return fmt.Errorf("llm scheduler: %s request dropped from queue: %w", purpose, ctx.Err())
+		}
+		s.mu.Unlock()
+		// Слот выдан одновременно с отменой — возвращаем его.
+		s.release()
+		return fmt.Errorf("llm scheduler: %w", ctx.Err())
+	}
+}
+
+// release передаёт слот следующему в очереди или освобождает его.
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queue.Len() == 0 {
+		s.inFlight--
+		return
+	}
+	w := heap.Pop(&s.queue).(*waiter)
+	wait := time.Since(w.enqueued)
+
+	st := s.statsFor(w.purpose)
+	st.queued--
+	st.granted++
+	st.totalWait += wait
+	if wait > st.maxWait {
+		st.maxWait = wait
+	}
+	close(w.ready)
+}
+
+// statsFor возвращает (создавая при необходимости) счётчики Purpose. Под s.mu.
+func (s *Scheduler) statsFor(p Purpose) *purposeStats {
+	st, ok := s.stats[p]
+	if !ok {
+		st = &purposeStats{}
+		s.stats[p] = st
+	}
+	return st
+}
+
+// waitQueue — min-heap по (priority, seq): сначала важные, внутри — FIFO.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateCompleter записывает Purpose каждого вызова и ждёт сигнала в gate.
+type gateCompleter struct {
+	mu    sync.Mutex
+	order []Purpose
+	gate  chan struct{}
+}
+
+func (g *gateCompleter) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	g.mu.Lock()
+	g.order = append(g.order, CallInfoFrom(ctx).Purpose)
+	g.mu.Unlock()
+	<-g.gate
+	return CompletionResponse{Content: "ok"}, nil
+}
+
+// waitQueued ждёт, пока слот окажется занят, а в очереди — depth запросов.
+func waitQueued(t *testing.T, s *Scheduler, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for st := s.Stats(); st.InFlight != 1 || st.QueueDepth != depth; st = s.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight = %d, queue depth = %d; want 1, %d", st.InFlight, st.QueueDepth, depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerPriorityOrder(t *testing.T) {
+	next := &gateCompleter{gate: make(chan struct{})}
+	s := NewScheduler(next, 1)
+
+	var wg sync.WaitGroup
+	call := func(p Purpose) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := WithCallInfo(context.Background(), CallInfo{Purpose: p})
+			if _, err := s.Complete(ctx, CompletionRequest{}); err != nil {
+				t.Errorf("%s: %v", p, err)
+			}
+		}()
+	}
+
+	// Первый вызов занимает единственный слот, остальные встают в очередь.
+	call(PurposeDialogue)
+	waitQueued(t, s, 0)
+	queued := []Purpose{PurposeConsolidation, PurposeReflection, PurposeDialogue, PurposeHumanReply, PurposeSummarization}
+	for i, p := range queued {
+		call(p)
+		waitQueued(t, s, i+1)
+	}
+	for range len(queued) + 1 {
+		next.gate <- struct{}{}
+	}
+	wg.Wait()
+
+	want := []Purpose{
+		PurposeDialogue,
+		PurposeHumanReply, PurposeDialogue, PurposeSummarization, PurposeReflection, PurposeConsolidation,
+	}
+	if len(next.order) != len(want) {
+		t.Fatalf("order = %v, want %v", next.order, want)
+	}
+	for i := range want {
+		if next.order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", next.order, want)
+		}
+	}
+	if st := s.Stats(); st.InFlight != 0 || st.QueueDepth != 0 {
+		t.Errorf("after drain in-flight = %d, queue = %d; want 0, 0", st.InFlight, st.QueueDepth)
+	}
+}
+
+func TestSchedulerCancelInQueue(t *testing.T) {
+	next := &gateCompleter{gate: make(chan struct{})}
+	s := NewScheduler(next, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Complete(context.Background(), CompletionRequest{})
+	}()
+	waitQueued(t, s, 0)
+
+	ctx, cancel := context.WithCancel(WithCallInfo(context.Background(), CallInfo{Purpose: PurposeReflection}))
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Complete(ctx, CompletionRequest{})
+		errc <- err
+	}()
+	waitQueued(t, s, 1)
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled request error = %v, want context.Canceled", err)
+	}
+	st := s.Stats()
+	if st.QueueDepth != 0 || st.ByPurpose[PurposeReflection].Canceled != 1 {
+		t.Errorf("queue = %d, canceled = %d; want 0, 1", st.QueueDepth, st.ByPurpose[PurposeReflection].Canceled)
+	}
+
+	// Снятый с очереди запрос не занимает слот: после первого вызова он свободен.
+	next.gate <- struct{}{}
+	<-done
+	if st := s.Stats(); st.InFlight != 0 {
+		t.Errorf("in-flight after release = %d, want 0", st.InFlight)
+	}
+	if len(next.order) != 1 {
+		t.Errorf("provider calls = %d, want 1", len(next.order))
+	}
+}
@@ -0,0 +1,78 @@
+// Package llm — учёт токенов и задержек по вызовам.
+//
+// UsageRecorder замеряет каждый вызов провайдера и отдаёт CallRecord
+// в Sink вместе с метаданными из CallInfo (агент, тик, назначение)
+// и решением Router (какая модель выбрана и по какому правилу), а также
+// моделью и провайдером, которые на самом деле ответили.
+// Пакет не знает о БД: main подключает Sink к Repository.SaveLLMCall.
+
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+// CallRecord — итог одного вызова LLM для учёта.
+type CallRecord struct {
+	CallInfo
+
+	// Route — решение маршрутизатора. Пустой, если Router не подключён.
+	// Модель маршрута применяет только основной провайдер цепочки.
+	Route Route
+
+	// Model — модель, которая ответила (пусто при ошибке).
+	Model string
+
+	// Provider — провайдер, который ответил (пусто при ошибке и без
+	// FallbackClient).
+	Provider string
+
+	// PromptTokens, CompletionTokens — токены по данным провайдера.
+	PromptTokens     int
+	CompletionTokens int
+
+	// Latency — время вызова Next (без ожидания в очереди планировщика).
+	Latency time.Duration
+
+	// Err — ошибка вызова, nil при успехе.
+	Err error
+
+	// StartedAt — момент начала вызова.
+	StartedAt time.Time
+}
+
+// UsageRecorder — декоратор Completer, пишущий CallRecord на каждый вызов.
+type UsageRecorder struct {
+	// Next — оборачиваемый провайдер.
+	Next Completer
+
+	// Sink — получатель записей. Вызывается синхронно после ответа.
+	Sink func(CallRecord)
+}
+
+// NewUsageRecorder создаёт UsageRecorder.
+func NewUsageRecorder(next Completer, sink func(CallRecord)) *UsageRecorder {
+	return &UsageRecorder{Next: next, Sink: sink}
+}
+
+// Complete вызывает Next и записывает токены и задержку.
+func (u *UsageRecorder) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	start := time.Now()
+	resp, err := u.Next.Complete(ctx, req)
+
+	if u.Sink != nil {
+		u.Sink(CallRecord{
+			CallInfo:         CallInfoFrom(ctx),
+			Route:            RouteFrom(ctx),
+			Model:            resp.Model,
+			Provider:         resp.Provider,
+			PromptTokens:     resp.PromptTokens,
+			CompletionTokens: resp.CompletionTokens,
+			Latency:          time.Since(start),
+			Err:              err,
+			StartedAt:        start,
+		})
+	}
+	return resp, err
+}
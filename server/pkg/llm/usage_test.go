@@ -0,0 +1,32 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestUsageRecorder(t *testing.T) {
+	var got []CallRecord
+	next := &ScriptedResponder{Respond: func(CompletionRequest) string { return "ok" }}
+	u := NewUsageRecorder(NewFallbackClient(nil, FallbackEntry{Name: "primary", Client: next}), func(r CallRecord) { got = append(got, r) })
+
+	ctx := WithCallInfo(context.Background(), CallInfo{Purpose: PurposeReflection, AgentID: "a", Tick: 7})
+	if _, err := u.Complete(ctx, CompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	fail := &StatusError{Code: http.StatusBadGateway}
+	u.Next = &stubCompleter{errs: []error{fail}}
+	u.Complete(context.Background(), CompletionRequest{})
+
+	if len(got) != 2 {
+		t.Fatalf("records = %d, want 2", len(got))
+	}
+	if r := got[0]; r.Purpose != PurposeReflection || r.AgentID != "a" || r.Tick != 7 || r.Model != "scripted" || r.Provider != "primary" || r.Err != nil {
+		t.Errorf("first record = %+v", r)
+	}
+	if r := got[1]; r.Purpose != PurposeDialogue || !errors.Is(r.Err, fail) {
+		t.Errorf("failed call record = %+v, want dialogue with the provider error", r)
+	}
+}